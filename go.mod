@@ -2,14 +2,17 @@ module github.com/bsaid97/go-polygon-fixer
 
 go 1.23.2
 
+require (
+	github.com/jonas-p/go-shp v0.1.1
+	github.com/twpayne/go-geos v0.19.0
+)
+
 require (
 	github.com/everystreet/go-proj/v8 v8.0.0 // indirect
 	github.com/golang/geo v0.0.0-20230421003525-6adc56603217 // indirect
-	github.com/jonas-p/go-shp v0.1.1 // indirect
 	github.com/remyoudompheng/bigfft v0.0.0-20200410134404-eec4a21b6bb0 // indirect
 	github.com/tj/go-spin v1.1.0 // indirect
 	github.com/twpayne/go-geom v1.5.7 // indirect
-	github.com/twpayne/go-geos v0.19.0 // indirect
 	github.com/xlab/c-for-go v0.0.0-20201223145653-3ba5db515dcb // indirect
 	github.com/xlab/pkgconfig v0.0.0-20170226114623-cea12a0fd245 // indirect
 	go.mongodb.org/mongo-driver v1.11.4 // indirect
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+// BoundingCircle describes the minimum bounding circle of a feature
+type BoundingCircle struct {
+	CenterX    float64                `json:"centerX"`
+	CenterY    float64                `json:"centerY"`
+	RadiusM    float64                `json:"radiusM"`
+	Properties map[string]interface{} `json:"properties"`
+	radius     float64                // radius in degrees, used to draw the circle
+}
+
+// MinimumBoundingCircles computes the smallest enclosing circle of each feature.
+// The circle is found in degree space; its radius is reported in meters as the
+// geodesic distance from the centre to the furthest vertex.
+func MinimumBoundingCircles(features []Feature) []BoundingCircle {
+	circles := make([]BoundingCircle, 0, len(features))
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+		coords := utils.Coordinates(geom)
+		geom.Destroy()
+
+		if len(coords) == 0 {
+			log.Printf("Skipping feature %d: empty geometry", i)
+			continue
+		}
+
+		circle := utils.MinimumBoundingCircle(coords)
+		radiusM := 0.0
+		for _, coord := range coords {
+			distance := utils.HaversineDistance(circle.X, circle.Y, coord[0], coord[1])
+			if distance > radiusM {
+				radiusM = distance
+			}
+		}
+
+		circles = append(circles, BoundingCircle{
+			CenterX:    circle.X,
+			CenterY:    circle.Y,
+			RadiusM:    radiusM,
+			Properties: feature.Properties,
+			radius:     circle.Radius,
+		})
+	}
+
+	return circles
+}
+
+// BoundingCirclePolygons converts bounding circles to polygon features, adding
+// the radius as a _radius_m property
+func BoundingCirclePolygons(features []Feature, quadSegs int) []Feature {
+	circles := MinimumBoundingCircles(features)
+	polygons := make([]Feature, 0, len(circles))
+
+	for _, circle := range circles {
+		center := geos.NewPointFromXY(circle.CenterX, circle.CenterY)
+		polygon := center.Buffer(circle.radius, quadSegs)
+		center.Destroy()
+
+		properties := copyProperties(circle.Properties)
+		properties["_radius_m"] = circle.RadiusM
+
+		polygons = append(polygons, newGeomFeature(polygon, properties))
+		polygon.Destroy()
+	}
+
+	return polygons
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/twpayne/go-geos"
+)
+
+// Label point methods supported by LabelPoints
+const (
+	LabelMethodCentroid       = "centroid"
+	LabelMethodPointOnSurface = "pointOnSurface"
+)
+
+// LabelPoints returns one Point feature per input feature, preserving properties.
+// The centroid method gives the true centre of mass, which can fall outside
+// concave or multi-part shapes; pointOnSurface is guaranteed to lie inside.
+func LabelPoints(features []Feature, method string) ([]Feature, error) {
+	if method != LabelMethodCentroid && method != LabelMethodPointOnSurface {
+		return nil, fmt.Errorf("unsupported method %q (expected %s or %s)", method, LabelMethodCentroid, LabelMethodPointOnSurface)
+	}
+
+	points := make([]Feature, 0, len(features))
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+
+		var point *geos.Geom
+		if method == LabelMethodCentroid {
+			point = geom.Centroid()
+		} else {
+			point = geom.PointOnSurface()
+		}
+		geom.Destroy()
+
+		if point == nil {
+			log.Printf("Skipping feature %d: failed to compute %s", i, method)
+			continue
+		}
+
+		points = append(points, newGeomFeature(point, feature.Properties))
+		point.Destroy()
+	}
+
+	return points, nil
+}
+
+// CentroidProperty holds, when requested, the area-weighted centroid of a
+// dissolved or collected feature as a GeoJSON Point, for use as a label point
+const CentroidProperty = "_centroid"
+
+// CentroidPoint returns the area-weighted centroid of geom as a GeoJSON Point
+// object. Overlapping parts are counted twice, so geom should already be a
+// union. It reports false for empty geometries.
+func CentroidPoint(geom *geos.Geom) (map[string]interface{}, bool) {
+	if geom == nil || geom.IsEmpty() {
+		return nil, false
+	}
+	centroid := geom.Centroid()
+	if centroid == nil {
+		return nil, false
+	}
+	defer centroid.Destroy()
+	if centroid.IsEmpty() {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		"type":        "Point",
+		"coordinates": []float64{centroid.X(), centroid.Y()},
+	}, true
+}
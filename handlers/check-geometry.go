@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/bsaid97/go-polygon-fixer/utils"
 	"github.com/twpayne/go-geos"
 )
 
@@ -27,3 +29,86 @@ func CheckGeometry(geometryCollection *geos.Geom) []Error {
 	}
 	return errors
 }
+
+// GeometrySummary is a one-shot health snapshot of a geometry collection
+type GeometrySummary struct {
+	Total   int            `json:"total"`
+	ByType  map[string]int `json:"byType"`
+	Empty   int            `json:"empty"`
+	Invalid int            `json:"invalid"`
+}
+
+// CheckResult is the /check-geometry response when a summary is requested
+type CheckResult struct {
+	Errors  []Error         `json:"errors"`
+	Summary GeometrySummary `json:"summary"`
+}
+
+// SummarizeGeometry counts the geometries of a collection by type, and how many
+// are empty or invalid. Types other than Polygon and MultiPolygon are counted
+// under their own GEOS type name.
+func SummarizeGeometry(geometryCollection *geos.Geom) GeometrySummary {
+	summary := GeometrySummary{
+		Total:  geometryCollection.NumGeometries(),
+		ByType: make(map[string]int),
+	}
+
+	for i := range geometryCollection.NumGeometries() {
+		shape := geometryCollection.Geometry(i)
+		summary.ByType[shape.Type()]++
+		if shape.IsEmpty() {
+			summary.Empty++
+		}
+		if !shape.IsValid() {
+			summary.Invalid++
+		}
+	}
+	return summary
+}
+
+// ValiditySummary counts invalid geometries in a collection by reason
+type ValiditySummary struct {
+	Total       int            `json:"total"`
+	Valid       int            `json:"valid"`
+	Invalid     int            `json:"invalid"`
+	ParseErrors int            `json:"parseErrors"`
+	NullCount   int            `json:"nullGeometries"`
+	Reasons     map[string]int `json:"reasons"`
+}
+
+// SummarizeValidity checks every feature with IsValid/IsValidReason without
+// repairing anything. Reasons are grouped without their location suffix so
+// e.g. all self-intersections are counted together.
+func SummarizeValidity(features []Feature) ValiditySummary {
+	summary := ValiditySummary{
+		Total:   len(features),
+		Reasons: make(map[string]int),
+	}
+
+	for _, feature := range features {
+		if utils.IsNullGeometry(feature.Geometry) {
+			summary.NullCount++
+			continue
+		}
+
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			summary.ParseErrors++
+			continue
+		}
+
+		if geom.IsValid() {
+			summary.Valid++
+		} else {
+			summary.Invalid++
+			reason := geom.IsValidReason()
+			if location := strings.Index(reason, "["); location > 0 {
+				reason = reason[:location]
+			}
+			summary.Reasons[reason]++
+		}
+		geom.Destroy()
+	}
+
+	return summary
+}
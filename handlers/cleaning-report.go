@@ -0,0 +1,139 @@
+package handlers
+
+// CleaningReport is the report.json member of the /clean-topology zip: the
+// coverage and boundary preservation checks of the cleaned output, with
+// distances in meters and areas in square meters. Features are referred to by
+// their position in the request.
+type CleaningReport struct {
+	Coverage             CoverageSummary              `json:"coverage"`
+	BoundaryPreservation *BoundaryPreservationSummary `json:"boundaryPreservation,omitempty"`
+}
+
+// CoverageSummary is a CoverageReport converted to meters
+type CoverageSummary struct {
+	GapCount         int     `json:"gapCount"`
+	BoundaryGaps     int     `json:"boundaryGapSegments"`
+	MaxGapWidthM     float64 `json:"maxGapWidthM"`
+	TotalGapLengthM  float64 `json:"totalGapLengthM"`
+	GapAreaM2        float64 `json:"gapAreaM2"`
+	OverlapCount     int     `json:"overlapCount"`
+	OverlapAreaM2    float64 `json:"overlapAreaM2"`
+	ContainmentCount int     `json:"containmentCount"`
+
+	Gaps         []GapSummary         `json:"gaps"`
+	Overlaps     []OverlapPair        `json:"overlaps"`
+	Containments []ContainmentSummary `json:"containments"`
+}
+
+// GapSummary is a GapPair converted to meters
+type GapSummary struct {
+	A         int     `json:"a"`
+	B         int     `json:"b"`
+	DistanceM float64 `json:"distanceM"`
+	MaxWidthM float64 `json:"maxWidthM"`
+	AreaM2    float64 `json:"areaM2"`
+	Segments  int     `json:"segments"`
+}
+
+// ContainmentSummary is a ContainmentPair with JSON names
+type ContainmentSummary struct {
+	Container int `json:"container"`
+	Contained int `json:"contained"`
+}
+
+// BoundaryPreservationSummary is a BoundaryPreservationReport with JSON names.
+// Distortion combines the relative area change with the Hausdorff distance.
+type BoundaryPreservationSummary struct {
+	TotalGeometries    int     `json:"totalGeometries"`
+	SignificantChanges int     `json:"significantChanges"`
+	AverageDistortion  float64 `json:"averageDistortion"`
+	MaxDistortion      float64 `json:"maxDistortion"`
+}
+
+// newCleaningReport converts the reports of one cleaning run, whose pair
+// indices are pipeline positions into geomFeatures, to a CleaningReport.
+// preservation is nil when boundary preservation was not validated.
+func newCleaningReport(coverage CoverageReport, preservation *BoundaryPreservationReport, geomFeatures []GeomFeature) *CleaningReport {
+	position := func(index int) int {
+		return inputIndex(Feature{Properties: geomFeatures[index].Properties})
+	}
+
+	summary := CoverageSummary{
+		GapCount:         coverage.GapCount,
+		BoundaryGaps:     coverage.BoundaryGaps,
+		MaxGapWidthM:     coverage.MaxGapWidthM,
+		TotalGapLengthM:  coverage.TotalGapLengthM,
+		GapAreaM2:        coverage.GapAreaM2,
+		OverlapCount:     coverage.OverlapCount,
+		OverlapAreaM2:    coverage.OverlapAreaM2,
+		ContainmentCount: coverage.ContainmentCount,
+		Gaps:             make([]GapSummary, 0, len(coverage.GapPairs)),
+		Overlaps:         make([]OverlapPair, 0, len(coverage.OverlapPairs)),
+		Containments:     make([]ContainmentSummary, 0, len(coverage.ContainmentPairs)),
+	}
+	for _, pair := range coverage.GapPairs {
+		summary.Gaps = append(summary.Gaps, GapSummary{
+			A:         position(pair.A),
+			B:         position(pair.B),
+			DistanceM: pair.DistanceM,
+			MaxWidthM: pair.MaxWidthM,
+			AreaM2:    pair.AreaM2,
+			Segments:  pair.Segments,
+		})
+	}
+	for _, pair := range coverage.OverlapPairs {
+		summary.Overlaps = append(summary.Overlaps, OverlapPair{A: position(pair.A), B: position(pair.B), AreaM2: pair.AreaM2})
+	}
+	for _, pair := range coverage.ContainmentPairs {
+		summary.Containments = append(summary.Containments, ContainmentSummary{
+			Container: position(pair.Container),
+			Contained: position(pair.Contained),
+		})
+	}
+
+	report := &CleaningReport{Coverage: summary}
+	if preservation != nil {
+		report.BoundaryPreservation = &BoundaryPreservationSummary{
+			TotalGeometries:    preservation.TotalGeometries,
+			SignificantChanges: preservation.SignificantChanges,
+			AverageDistortion:  preservation.AverageDistortion,
+			MaxDistortion:      preservation.MaxDistortion,
+		}
+	}
+	return report
+}
+
+// merge adds the report of another tile. Pairs across a tile seam are in
+// neither tile, so they are not reported.
+func (r *CleaningReport) merge(other *CleaningReport) {
+	if other == nil {
+		return
+	}
+
+	coverage := &r.Coverage
+	coverage.GapCount += other.Coverage.GapCount
+	coverage.BoundaryGaps += other.Coverage.BoundaryGaps
+	coverage.MaxGapWidthM = max(coverage.MaxGapWidthM, other.Coverage.MaxGapWidthM)
+	coverage.TotalGapLengthM += other.Coverage.TotalGapLengthM
+	coverage.GapAreaM2 += other.Coverage.GapAreaM2
+	coverage.OverlapCount += other.Coverage.OverlapCount
+	coverage.OverlapAreaM2 += other.Coverage.OverlapAreaM2
+	coverage.ContainmentCount += other.Coverage.ContainmentCount
+	coverage.Gaps = append(coverage.Gaps, other.Coverage.Gaps...)
+	coverage.Overlaps = append(coverage.Overlaps, other.Coverage.Overlaps...)
+	coverage.Containments = append(coverage.Containments, other.Coverage.Containments...)
+
+	// The average is weighted by the geometries each tile checked
+	preservation := r.BoundaryPreservation
+	if preservation == nil || other.BoundaryPreservation == nil {
+		return
+	}
+	total := preservation.TotalGeometries + other.BoundaryPreservation.TotalGeometries
+	if total > 0 {
+		preservation.AverageDistortion = (preservation.AverageDistortion*float64(preservation.TotalGeometries) +
+			other.BoundaryPreservation.AverageDistortion*float64(other.BoundaryPreservation.TotalGeometries)) / float64(total)
+	}
+	preservation.TotalGeometries = total
+	preservation.SignificantChanges += other.BoundaryPreservation.SignificantChanges
+	preservation.MaxDistortion = max(preservation.MaxDistortion, other.BoundaryPreservation.MaxDistortion)
+}
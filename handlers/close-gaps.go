@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+// MaxCloseGapsToleranceMeters caps the buffer distance used by CloseGaps. The
+// closing operation joins anything closer than the tolerance, so a large value
+// would start filling genuine gaps such as paths between parcels.
+const MaxCloseGapsToleranceMeters = 2.0
+
+// GapClosingReport summarises the gaps filled by CloseGaps
+type GapClosingReport struct {
+	GapPieces       int     `json:"gapPieces"`
+	UnassignedGaps  int     `json:"unassignedGaps"`
+	ClosedGapArea   float64 `json:"closedGapArea"`   // square degrees
+	ClosedGapAreaM2 float64 `json:"closedGapAreaM2"` // square meters
+}
+
+// GapClosingResult is a FeatureCollection with the gap closing report attached
+type GapClosingResult struct {
+	Type     string           `json:"type"`
+	Features []Feature        `json:"features"`
+	Report   GapClosingReport `json:"report"`
+}
+
+// CloseGaps closes small gaps across the whole coverage with a morphological
+// closing: every polygon is buffered out by half the tolerance, unioned, and
+// buffered back in. Mitred joins keep corners sharp so the outer boundary is
+// restored. The area the closing added is split into pieces, and each piece is
+// merged into the neighbouring feature it shares the most boundary with, so
+// distinct parcels are never merged with each other.
+func CloseGaps(features []Feature, toleranceMeters float64, quadSegs int) (*GapClosingResult, error) {
+	if toleranceMeters <= 0 || toleranceMeters > MaxCloseGapsToleranceMeters {
+		return nil, fmt.Errorf("tolerance must be greater than 0 and at most %.1f meters, got %f", MaxCloseGapsToleranceMeters, toleranceMeters)
+	}
+	tolerance := utils.CalculateWGS84ToleranceFromMeters(toleranceMeters)
+
+	// Parse polygonal features, keeping their input position
+	geomFeatures := make([]GeomFeature, 0, len(features))
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+		if geom.TypeID() != geos.TypeIDPolygon && geom.TypeID() != geos.TypeIDMultiPolygon {
+			log.Printf("Skipping non-polygon feature %d (type: %s)", i, geom.Type())
+			geom.Destroy()
+			continue
+		}
+		geomFeatures = append(geomFeatures, GeomFeature{Geom: geom, Properties: feature.Properties})
+	}
+	defer func() {
+		for _, geomFeature := range geomFeatures {
+			geomFeature.Geom.Destroy()
+		}
+	}()
+
+	if len(geomFeatures) == 0 {
+		return nil, fmt.Errorf("no valid polygon geometries found")
+	}
+
+	clones := make([]*geos.Geom, len(geomFeatures))
+	for i, geomFeature := range geomFeatures {
+		clones[i] = geomFeature.Geom.Clone()
+	}
+	collection := geos.NewCollection(geos.TypeIDGeometryCollection, clones)
+	union := collection.UnaryUnion()
+	collection.Destroy()
+	if union == nil {
+		return nil, fmt.Errorf("failed to union geometries")
+	}
+	defer union.Destroy()
+
+	expanded := union.BufferWithStyle(tolerance/2, quadSegs, geos.BufCapStyleFlat, geos.BufJoinStyleMitre, 5)
+	if expanded == nil {
+		return nil, fmt.Errorf("failed to buffer coverage")
+	}
+	closed := expanded.BufferWithStyle(-tolerance/2, quadSegs, geos.BufCapStyleFlat, geos.BufJoinStyleMitre, 5)
+	expanded.Destroy()
+	if closed == nil {
+		return nil, fmt.Errorf("failed to debuffer coverage")
+	}
+	defer closed.Destroy()
+
+	gaps := closed.Difference(union)
+	if gaps == nil {
+		return nil, fmt.Errorf("failed to extract closed gaps")
+	}
+	defer gaps.Destroy()
+
+	// Index features so each gap piece only tests its immediate neighbours
+	spatialIndex := utils.NewSpatialIndex(tolerance * 100)
+	spatialIndex.SetQuadSegs(quadSegs)
+	for i, geomFeature := range geomFeatures {
+		if err := spatialIndex.AddGeometry(geomFeature.Geom, i, geomFeature.Properties); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	report := GapClosingReport{}
+	assigned := make([][]*geos.Geom, len(geomFeatures))
+	contactDistance := tolerance / 100
+
+	for i := range gaps.NumGeometries() {
+		piece := gaps.Geometry(i)
+		if piece.TypeID() != geos.TypeIDPolygon || piece.Area() == 0 {
+			continue
+		}
+		report.GapPieces++
+
+		// Pick the neighbour sharing the longest boundary with the piece
+		bestIndex := -1
+		bestContact := 0.0
+		for _, neighbor := range spatialIndex.FindNeighbors(piece, contactDistance) {
+			buffered := neighbor.Geom.Buffer(contactDistance, quadSegs)
+			contact := buffered.Intersection(piece)
+			buffered.Destroy()
+			if contact == nil {
+				continue
+			}
+			if area := contact.Area(); area > bestContact || (area == bestContact && bestIndex >= 0 && neighbor.Index < bestIndex) {
+				bestContact = area
+				bestIndex = neighbor.Index
+			}
+			contact.Destroy()
+		}
+
+		if bestIndex < 0 {
+			report.UnassignedGaps++
+			continue
+		}
+
+		assigned[bestIndex] = append(assigned[bestIndex], piece.Clone())
+		report.ClosedGapArea += piece.Area()
+		report.ClosedGapAreaM2 += utils.GeodesicArea(piece)
+	}
+
+	result := &GapClosingResult{
+		Type:     "FeatureCollection",
+		Features: make([]Feature, 0, len(geomFeatures)),
+		Report:   report,
+	}
+
+	for i, geomFeature := range geomFeatures {
+		geom := geomFeature.Geom
+		if len(assigned[i]) > 0 {
+			parts := append([]*geos.Geom{geom.Clone()}, assigned[i]...)
+			merged := geos.NewCollection(geos.TypeIDGeometryCollection, parts)
+			filled := merged.UnaryUnion()
+			merged.Destroy()
+			if filled != nil {
+				result.Features = append(result.Features, newGeomFeature(filled, geomFeature.Properties))
+				filled.Destroy()
+				continue
+			}
+			log.Printf("Failed to merge gap pieces into feature %d, keeping original", i)
+		}
+		result.Features = append(result.Features, newGeomFeature(geom, geomFeature.Properties))
+	}
+
+	log.Printf("Closed %d gap pieces (%.2f m²), %d left unassigned",
+		report.GapPieces-report.UnassignedGaps, report.ClosedGapAreaM2, report.UnassignedGaps)
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/twpayne/go-geos"
+)
+
+// CollectFeatures groups features sharing the same key property into one
+// MultiPolygon feature per key, the inverse of ExplodeFeatures. Groups are
+// emitted in order of their first feature, whose properties they carry.
+// Non-polygon parts are dropped, and features without the key are passed
+// through unchanged. With includeCentroid each group also carries the
+// centroid of the union of its parts in CentroidProperty.
+func CollectFeatures(features []Feature, key string, includeCentroid bool) ([]Feature, error) {
+	if key == "" {
+		return nil, fmt.Errorf("a key property to collect by is required")
+	}
+
+	type group struct {
+		first Feature
+		parts []*geos.Geom
+	}
+	groups := make(map[string]*group)
+	order := make([]string, 0)
+	collected := make([]Feature, 0, len(features))
+	// Pass-through features are placed after all groups, in input order
+	unkeyed := make([]Feature, 0)
+
+	for i, feature := range features {
+		value, ok := propertyKey(feature, key)
+		if !ok {
+			log.Printf("Feature %d has no %q property, passing it through", i, key)
+			unkeyed = append(unkeyed, feature)
+			continue
+		}
+
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+
+		g, exists := groups[value]
+		if !exists {
+			g = &group{first: feature}
+			groups[value] = g
+			order = append(order, value)
+		}
+		collectPolygons(geom, &g.parts)
+		geom.Destroy()
+	}
+
+	for _, value := range order {
+		g := groups[value]
+
+		// The collection takes ownership of the parts
+		var multiPolygon *geos.Geom
+		if len(g.parts) > 0 {
+			multiPolygon = geos.NewCollection(geos.TypeIDMultiPolygon, g.parts)
+		} else {
+			multiPolygon = geos.NewEmptyCollection(geos.TypeIDMultiPolygon)
+		}
+
+		// The first feature's properties include the key itself
+		properties := copyProperties(g.first.Properties)
+		if includeCentroid {
+			// Parts of a group may overlap, which would skew the centroid
+			union := multiPolygon.UnaryUnion()
+			if centroid, ok := CentroidPoint(union); ok {
+				properties[CentroidProperty] = centroid
+			}
+			union.Destroy()
+		}
+		collected = append(collected, newGeomFeature(multiPolygon, properties))
+		multiPolygon.Destroy()
+	}
+
+	return append(collected, unkeyed...), nil
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// keyedFeature returns a feature with the given GeoJSON geometry and properties
+func keyedFeature(geometry string, properties map[string]interface{}) Feature {
+	return Feature{Type: "Feature", Geometry: json.RawMessage(geometry), Properties: properties}
+}
+
+// multiPolygonParts returns the number of polygons in a MultiPolygon geometry
+func multiPolygonParts(t testing.TB, geometry json.RawMessage) int {
+	t.Helper()
+	var multiPolygon struct {
+		Type        string          `json:"type"`
+		Coordinates [][][][]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(geometry, &multiPolygon); err != nil || multiPolygon.Type != "MultiPolygon" {
+		t.Fatalf("geometry %s is not a MultiPolygon", geometry)
+	}
+	return len(multiPolygon.Coordinates)
+}
+
+func TestCollectFeatures(t *testing.T) {
+	const line = `{"type":"LineString","coordinates":[[0,0],[1,1]]}`
+
+	tests := []struct {
+		name      string
+		features  []Feature
+		wantNames []string
+		wantParts []int // -1 for a feature passed through unchanged
+	}{
+		{
+			name: "two groups",
+			features: []Feature{
+				keyedFeature(westSquare, map[string]interface{}{"parcelId": "A", "name": "west"}),
+				keyedFeature(westSquare, map[string]interface{}{"parcelId": "B", "name": "other"}),
+				keyedFeature(eastSquare, map[string]interface{}{"parcelId": "A", "name": "east"}),
+			},
+			wantNames: []string{"west", "other"},
+			wantParts: []int{2, 1},
+		},
+		{
+			name: "numeric keys group by value",
+			features: []Feature{
+				keyedFeature(westSquare, map[string]interface{}{"parcelId": 7.0, "name": "first"}),
+				keyedFeature(eastSquare, map[string]interface{}{"parcelId": 7.0, "name": "second"}),
+			},
+			wantNames: []string{"first"},
+			wantParts: []int{2},
+		},
+		{
+			name: "features without the key come last",
+			features: []Feature{
+				keyedFeature(westSquare, map[string]interface{}{"name": "unkeyed"}),
+				keyedFeature(eastSquare, map[string]interface{}{"parcelId": "A", "name": "keyed"}),
+				keyedFeature(eastSquare, map[string]interface{}{"parcelId": nil, "name": "null key"}),
+			},
+			wantNames: []string{"keyed", "unkeyed", "null key"},
+			wantParts: []int{1, -1, -1},
+		},
+		{
+			name: "non-polygon parts are dropped",
+			features: []Feature{
+				keyedFeature(westSquare, map[string]interface{}{"parcelId": "A", "name": "square"}),
+				keyedFeature(line, map[string]interface{}{"parcelId": "A", "name": "line"}),
+			},
+			wantNames: []string{"square"},
+			wantParts: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collected, err := CollectFeatures(tt.features, "parcelId", false)
+			if err != nil {
+				t.Fatalf("CollectFeatures: %v", err)
+			}
+			if len(collected) != len(tt.wantNames) {
+				t.Fatalf("collected %d features, want %d", len(collected), len(tt.wantNames))
+			}
+			for i, feature := range collected {
+				if name := feature.Properties["name"]; name != tt.wantNames[i] {
+					t.Errorf("feature %d name = %v, want %q", i, name, tt.wantNames[i])
+				}
+				if tt.wantParts[i] < 0 {
+					continue
+				}
+				if parts := multiPolygonParts(t, feature.Geometry); parts != tt.wantParts[i] {
+					t.Errorf("feature %d has %d parts, want %d", i, parts, tt.wantParts[i])
+				}
+				if _, ok := feature.Properties["parcelId"]; !ok {
+					t.Errorf("feature %d lost its parcelId key", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectFeaturesRequiresKey(t *testing.T) {
+	if _, err := CollectFeatures(rawFeatures(westSquare), "", false); err == nil {
+		t.Error("CollectFeatures accepted an empty key")
+	}
+}
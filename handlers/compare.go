@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+// ComparisonPair quantifies how a matched feature differs between two layers
+type ComparisonPair struct {
+	Key                string  `json:"key"`
+	HausdorffDistance  float64 `json:"hausdorffDistance"`  // degrees
+	HausdorffDistanceM float64 `json:"hausdorffDistanceM"` // approximate meters
+	ReferenceAreaM2    float64 `json:"referenceAreaM2"`
+	CandidateAreaM2    float64 `json:"candidateAreaM2"`
+	AreaDifferenceM2   float64 `json:"areaDifferenceM2"`
+	AreaChangeRatio    float64 `json:"areaChangeRatio"`
+}
+
+// ComparisonReport lists per-feature similarity and features present in only one layer
+type ComparisonReport struct {
+	Key                string           `json:"key"`
+	Matched            []ComparisonPair `json:"matched"`
+	UnmatchedReference []string         `json:"unmatchedReference"`
+	UnmatchedCandidate []string         `json:"unmatchedCandidate"`
+}
+
+// CompareLayers matches reference and candidate features by a key property and
+// reports the Hausdorff distance and geodesic area difference, in m², of every
+// matched pair
+func CompareLayers(reference, candidate []Feature, key string) (*ComparisonReport, error) {
+	if key == "" {
+		return nil, fmt.Errorf("a key property is required to match features")
+	}
+
+	referenceGeoms := geometriesByKey(reference, key, "reference")
+	defer destroyKeyedGeometries(referenceGeoms)
+
+	return compareKeyedLayers(referenceGeoms, candidate, key), nil
+}
+
+// CompareWithReference is CompareLayers against a cached reference layer, whose
+// geometries are reused rather than parsed again
+func CompareWithReference(reference *ReferenceLayer, candidate []Feature, key string) (*ComparisonReport, error) {
+	if key == "" {
+		return nil, fmt.Errorf("a key property is required to match features")
+	}
+
+	// The cache owns these geometries, so they are not destroyed here
+	referenceGeoms := keyGeometries(reference.Features, key, "reference", func(i int, _ Feature) (*geos.Geom, error) {
+		return reference.geoms[i], reference.errs[i]
+	})
+
+	return compareKeyedLayers(referenceGeoms, candidate, key), nil
+}
+
+// compareKeyedLayers reports the differences between keyed reference geometries and a candidate layer
+func compareKeyedLayers(referenceGeoms *keyedGeometries, candidate []Feature, key string) *ComparisonReport {
+	candidateGeoms := geometriesByKey(candidate, key, "candidate")
+	defer destroyKeyedGeometries(candidateGeoms)
+
+	report := &ComparisonReport{
+		Key:                key,
+		Matched:            make([]ComparisonPair, 0),
+		UnmatchedReference: make([]string, 0),
+		UnmatchedCandidate: make([]string, 0),
+	}
+
+	for _, keyed := range referenceGeoms.ordered {
+		candidateGeom, ok := candidateGeoms.byKey[keyed.key]
+		if !ok {
+			report.UnmatchedReference = append(report.UnmatchedReference, keyed.key)
+			continue
+		}
+
+		hausdorff := keyed.geom.HausdorffDistance(candidateGeom)
+		referenceArea := utils.GeodesicArea(keyed.geom)
+		candidateArea := utils.GeodesicArea(candidateGeom)
+		changeRatio := 0.0
+		if referenceArea != 0 {
+			changeRatio = math.Abs(candidateArea-referenceArea) / referenceArea
+		}
+
+		report.Matched = append(report.Matched, ComparisonPair{
+			Key:                keyed.key,
+			HausdorffDistance:  hausdorff,
+			HausdorffDistanceM: utils.CalculateMetersFromWGS84Degrees(hausdorff),
+			ReferenceAreaM2:    referenceArea,
+			CandidateAreaM2:    candidateArea,
+			AreaDifferenceM2:   candidateArea - referenceArea,
+			AreaChangeRatio:    changeRatio,
+		})
+	}
+
+	for _, keyed := range candidateGeoms.ordered {
+		if _, ok := referenceGeoms.byKey[keyed.key]; !ok {
+			report.UnmatchedCandidate = append(report.UnmatchedCandidate, keyed.key)
+		}
+	}
+
+	return report
+}
+
+type keyedGeometry struct {
+	key  string
+	geom *geos.Geom
+}
+
+type keyedGeometries struct {
+	ordered []keyedGeometry
+	byKey   map[string]*geos.Geom
+}
+
+// geometriesByKey parses a layer's geometries indexed by key, keeping input order
+func geometriesByKey(features []Feature, key string, layerName string) *keyedGeometries {
+	return keyGeometries(features, key, layerName, func(_ int, feature Feature) (*geos.Geom, error) {
+		return parseFeatureGeometry(feature)
+	})
+}
+
+// keyGeometries indexes a layer's geometries by key, obtaining each feature's geometry from geometryOf
+func keyGeometries(features []Feature, key string, layerName string, geometryOf func(int, Feature) (*geos.Geom, error)) *keyedGeometries {
+	keyed := &keyedGeometries{
+		ordered: make([]keyedGeometry, 0, len(features)),
+		byKey:   make(map[string]*geos.Geom, len(features)),
+	}
+
+	for i, feature := range features {
+		value, ok := propertyKey(feature, key)
+		if !ok {
+			log.Printf("Skipping %s feature %d: missing key property %q", layerName, i, key)
+			continue
+		}
+		if _, exists := keyed.byKey[value]; exists {
+			log.Printf("Skipping %s feature %d: duplicate key %q", layerName, i, value)
+			continue
+		}
+
+		geom, err := geometryOf(i, feature)
+		if err != nil {
+			log.Printf("Skipping %s feature %d: %v", layerName, i, err)
+			continue
+		}
+
+		keyed.ordered = append(keyed.ordered, keyedGeometry{key: value, geom: geom})
+		keyed.byKey[value] = geom
+	}
+
+	return keyed
+}
+
+func destroyKeyedGeometries(keyed *keyedGeometries) {
+	for _, entry := range keyed.ordered {
+		entry.geom.Destroy()
+	}
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/twpayne/go-geos"
+)
+
+// ConcaveHull computes a concave hull around the union of all feature geometries.
+//
+// ratio controls the trade-off between tightness and smoothness: 0 produces the
+// tightest, most concave hull that closely follows the input (and can become
+// jagged), while 1 produces the convex hull. Values around 0.2-0.4 usually give
+// a realistic service-area footprint. allowHoles lets the hull contain holes
+// where the input leaves large empty areas.
+func ConcaveHull(features []Feature, ratio float64, allowHoles bool) (*geos.Geom, error) {
+	if ratio < 0 || ratio > 1 {
+		return nil, fmt.Errorf("ratio must be between 0 and 1, got %f", ratio)
+	}
+
+	union, err := unionFeatures(features)
+	if err != nil {
+		return nil, err
+	}
+	defer union.Destroy()
+
+	holes := uint(0)
+	if allowHoles {
+		holes = 1
+	}
+
+	hull := union.ConcaveHull(ratio, holes)
+	if hull == nil {
+		return nil, fmt.Errorf("failed to compute concave hull")
+	}
+
+	return hull, nil
+}
+
+// unionFeatures parses all feature geometries and returns their unary union
+func unionFeatures(features []Feature) (*geos.Geom, error) {
+	geoms := make([]*geos.Geom, 0, len(features))
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+		geoms = append(geoms, geom)
+	}
+
+	if len(geoms) == 0 {
+		return nil, fmt.Errorf("no valid geometries found")
+	}
+
+	// The collection takes ownership of the parsed geometries
+	collection := geos.NewCollection(geos.TypeIDGeometryCollection, geoms)
+	defer collection.Destroy()
+
+	union := collection.UnaryUnion()
+	if union == nil {
+		return nil, fmt.Errorf("failed to union geometries")
+	}
+
+	return union, nil
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/twpayne/go-geos"
+)
+
+// Property merge strategies for duplicate geometries
+const (
+	// DedupeKeepFirst keeps the properties of the first duplicate in input order
+	DedupeKeepFirst = "keepFirst"
+	// DedupeKeepLast keeps the properties of the last duplicate in input order
+	DedupeKeepLast = "keepLast"
+	// DedupeMerge combines the properties of all duplicates, earlier values winning conflicts
+	DedupeMerge = "merge"
+)
+
+// DedupeGeometries collapses geometrically equal features into one, combining
+// their properties according to strategy. Candidates are grouped by their exact
+// bounding box, which equal geometries share, so only features with identical
+// bounds are compared with Equals. Dropped geometries are destroyed. It returns
+// the remaining features and, for each, its position in geomFeatures.
+func DedupeGeometries(geomFeatures []GeomFeature, strategy string) ([]GeomFeature, []int) {
+	byBounds := make(map[geos.Box2D][]int)
+	kept := make([]int, 0, len(geomFeatures))
+	merged := make(map[int]map[string]interface{})
+
+	for i, geomFeature := range geomFeatures {
+		bounds := *geomFeature.Geom.Bounds()
+
+		duplicateOf := -1
+		for _, candidate := range byBounds[bounds] {
+			if geomFeatures[candidate].Geom.Equals(geomFeature.Geom) {
+				duplicateOf = candidate
+				break
+			}
+		}
+
+		if duplicateOf < 0 {
+			byBounds[bounds] = append(byBounds[bounds], i)
+			kept = append(kept, i)
+			continue
+		}
+
+		log.Printf("Feature %d duplicates the geometry of feature %d", i, duplicateOf)
+		properties, ok := merged[duplicateOf]
+		if !ok {
+			properties = geomFeatures[duplicateOf].Properties
+		}
+		merged[duplicateOf] = mergeDuplicateProperties(properties, geomFeature.Properties, strategy)
+		geomFeature.Geom.Destroy()
+	}
+
+	result := make([]GeomFeature, 0, len(kept))
+	for _, index := range kept {
+		geomFeature := geomFeatures[index]
+		if properties, ok := merged[index]; ok {
+			geomFeature.Properties = properties
+		}
+		result = append(result, geomFeature)
+	}
+
+	return result, kept
+}
+
+// mergeDuplicateProperties combines the properties of a kept feature with those of a later duplicate
+func mergeDuplicateProperties(kept, duplicate map[string]interface{}, strategy string) map[string]interface{} {
+	switch strategy {
+	case DedupeKeepLast:
+		return duplicate
+	case DedupeMerge:
+		properties := copyProperties(kept)
+		for key, value := range duplicate {
+			if _, exists := properties[key]; !exists {
+				properties[key] = value
+			}
+		}
+		return properties
+	default:
+		return kept
+	}
+}
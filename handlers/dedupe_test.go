@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+)
+
+func TestDedupeGeometries(t *testing.T) {
+	const (
+		square        = "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))"
+		squareRotated = "POLYGON ((1 1, 0 1, 0 0, 1 0, 1 1))"
+		neighbour     = "POLYGON ((1 0, 2 0, 2 1, 1 1, 1 0))"
+	)
+	first := map[string]interface{}{"name": "first", "owner": "a"}
+	last := map[string]interface{}{"name": "last", "area": 1.0}
+	other := map[string]interface{}{"name": "neighbour"}
+
+	tests := []struct {
+		name       string
+		wkts       []string
+		properties []map[string]interface{}
+		strategy   string
+		kept       []int
+		want       []map[string]interface{}
+	}{
+		{
+			name:       "no duplicates",
+			wkts:       []string{square, neighbour},
+			properties: []map[string]interface{}{first, other},
+			strategy:   DedupeKeepFirst,
+			kept:       []int{0, 1},
+			want:       []map[string]interface{}{first, other},
+		},
+		{
+			name:       "keep first",
+			wkts:       []string{square, neighbour, square},
+			properties: []map[string]interface{}{first, other, last},
+			strategy:   DedupeKeepFirst,
+			kept:       []int{0, 1},
+			want:       []map[string]interface{}{first, other},
+		},
+		{
+			name:       "keep last",
+			wkts:       []string{square, neighbour, square},
+			properties: []map[string]interface{}{first, other, last},
+			strategy:   DedupeKeepLast,
+			kept:       []int{0, 1},
+			want:       []map[string]interface{}{last, other},
+		},
+		{
+			name:       "merge",
+			wkts:       []string{square, square, neighbour},
+			properties: []map[string]interface{}{first, last, other},
+			strategy:   DedupeMerge,
+			kept:       []int{0, 2},
+			want:       []map[string]interface{}{{"name": "first", "owner": "a", "area": 1.0}, other},
+		},
+		{
+			name:       "same polygon from another start vertex",
+			wkts:       []string{square, squareRotated},
+			properties: []map[string]interface{}{first, last},
+			strategy:   DedupeKeepFirst,
+			kept:       []int{0},
+			want:       []map[string]interface{}{first},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// DedupeGeometries destroys the duplicates, so only the result is freed here
+			features := make([]GeomFeature, len(tt.wkts))
+			for i, wkt := range tt.wkts {
+				geom, err := geos.NewGeomFromWKT(wkt)
+				if err != nil {
+					t.Fatalf("parsing %q: %v", wkt, err)
+				}
+				features[i] = GeomFeature{Geom: geom, Properties: tt.properties[i]}
+			}
+
+			deduped, kept := DedupeGeometries(features, tt.strategy)
+			defer destroyGeomFeatures(deduped)
+
+			if !reflect.DeepEqual(kept, tt.kept) {
+				t.Errorf("kept %v, want %v", kept, tt.kept)
+			}
+			if len(deduped) != len(tt.want) {
+				t.Fatalf("%d features after dedupe, want %d", len(deduped), len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !reflect.DeepEqual(deduped[i].Properties, want) {
+					t.Errorf("feature %d properties = %v, want %v", i, deduped[i].Properties, want)
+				}
+			}
+		})
+	}
+}
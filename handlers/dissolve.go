@@ -1,7 +1,14 @@
 package handlers
 
-import "github.com/twpayne/go-geos"
+import (
+	"encoding/json"
 
+	"github.com/twpayne/go-geos"
+)
+
+// CascadedUnion unions geometries pairwise, splitting the slice in halves so
+// the merge tree depends only on input positions; the same input always gives
+// the same result. It destroys its inputs as it merges them.
 func CascadedUnion(geometries []*geos.Geom) (*geos.Geom, error) {
 	// Base case: if there is only one geometry, return it
 	if len(geometries) == 1 {
@@ -28,3 +35,42 @@ func CascadedUnion(geometries []*geos.Geom) (*geos.Geom, error) {
 
 	return result, nil
 }
+
+// MergeToMultiPolygon collects every polygon part of geom, descending into
+// collections, into a single MultiPolygon. Points and lines are dropped, so the
+// result is always areal, which renderers expecting one shape per area rely on.
+func MergeToMultiPolygon(geom *geos.Geom) *geos.Geom {
+	parts := make([]*geos.Geom, 0)
+	collectPolygons(geom, &parts)
+	if len(parts) == 0 {
+		return geos.NewEmptyCollection(geos.TypeIDMultiPolygon)
+	}
+	return geos.NewCollection(geos.TypeIDMultiPolygon, parts)
+}
+
+// collectPolygons appends clones of the polygons in geom to parts
+func collectPolygons(geom *geos.Geom, parts *[]*geos.Geom) {
+	switch geom.TypeID() {
+	case geos.TypeIDPolygon:
+		if !geom.IsEmpty() {
+			*parts = append(*parts, geom.Clone())
+		}
+	case geos.TypeIDMultiPolygon, geos.TypeIDGeometryCollection:
+		for i := 0; i < geom.NumGeometries(); i++ {
+			collectPolygons(geom.Geometry(i), parts)
+		}
+	}
+}
+
+// NewMergedFeature wraps a merged dissolve result as a single feature carrying
+// the caller-supplied properties, or none
+func NewMergedFeature(geom *geos.Geom, properties map[string]interface{}) Feature {
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+	return Feature{
+		Type:       "Feature",
+		Geometry:   json.RawMessage(geom.ToGeoJSON(-1)),
+		Properties: properties,
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+func TestMergeToMultiPolygon(t *testing.T) {
+	tests := []struct {
+		name  string
+		wkt   string
+		parts int
+		area  float64
+	}{
+		{"polygon", "POLYGON ((0 0, 2 0, 2 2, 0 2, 0 0))", 1, 4},
+		{"multipolygon", "MULTIPOLYGON (((0 0, 1 0, 1 1, 0 1, 0 0)), ((2 0, 3 0, 3 1, 2 1, 2 0)))", 2, 2},
+		{"collection with lines and points", "GEOMETRYCOLLECTION (POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0)), LINESTRING (5 5, 6 6), POINT (9 9))", 1, 1},
+		{"nested collections", "GEOMETRYCOLLECTION (GEOMETRYCOLLECTION (POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))), MULTIPOLYGON (((2 0, 3 0, 3 1, 2 1, 2 0))))", 2, 2},
+		{"polygon with hole", "POLYGON ((0 0, 4 0, 4 4, 0 4, 0 0), (1 1, 1 2, 2 2, 2 1, 1 1))", 1, 15},
+		{"no areal parts", "GEOMETRYCOLLECTION (LINESTRING (0 0, 1 1), POINT (2 2))", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom := mustGeomFromWKT(t, tt.wkt)
+			merged := MergeToMultiPolygon(geom)
+			defer merged.Destroy()
+
+			if merged.TypeID() != geos.TypeIDMultiPolygon {
+				t.Errorf("merged type = %v, want MultiPolygon", merged.Type())
+			}
+			if merged.NumGeometries() != tt.parts {
+				t.Errorf("merged into %d parts, want %d: %s", merged.NumGeometries(), tt.parts, merged.ToWKT())
+			}
+			if area := merged.Area(); area != tt.area {
+				t.Errorf("merged area = %v, want %v", area, tt.area)
+			}
+		})
+	}
+}
+
+func TestNewMergedFeature(t *testing.T) {
+	tests := []struct {
+		name       string
+		properties map[string]interface{}
+		want       int
+	}{
+		{"no properties", nil, 0},
+		{"caller properties", map[string]interface{}{"name": "district"}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom := mustGeomFromWKT(t, "MULTIPOLYGON (((0 0, 1 0, 1 1, 0 1, 0 0)))")
+			feature := NewMergedFeature(geom, tt.properties)
+
+			if feature.Properties == nil || len(feature.Properties) != tt.want {
+				t.Errorf("properties = %v, want %d entries", feature.Properties, tt.want)
+			}
+			var geometry struct {
+				Type string `json:"type"`
+			}
+			if err := json.Unmarshal(feature.Geometry, &geometry); err != nil || geometry.Type != "MultiPolygon" {
+				t.Errorf("geometry = %s, want a MultiPolygon", feature.Geometry)
+			}
+		})
+	}
+}
+
+func TestDissolveReproducible(t *testing.T) {
+	defects := utils.GridDefects{GapRatio: 0.1, OverlapRatio: 0.1, InvalidRatio: 0.05, Seed: 42}
+	grids := []struct {
+		name    string
+		payload []byte
+	}{
+		{"clean grid", utils.GenerateGrid(12, 15, 0.001)},
+		{"grid with gaps, overlaps and bow-ties", utils.GenerateGridWithDefects(12, 15, 0.001, defects)},
+	}
+
+	for _, grid := range grids {
+		for _, method := range []string{UnionMethodUnary, UnionMethodCascaded} {
+			t.Run(grid.name+"/"+method, func(t *testing.T) {
+				features := generatedGeomFeatures(t, grid.payload)
+
+				var first []byte
+				for run := range 2 {
+					// Bow-ties are made valid first, as the dissolve handler does
+					geoms := make([]*geos.Geom, len(features))
+					for i, feature := range features {
+						geoms[i] = feature.Geom.Buffer(0, 0)
+					}
+					union, err := UnionGeometries(geoms, method)
+					if err != nil {
+						t.Fatalf("run %d: %v", run, err)
+					}
+					wkb := union.ToWKB()
+					union.Destroy()
+
+					if run == 0 {
+						first = wkb
+					} else if !bytes.Equal(wkb, first) {
+						t.Errorf("second dissolve gave different WKB (%d bytes, first %d bytes)", len(wkb), len(first))
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+)
+
+// ExplodeFeatures splits every multi-part feature into one feature per part,
+// the inverse of dissolve's collect step. Each part carries a copy of its
+// feature's properties plus a zero-based _part_index; single-part features
+// come through with _part_index 0.
+func ExplodeFeatures(features []Feature) []Feature {
+	exploded := make([]Feature, 0, len(features))
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+
+		parts := utils.Explode(geom)
+		geom.Destroy()
+
+		for partIndex, part := range parts {
+			properties := copyProperties(feature.Properties)
+			properties["_part_index"] = partIndex
+
+			exploded = append(exploded, newGeomFeature(part, properties))
+			part.Destroy()
+		}
+	}
+
+	return exploded
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExplodeFeatures(t *testing.T) {
+	const threeParts = `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,0],[3,0],[3,1],[2,0]]],[[[4,0],[5,0],[5,1],[4,0]]]]}`
+
+	tests := []struct {
+		name      string
+		features  []Feature
+		wantParts []int
+		wantIDs   []float64
+	}{
+		{
+			name:      "three-part multipolygon",
+			features:  rawFeatures(threeParts),
+			wantParts: []int{0, 1, 2},
+			wantIDs:   []float64{0, 0, 0},
+		},
+		{
+			name:      "single polygon keeps part index 0",
+			features:  rawFeatures(westSquare),
+			wantParts: []int{0},
+			wantIDs:   []float64{0},
+		},
+		{
+			name:      "features are exploded in order",
+			features:  rawFeatures(westSquare, threeParts, eastSquare),
+			wantParts: []int{0, 0, 1, 2, 0},
+			wantIDs:   []float64{0, 1, 1, 1, 2},
+		},
+		{
+			name:      "unparseable feature is skipped",
+			features:  rawFeatures(`{"type":"Polygon","coordinates":"nope"}`, eastSquare),
+			wantParts: []int{0},
+			wantIDs:   []float64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Round-trip the input properties through JSON as a request would
+			payload, err := json.Marshal(tt.features)
+			if err != nil {
+				t.Fatalf("encoding features: %v", err)
+			}
+			var features []Feature
+			if err := json.Unmarshal(payload, &features); err != nil {
+				t.Fatalf("decoding features: %v", err)
+			}
+
+			exploded := ExplodeFeatures(features)
+			if len(exploded) != len(tt.wantParts) {
+				t.Fatalf("exploded into %d features, want %d", len(exploded), len(tt.wantParts))
+			}
+			for i, feature := range exploded {
+				if got := feature.Properties["_part_index"]; got != tt.wantParts[i] {
+					t.Errorf("feature %d _part_index = %v, want %d", i, got, tt.wantParts[i])
+				}
+				if got := feature.Properties["id"]; got != tt.wantIDs[i] {
+					t.Errorf("feature %d id = %v, want %v", i, got, tt.wantIDs[i])
+				}
+				var geometry struct {
+					Type string `json:"type"`
+				}
+				if err := json.Unmarshal(feature.Geometry, &geometry); err != nil || geometry.Type != "Polygon" {
+					t.Errorf("feature %d geometry = %s, want a Polygon", i, feature.Geometry)
+				}
+			}
+
+			// Parts get their own property maps
+			if len(exploded) > 1 {
+				exploded[0].Properties["edited"] = true
+				if _, shared := exploded[1].Properties["edited"]; shared {
+					t.Error("exploded parts share one properties map")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/twpayne/go-geos"
+)
+
+// SortByIndex keeps features in input order when used as a sort key
+const SortByIndex = "index"
+
+// FeatureCollection is a GeoJSON FeatureCollection returned by the geometry endpoints
+type FeatureCollection struct {
+	Type     string    `json:"type"`
+	Features []Feature `json:"features"`
+}
+
+// NewFeatureCollection wraps features in a GeoJSON FeatureCollection
+func NewFeatureCollection(features []Feature) *FeatureCollection {
+	if features == nil {
+		features = make([]Feature, 0)
+	}
+
+	return &FeatureCollection{
+		Type:     "FeatureCollection",
+		Features: features,
+	}
+}
+
+// ParseFeatureCollection decodes a GeoJSON FeatureCollection payload
+func ParseFeatureCollection(geometryPayload string) ([]Feature, error) {
+	var featureCollection FeatureCollection
+	if err := json.Unmarshal([]byte(geometryPayload), &featureCollection); err != nil {
+		return nil, fmt.Errorf("failed to parse feature collection: %v", err)
+	}
+
+	return featureCollection.Features, nil
+}
+
+// ParseLayers decodes a multi-layer payload of the form {"layers": [FeatureCollection, ...]}
+func ParseLayers(geometryPayload string) ([][]Feature, error) {
+	var payload struct {
+		Layers []FeatureCollection `json:"layers"`
+	}
+	if err := json.Unmarshal([]byte(geometryPayload), &payload); err != nil {
+		return nil, fmt.Errorf("failed to parse layers: %v", err)
+	}
+
+	layers := make([][]Feature, len(payload.Layers))
+	for i, layer := range payload.Layers {
+		layers[i] = layer.Features
+	}
+	return layers, nil
+}
+
+// propertyKey returns a feature's key property as a string, and whether it was present
+func propertyKey(feature Feature, key string) (string, bool) {
+	value, ok := feature.Properties[key]
+	if !ok || value == nil {
+		return "", false
+	}
+	return fmt.Sprint(value), true
+}
+
+// parseFeatureGeometry creates a GEOS geometry from a feature's GeoJSON geometry
+func parseFeatureGeometry(feature Feature) (*geos.Geom, error) {
+	return geos.NewGeomFromGeoJSON(string(feature.Geometry))
+}
+
+// newGeomFeature builds an output feature from a geometry and properties
+func newGeomFeature(geom *geos.Geom, properties map[string]interface{}) Feature {
+	return Feature{
+		Type:       "Feature",
+		Properties: properties,
+		Geometry:   json.RawMessage(geom.ToGeoJSON(-1)),
+	}
+}
+
+// copyProperties returns a shallow copy of properties with room for extra keys,
+// so derived outputs can be tagged without mutating the input feature
+func copyProperties(properties map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(properties)+1)
+	for key, value := range properties {
+		copied[key] = value
+	}
+	return copied
+}
+
+// SelectProperties returns a copy of properties holding only the listed keys,
+// plus the underscore-prefixed annotations the service adds, such as
+// RepairMethodProperty, which other options ask for explicitly. Listed keys a
+// feature lacks are skipped. An empty list returns properties unchanged.
+func SelectProperties(properties map[string]interface{}, keys []string) map[string]interface{} {
+	if len(keys) == 0 || properties == nil {
+		return properties
+	}
+
+	selected := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if value, ok := properties[key]; ok {
+			selected[key] = value
+		}
+	}
+	for key, value := range properties {
+		if strings.HasPrefix(key, "_") {
+			selected[key] = value
+		}
+	}
+	return selected
+}
+
+// SortFeatures orders features by a named property so output is reproducible.
+// Numeric values sort numerically, everything else by its string form, and
+// features missing the property sort last. The sort is stable, so ties keep
+// their input order. An empty key or SortByIndex leaves the order unchanged.
+func SortFeatures(features []Feature, sortBy string) {
+	if sortBy == "" || sortBy == SortByIndex {
+		return
+	}
+
+	sort.SliceStable(features, func(i, j int) bool {
+		a, aOK := features[i].Properties[sortBy]
+		b, bOK := features[j].Properties[sortBy]
+		if !aOK || a == nil {
+			return false
+		}
+		if !bOK || b == nil {
+			return true
+		}
+
+		aNum, aIsNum := a.(float64)
+		bNum, bIsNum := b.(float64)
+		if aIsNum && bIsNum {
+			return aNum < bNum
+		}
+		return fmt.Sprint(a) < fmt.Sprint(b)
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSelectProperties(t *testing.T) {
+	properties := map[string]interface{}{
+		"name":           "north",
+		"parcel":         "P-1",
+		"area":           12.5,
+		"_input_index":   3,
+		"_repair_method": "buffer(0)",
+	}
+
+	tests := []struct {
+		name       string
+		properties map[string]interface{}
+		keys       []string
+		want       map[string]interface{}
+	}{
+		{
+			name:       "listed keys kept",
+			properties: properties,
+			keys:       []string{"parcel", "area"},
+			want:       map[string]interface{}{"parcel": "P-1", "area": 12.5, "_input_index": 3, "_repair_method": "buffer(0)"},
+		},
+		{
+			name:       "unknown keys ignored",
+			properties: properties,
+			keys:       []string{"parcel", "owner"},
+			want:       map[string]interface{}{"parcel": "P-1", "_input_index": 3, "_repair_method": "buffer(0)"},
+		},
+		{
+			name:       "keys are case-sensitive",
+			properties: properties,
+			keys:       []string{"Name"},
+			want:       map[string]interface{}{"_input_index": 3, "_repair_method": "buffer(0)"},
+		},
+		{
+			name:       "empty list keeps everything",
+			properties: properties,
+			want:       properties,
+		},
+		{
+			name: "nil properties stay nil",
+			keys: []string{"parcel"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectProperties(tt.properties, tt.keys)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SelectProperties(%v) = %v, want %v", tt.keys, got, tt.want)
+			}
+		})
+	}
+
+	// The input must be left alone, as it may be shared with the originals
+	if len(properties) != 5 {
+		t.Errorf("SelectProperties changed its input to %v", properties)
+	}
+}
+
+func TestCleanTopologyKeepProperties(t *testing.T) {
+	const feature = `{"type":"Feature","geometry":%s,"properties":{"id":%d,"name":"parcel","owner":"someone","area":1}}`
+	payload := fmt.Sprintf(`{"type":"FeatureCollection","features":[`+feature+`,`+feature+`]}`, westSquare, 0, eastSquare, 1)
+
+	tests := []struct {
+		name string
+		keep []string
+		want []string
+	}{
+		{"no whitelist", nil, []string{"area", "id", "name", "owner"}},
+		{"whitelist", []string{"id", "name"}, []string{"id", "name"}},
+		{"unknown keys dropped", []string{"id", "missing"}, []string{"id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := cleanTopology(t, payload, func(options *CleanTopologyOptions) {
+				options.KeepProperties = tt.keep
+			})
+			if len(result.Features) != 2 {
+				t.Fatalf("got %d features, want 2", len(result.Features))
+			}
+			for i, feature := range result.Features {
+				var keys []string
+				for key := range feature.Properties {
+					keys = append(keys, key)
+				}
+				sort.Strings(keys)
+				if !reflect.DeepEqual(keys, tt.want) {
+					t.Errorf("feature %d has properties %v, want %v", i, keys, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+)
+
+// LayerFeatureRef identifies a feature by its layer and its position within that layer
+type LayerFeatureRef struct {
+	Layer   int `json:"layer"`
+	Feature int `json:"feature"`
+}
+
+// LayerGap is a boundary gap between two features, with widths in meters and
+// the gap region's area in square meters
+type LayerGap struct {
+	A         LayerFeatureRef `json:"a"`
+	B         LayerFeatureRef `json:"b"`
+	DistanceM float64         `json:"distanceM"`
+	MaxWidthM float64         `json:"maxWidthM"`
+	AreaM2    float64         `json:"areaM2"`
+	Segments  int             `json:"segments"`
+}
+
+// LayerOverlap is an overlap between two features
+type LayerOverlap struct {
+	A      LayerFeatureRef `json:"a"`
+	B      LayerFeatureRef `json:"b"`
+	AreaM2 float64         `json:"areaM2"`
+}
+
+// LayerContainment is a feature lying entirely inside another
+type LayerContainment struct {
+	Container LayerFeatureRef `json:"container"`
+	Contained LayerFeatureRef `json:"contained"`
+}
+
+// CoverageFindings lists the coverage problems found between pairs of features
+type CoverageFindings struct {
+	Gaps         []LayerGap         `json:"gaps"`
+	Overlaps     []LayerOverlap     `json:"overlaps"`
+	Containments []LayerContainment `json:"containments"`
+}
+
+// LayerCoverageReport is the result of validating several layers as one
+// coverage. CrossLayer holds problems between features of different layers,
+// such as at the shared edge of two separately maintained layers, and
+// IntraLayer those within a single layer.
+type LayerCoverageReport struct {
+	LayerCount      int               `json:"layerCount"`
+	FeatureCount    int               `json:"featureCount"`
+	ToleranceMeters float64           `json:"toleranceMeters"`
+	SkippedFeatures []LayerFeatureRef `json:"skippedFeatures,omitempty"`
+	CrossLayer      CoverageFindings  `json:"crossLayer"`
+	IntraLayer      CoverageFindings  `json:"intraLayer"`
+}
+
+// ValidateLayerCoverage runs coverage validation over the features of all
+// layers together and splits the findings into cross-layer and intra-layer
+// ones. Features that cannot be parsed are listed in SkippedFeatures and left
+// out of validation. toleranceMeters is the adjacency tolerance as in
+// /clean-topology, and searchFactor scales it into the radius at which pairs
+// are checked for gaps.
+func ValidateLayerCoverage(layers [][]Feature, toleranceMeters float64, searchFactor float64, quadSegs int) (*LayerCoverageReport, error) {
+	if len(layers) < 2 {
+		return nil, fmt.Errorf("expected at least two layers, got %d", len(layers))
+	}
+
+	report := &LayerCoverageReport{
+		LayerCount:      len(layers),
+		ToleranceMeters: toleranceMeters,
+		CrossLayer:      newCoverageFindings(),
+		IntraLayer:      newCoverageFindings(),
+	}
+
+	// Features of every layer share one index space; refs map it back
+	geomFeatures := make([]GeomFeature, 0)
+	refs := make([]LayerFeatureRef, 0)
+	for layer, features := range layers {
+		for i, feature := range features {
+			geom, err := parseFeatureGeometry(feature)
+			if err != nil {
+				log.Printf("Skipping layer %d feature %d: %v", layer, i, err)
+				report.SkippedFeatures = append(report.SkippedFeatures, LayerFeatureRef{Layer: layer, Feature: i})
+				continue
+			}
+			geomFeatures = append(geomFeatures, GeomFeature{Geom: geom, Properties: feature.Properties})
+			refs = append(refs, LayerFeatureRef{Layer: layer, Feature: i})
+		}
+	}
+	defer destroyGeomFeatures(geomFeatures)
+	report.FeatureCount = len(geomFeatures)
+
+	tolerance := utils.CalculateWGS84ToleranceFromMeters(toleranceMeters)
+	coverage := validateCoverageParallel(geomFeatures, tolerance, searchFactor, quadSegs, nil)
+
+	findings := func(a, b int) *CoverageFindings {
+		if refs[a].Layer != refs[b].Layer {
+			return &report.CrossLayer
+		}
+		return &report.IntraLayer
+	}
+	for _, pair := range coverage.GapPairs {
+		target := findings(pair.A, pair.B)
+		target.Gaps = append(target.Gaps, LayerGap{
+			A:         refs[pair.A],
+			B:         refs[pair.B],
+			DistanceM: pair.DistanceM,
+			MaxWidthM: pair.MaxWidthM,
+			AreaM2:    pair.AreaM2,
+			Segments:  pair.Segments,
+		})
+	}
+	for _, pair := range coverage.OverlapPairs {
+		target := findings(pair.A, pair.B)
+		target.Overlaps = append(target.Overlaps, LayerOverlap{A: refs[pair.A], B: refs[pair.B], AreaM2: pair.AreaM2})
+	}
+	for _, pair := range coverage.ContainmentPairs {
+		target := findings(pair.Container, pair.Contained)
+		target.Containments = append(target.Containments, LayerContainment{Container: refs[pair.Container], Contained: refs[pair.Contained]})
+	}
+
+	// Pairs are validated in parallel and arrive in any order
+	report.CrossLayer.sort()
+	report.IntraLayer.sort()
+
+	log.Printf("Layer coverage: %d cross-layer gaps, %d overlaps; %d intra-layer gaps, %d overlaps",
+		len(report.CrossLayer.Gaps), len(report.CrossLayer.Overlaps), len(report.IntraLayer.Gaps), len(report.IntraLayer.Overlaps))
+	return report, nil
+}
+
+func newCoverageFindings() CoverageFindings {
+	return CoverageFindings{
+		Gaps:         make([]LayerGap, 0),
+		Overlaps:     make([]LayerOverlap, 0),
+		Containments: make([]LayerContainment, 0),
+	}
+}
+
+func (f *CoverageFindings) sort() {
+	sort.Slice(f.Gaps, func(i, j int) bool {
+		return refPairLess(f.Gaps[i].A, f.Gaps[i].B, f.Gaps[j].A, f.Gaps[j].B)
+	})
+	sort.Slice(f.Overlaps, func(i, j int) bool {
+		return refPairLess(f.Overlaps[i].A, f.Overlaps[i].B, f.Overlaps[j].A, f.Overlaps[j].B)
+	})
+	sort.Slice(f.Containments, func(i, j int) bool {
+		return refPairLess(f.Containments[i].Container, f.Containments[i].Contained, f.Containments[j].Container, f.Containments[j].Contained)
+	})
+}
+
+// refPairLess orders pairs of feature references by layer, then feature
+func refPairLess(a1, b1, a2, b2 LayerFeatureRef) bool {
+	if a1 != a2 {
+		return refLess(a1, a2)
+	}
+	return refLess(b1, b2)
+}
+
+func refLess(a, b LayerFeatureRef) bool {
+	if a.Layer != b.Layer {
+		return a.Layer < b.Layer
+	}
+	return a.Feature < b.Feature
+}
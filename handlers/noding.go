@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"github.com/twpayne/go-geos"
+)
+
+// nodingSegment is one segment of the input linework, tagged with the feature it came from
+type nodingSegment struct {
+	a, b    [2]float64
+	feature int
+}
+
+// ValidateNoding checks that the linework of all features (lines, and polygon
+// rings) is properly noded: wherever two segments intersect, the intersection
+// must be a vertex of both. Each offending location is returned once as a
+// Point feature carrying the two features involved as featureA and featureB.
+// Segments are compared with a sweep over their x extents, so only segments
+// whose extents overlap are tested.
+func ValidateNoding(features []Feature) []Feature {
+	segments := make([]nodingSegment, 0)
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+		for _, line := range linework(geom) {
+			for k := 0; k+1 < len(line); k++ {
+				if line[k] != line[k+1] {
+					segments = append(segments, nodingSegment{a: line[k], b: line[k+1], feature: i})
+				}
+			}
+		}
+		geom.Destroy()
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return math.Min(segments[i].a[0], segments[i].b[0]) < math.Min(segments[j].a[0], segments[j].b[0])
+	})
+
+	reported := make(map[[2]float64]bool)
+	points := make([]Feature, 0)
+	active := make([]nodingSegment, 0)
+	for _, segment := range segments {
+		minX := math.Min(segment.a[0], segment.b[0])
+
+		// Drop segments that end before this one starts
+		kept := active[:0]
+		for _, other := range active {
+			if math.Max(other.a[0], other.b[0]) >= minX {
+				kept = append(kept, other)
+			}
+		}
+		active = kept
+
+		for _, other := range active {
+			for _, point := range nonNodedIntersections(segment, other) {
+				if reported[point] {
+					continue
+				}
+				reported[point] = true
+
+				geom := geos.NewPoint([]float64{point[0], point[1]})
+				points = append(points, newGeomFeature(geom, map[string]interface{}{
+					"featureA": other.feature,
+					"featureB": segment.feature,
+				}))
+				geom.Destroy()
+			}
+		}
+		active = append(active, segment)
+	}
+
+	return points
+}
+
+// linework returns the coordinate sequences of every line and polygon ring in geom
+func linework(geom *geos.Geom) [][][2]float64 {
+	if geom.IsEmpty() {
+		return nil
+	}
+
+	switch geom.TypeID() {
+	case geos.TypeIDLineString, geos.TypeIDLinearRing:
+		return [][][2]float64{sequenceCoordinates(geom.CoordSeq())}
+	case geos.TypeIDPolygon:
+		lines := [][][2]float64{sequenceCoordinates(geom.ExteriorRing().CoordSeq())}
+		for i := range geom.NumInteriorRings() {
+			lines = append(lines, sequenceCoordinates(geom.InteriorRing(i).CoordSeq()))
+		}
+		return lines
+	case geos.TypeIDMultiLineString, geos.TypeIDMultiPolygon, geos.TypeIDGeometryCollection:
+		lines := make([][][2]float64, 0)
+		for i := range geom.NumGeometries() {
+			lines = append(lines, linework(geom.Geometry(i))...)
+		}
+		return lines
+	default:
+		return nil
+	}
+}
+
+func sequenceCoordinates(coordSeq *geos.CoordSeq) [][2]float64 {
+	coordinates := make([][2]float64, coordSeq.Size())
+	for i := range coordSeq.Size() {
+		coordinates[i] = [2]float64{coordSeq.X(i), coordSeq.Y(i)}
+	}
+	return coordinates
+}
+
+// nonNodedIntersections returns the points where segments s and t intersect
+// without the point being a vertex of both: proper crossings, a vertex of one
+// lying inside the other, and the ends of collinear overlaps
+func nonNodedIntersections(s, t nodingSegment) [][2]float64 {
+	if math.Max(s.a[1], s.b[1]) < math.Min(t.a[1], t.b[1]) || math.Max(t.a[1], t.b[1]) < math.Min(s.a[1], s.b[1]) {
+		return nil
+	}
+
+	o1 := orientation(s.a, s.b, t.a)
+	o2 := orientation(s.a, s.b, t.b)
+	o3 := orientation(t.a, t.b, s.a)
+	o4 := orientation(t.a, t.b, s.b)
+
+	// Proper crossing in the interior of both segments
+	if o1*o2 < 0 && o3*o4 < 0 {
+		return [][2]float64{crossingPoint(s, t)}
+	}
+
+	// Touching or collinear: a vertex of one segment strictly inside the other
+	points := make([][2]float64, 0)
+	for _, candidate := range []struct {
+		point   [2]float64
+		inside  nodingSegment
+		collide bool
+	}{
+		{t.a, s, o1 == 0},
+		{t.b, s, o2 == 0},
+		{s.a, t, o3 == 0},
+		{s.b, t, o4 == 0},
+	} {
+		if candidate.collide && strictlyInside(candidate.point, candidate.inside) {
+			points = append(points, candidate.point)
+		}
+	}
+	return points
+}
+
+// orientation returns the sign of the turn a -> b -> c: 1 left, -1 right, 0 collinear
+func orientation(a, b, c [2]float64) int {
+	cross := (b[0]-a[0])*(c[1]-a[1]) - (b[1]-a[1])*(c[0]-a[0])
+	switch {
+	case cross > 0:
+		return 1
+	case cross < 0:
+		return -1
+	default:
+		return 0
+	}
+}
+
+// strictlyInside reports whether a point collinear with segment lies between its endpoints
+func strictlyInside(point [2]float64, segment nodingSegment) bool {
+	if point == segment.a || point == segment.b {
+		return false
+	}
+	return point[0] >= math.Min(segment.a[0], segment.b[0]) && point[0] <= math.Max(segment.a[0], segment.b[0]) &&
+		point[1] >= math.Min(segment.a[1], segment.b[1]) && point[1] <= math.Max(segment.a[1], segment.b[1])
+}
+
+// crossingPoint returns the intersection of two properly crossing segments
+func crossingPoint(s, t nodingSegment) [2]float64 {
+	dsx, dsy := s.b[0]-s.a[0], s.b[1]-s.a[1]
+	dtx, dty := t.b[0]-t.a[0], t.b[1]-t.a[1]
+	denominator := dsx*dty - dsy*dtx
+	along := ((t.a[0]-s.a[0])*dty - (t.a[1]-s.a[1])*dtx) / denominator
+	return [2]float64{s.a[0] + along*dsx, s.a[1] + along*dsy}
+}
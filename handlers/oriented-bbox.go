@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"log"
+	"math"
+
+	"github.com/twpayne/go-geos"
+)
+
+// OrientedBoundingBoxes returns the minimum-area rotated rectangle of each feature,
+// computed over the whole geometry so MultiPolygons get a single rectangle. The
+// angle of the rectangle's long axis, in degrees counter-clockwise from east in
+// [0, 180), is added as a _rotation_deg property.
+func OrientedBoundingBoxes(features []Feature) []Feature {
+	boxes := make([]Feature, 0, len(features))
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+
+		rectangle := geom.MinimumRotatedRectangle()
+		geom.Destroy()
+		if rectangle == nil {
+			log.Printf("Skipping feature %d: failed to compute rotated rectangle", i)
+			continue
+		}
+
+		properties := copyProperties(feature.Properties)
+		properties["_rotation_deg"] = rectangleRotation(rectangle)
+
+		boxes = append(boxes, newGeomFeature(rectangle, properties))
+		rectangle.Destroy()
+	}
+
+	return boxes
+}
+
+// rectangleRotation returns the orientation of a rectangle's longest edge in degrees
+func rectangleRotation(rectangle *geos.Geom) float64 {
+	var coordSeq *geos.CoordSeq
+	switch rectangle.TypeID() {
+	case geos.TypeIDPolygon:
+		coordSeq = rectangle.ExteriorRing().CoordSeq()
+	case geos.TypeIDLineString:
+		// Degenerate (collinear) input collapses to a line
+		coordSeq = rectangle.CoordSeq()
+	default:
+		return 0
+	}
+
+	longest := 0.0
+	angle := 0.0
+	for i := 1; i < coordSeq.Size(); i++ {
+		dx := coordSeq.X(i) - coordSeq.X(i-1)
+		dy := coordSeq.Y(i) - coordSeq.Y(i-1)
+		if length := math.Hypot(dx, dy); length > longest {
+			longest = length
+			angle = math.Atan2(dy, dx) * 180 / math.Pi
+		}
+	}
+
+	if angle < 0 {
+		angle += 180
+	}
+	if angle >= 180 {
+		angle -= 180
+	}
+	return angle
+}
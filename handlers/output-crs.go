@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+)
+
+// Output coordinate reference systems for cleaned results. Cleaning always
+// runs in WGS84, since every tolerance assumes degrees; Web Mercator is only
+// applied to the finished output, for tile pipelines.
+const (
+	OutputCRSWGS84       = "4326"
+	OutputCRSWebMercator = "3857"
+)
+
+// projectToWebMercator reprojects the features and bboxes of a cleaned result
+// from WGS84 to EPSG:3857, rounding coordinates to outputPrecision decimals
+// (now meters) unless it is negative, and names the crs on the collection.
+// Positions beyond MaxMercatorLatitude are clamped with a warning.
+func projectToWebMercator(result *TopologyCleaningResult, outputPrecision int) error {
+	clamped := 0
+	for _, features := range [][]Feature{result.Features, result.Original} {
+		for i := range features {
+			featureClamped, err := projectFeatureToWebMercator(&features[i], outputPrecision)
+			if err != nil {
+				return fmt.Errorf("feature %d: %v", i, err)
+			}
+			clamped += featureClamped
+		}
+	}
+	if result.BBox != nil {
+		result.BBox = utils.WebMercatorBBox(result.BBox)
+	}
+
+	if clamped > 0 {
+		log.Printf("WARNING: clamped %d position(s) beyond ±%g° latitude to the Web Mercator range", clamped, utils.MaxMercatorLatitude)
+	}
+	result.MercatorClampedPositions = clamped
+	result.CRS = map[string]interface{}{
+		"type":       "name",
+		"properties": map[string]interface{}{"name": utils.WebMercatorCRSName},
+	}
+	return nil
+}
+
+// projectFeatureToWebMercator reprojects one feature in place, returning how
+// many of its positions were clamped. Null geometries are left as they are.
+func projectFeatureToWebMercator(feature *Feature, outputPrecision int) (int, error) {
+	if len(feature.Geometry) == 0 || string(feature.Geometry) == "null" {
+		return 0, nil
+	}
+
+	geometry, clamped, err := utils.WebMercatorGeoJSON(feature.Geometry)
+	if err != nil {
+		return 0, err
+	}
+	if outputPrecision >= 0 {
+		geometry, err = utils.RoundGeoJSONCoordinates(geometry, outputPrecision)
+		if err != nil {
+			return 0, err
+		}
+	}
+	feature.Geometry = geometry
+	if feature.BBox != nil {
+		feature.BBox = utils.WebMercatorBBox(feature.BBox)
+	}
+	return clamped, nil
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"container/list"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/twpayne/go-geos"
+)
+
+// ReferenceLayer is a reference layer parsed once and shared across requests
+type ReferenceLayer struct {
+	Features []Feature
+	geoms    []*geos.Geom // parallel to Features; nil where the geometry failed to parse
+	errs     []error
+	expires  time.Time
+	refs     int
+	evicted  bool
+	size     int64         // GeoJSON bytes of the features, a proxy for their memory
+	element  *list.Element // position in the recency list while cached
+}
+
+// ReferenceCache holds parsed reference layers keyed by a client-supplied referenceId.
+// Layers are reference counted so an entry that expires or is deleted while a
+// request is still using it is only freed once that request releases it. The
+// cache holds at most maxEntries layers of at most maxBytes GeoJSON bytes in
+// total, evicting the least recently used layers to make room; a zero limit
+// disables that bound.
+type ReferenceCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	maxBytes   int64
+	size       int64
+	layers     map[string]*ReferenceLayer
+	recency    *list.List // ids, most recently used first
+}
+
+// NewReferenceCache creates a cache whose entries expire ttl after they are
+// stored, bounded by maxEntries layers and maxBytes GeoJSON bytes
+func NewReferenceCache(ttl time.Duration, maxEntries int, maxBytes int64) *ReferenceCache {
+	return &ReferenceCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		layers:     make(map[string]*ReferenceLayer),
+		recency:    list.New(),
+	}
+}
+
+// Acquire returns the cached layer for id, if present and not expired. The
+// caller must Release it when done.
+func (c *ReferenceCache) Acquire(id string) (*ReferenceLayer, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	layer, ok := c.layers[id]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(layer.expires) {
+		c.evictLocked(id)
+		return nil, false
+	}
+
+	layer.refs++
+	c.recency.MoveToFront(layer.element)
+	return layer, true
+}
+
+// Store parses features and caches them under id, replacing any existing entry
+// and evicting least recently used layers to stay within the cache bounds. A
+// layer larger than the whole byte bound is not cached at all. The returned
+// layer is already acquired and must be Released by the caller.
+func (c *ReferenceCache) Store(id string, features []Feature) *ReferenceLayer {
+	layer := &ReferenceLayer{
+		Features: features,
+		geoms:    make([]*geos.Geom, len(features)),
+		errs:     make([]error, len(features)),
+		refs:     1,
+	}
+	for i, feature := range features {
+		layer.geoms[i], layer.errs[i] = parseFeatureGeometry(feature)
+		layer.size += int64(len(feature.Geometry))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.evictExpiredLocked()
+	if _, ok := c.layers[id]; ok {
+		c.evictLocked(id)
+	}
+	if c.maxBytes > 0 && layer.size > c.maxBytes {
+		// Freed as soon as the caller releases it
+		layer.evicted = true
+		log.Printf("Not caching reference layer %q: %d bytes is more than the cache limit of %d", id, layer.size, c.maxBytes)
+		return layer
+	}
+	for c.recency.Len() > 0 && ((c.maxEntries > 0 && len(c.layers) >= c.maxEntries) || (c.maxBytes > 0 && c.size+layer.size > c.maxBytes)) {
+		c.evictLocked(c.recency.Back().Value.(string))
+	}
+	layer.expires = time.Now().Add(c.ttl)
+	layer.element = c.recency.PushFront(id)
+	c.layers[id] = layer
+	c.size += layer.size
+
+	log.Printf("Cached reference layer %q (%d features, expires %s)", id, len(features), layer.expires.Format(time.RFC3339))
+	return layer
+}
+
+// Release gives back a layer obtained from Acquire or Store
+func (c *ReferenceCache) Release(layer *ReferenceLayer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	layer.refs--
+	if layer.evicted && layer.refs == 0 {
+		layer.destroy()
+	}
+}
+
+// Delete removes the layer cached under id, reporting whether there was one
+func (c *ReferenceCache) Delete(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.layers[id]; !ok {
+		return false
+	}
+	c.evictLocked(id)
+	return true
+}
+
+// evictExpiredLocked removes every expired entry; c.mu must be held
+func (c *ReferenceCache) evictExpiredLocked() {
+	now := time.Now()
+	for id, layer := range c.layers {
+		if now.After(layer.expires) {
+			c.evictLocked(id)
+		}
+	}
+}
+
+// evictLocked removes id from the cache, freeing its geometries once unused; c.mu must be held
+func (c *ReferenceCache) evictLocked(id string) {
+	layer := c.layers[id]
+	delete(c.layers, id)
+	c.recency.Remove(layer.element)
+	c.size -= layer.size
+	layer.evicted = true
+	if layer.refs == 0 {
+		layer.destroy()
+	}
+	log.Printf("Evicted reference layer %q", id)
+}
+
+func (layer *ReferenceLayer) destroy() {
+	for _, geom := range layer.geoms {
+		if geom != nil {
+			geom.Destroy()
+		}
+	}
+}
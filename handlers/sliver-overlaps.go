@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+// DefaultSliverMaxAreaRatio is the largest overlap, as a fraction of the area of
+// the polygon it would be clipped from, that resolveSliverOverlaps removes
+const DefaultSliverMaxAreaRatio = 0.01
+
+// Rules for choosing which polygon of an overlapping pair loses the overlap
+const (
+	SliverClipSmaller = "smaller"
+	SliverClipLarger  = "larger"
+)
+
+// resolveSliverOverlaps clips each overlap in report from one polygon of the
+// pair, chosen by rule, so the two end up sharing the other's boundary. Only
+// slivers are clipped: an overlap larger than maxAreaRatio of the clipped
+// polygon is left alone, since removing it could change a parcel
+// substantially, as is any clip that would not leave a valid polygon. Overlaps
+// are re-measured before clipping, as an earlier clip may have removed them.
+// It returns how many overlaps were resolved.
+func resolveSliverOverlaps(geomFeatures []GeomFeature, report CoverageReport, maxAreaRatio float64, rule string) int {
+	resolved := 0
+	for _, pair := range report.OverlapPairs {
+		geomA, geomB := geomFeatures[pair.A].Geom, geomFeatures[pair.B].Geom
+		if geomA == nil || geomB == nil || !geomA.Overlaps(geomB) {
+			continue
+		}
+
+		// The clipped polygon gives up the overlap, the other keeps it
+		target, other := pair.A, pair.B
+		if (geomA.Area() > geomB.Area()) == (rule == SliverClipSmaller) {
+			target, other = pair.B, pair.A
+		}
+		targetGeom, otherGeom := geomFeatures[target].Geom, geomFeatures[other].Geom
+
+		intersection := targetGeom.Intersection(otherGeom)
+		if intersection == nil {
+			continue
+		}
+		overlapArea := intersection.Area()
+		overlapAreaM2 := utils.GeodesicArea(intersection)
+		intersection.Destroy()
+		if overlapArea > maxAreaRatio*targetGeom.Area() {
+			log.Printf("Keeping overlap between features %d and %d: %.3f m² is more than %g of feature %d", pair.A, pair.B, overlapAreaM2, maxAreaRatio, target)
+			continue
+		}
+
+		clipped := clipOverlap(targetGeom, otherGeom)
+		if clipped == nil {
+			log.Printf("Keeping overlap between features %d and %d: clipping feature %d left no valid polygon", pair.A, pair.B, target)
+			continue
+		}
+
+		targetGeom.Destroy()
+		geomFeatures[target].Geom = clipped
+		resolved++
+		log.Printf("Resolved %.3f m² sliver overlap between features %d and %d by clipping feature %d", overlapAreaM2, pair.A, pair.B, target)
+	}
+	return resolved
+}
+
+// clipOverlap returns the polygonal part of geom outside other, or nil when
+// nothing valid remains. Lines and points the difference leaves along the
+// shared edge are dropped.
+func clipOverlap(geom, other *geos.Geom) *geos.Geom {
+	difference := geom.Difference(other)
+	if difference == nil {
+		return nil
+	}
+	defer difference.Destroy()
+
+	parts := make([]*geos.Geom, 0)
+	collectPolygons(difference, &parts)
+	if len(parts) == 0 {
+		return nil
+	}
+
+	var clipped *geos.Geom
+	if len(parts) == 1 {
+		clipped = parts[0]
+	} else {
+		// The collection takes ownership of the parts
+		clipped = geos.NewCollection(geos.TypeIDMultiPolygon, parts)
+	}
+	if !clipped.IsValid() {
+		clipped.Destroy()
+		return nil
+	}
+	return clipped
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResolveSliverOverlaps(t *testing.T) {
+	const (
+		unitSquare = "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))"
+		// Overlaps unitSquare by a 0.005 wide sliver along x=1
+		sliverNeighbour = "POLYGON ((0.995 0, 3 0, 3 1, 0.995 1, 0.995 0))"
+		// Overlaps unitSquare by half its area
+		halfNeighbour = "POLYGON ((0.5 0, 3 0, 3 1, 0.5 1, 0.5 0))"
+		// Shares the edge x=1 with unitSquare
+		edgeNeighbour = "POLYGON ((1 0, 3 0, 3 1, 1 1, 1 0))"
+	)
+
+	tests := []struct {
+		name     string
+		wkts     []string
+		rule     string
+		resolved int
+		areas    []float64 // of each geometry afterwards
+	}{
+		{
+			name:     "smaller polygon is clipped",
+			wkts:     []string{unitSquare, sliverNeighbour},
+			rule:     SliverClipSmaller,
+			resolved: 1,
+			areas:    []float64{0.995, 2.005},
+		},
+		{
+			name:     "larger polygon is clipped",
+			wkts:     []string{unitSquare, sliverNeighbour},
+			rule:     SliverClipLarger,
+			resolved: 1,
+			areas:    []float64{1, 2},
+		},
+		{
+			name:     "pair order does not pick the clipped polygon",
+			wkts:     []string{sliverNeighbour, unitSquare},
+			rule:     SliverClipSmaller,
+			resolved: 1,
+			areas:    []float64{2.005, 0.995},
+		},
+		{
+			name:  "significant overlap is kept",
+			wkts:  []string{unitSquare, halfNeighbour},
+			rule:  SliverClipSmaller,
+			areas: []float64{1, 2.5},
+		},
+		{
+			name:  "pair that no longer overlaps is skipped",
+			wkts:  []string{unitSquare, edgeNeighbour},
+			rule:  SliverClipSmaller,
+			areas: []float64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := geomFeatures(t, tt.wkts...)
+			report := CoverageReport{OverlapCount: 1, OverlapPairs: []OverlapPair{{A: 0, B: 1}}}
+
+			resolved := resolveSliverOverlaps(features, report, DefaultSliverMaxAreaRatio, tt.rule)
+			if resolved != tt.resolved {
+				t.Errorf("resolved %d overlaps, want %d", resolved, tt.resolved)
+			}
+			for i, want := range tt.areas {
+				if area := features[i].Geom.Area(); math.Abs(area-want) > testTolerance {
+					t.Errorf("geometry %d has area %v, want %v", i, area, want)
+				}
+				if !features[i].Geom.IsValid() {
+					t.Errorf("geometry %d is invalid: %s", i, features[i].Geom.ToWKT())
+				}
+			}
+			if tt.resolved > 0 && features[0].Geom.Overlaps(features[1].Geom) {
+				t.Errorf("%s and %s still overlap", features[0].Geom.ToWKT(), features[1].Geom.ToWKT())
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+// DefaultMultipartSeparationMeters is the distance beyond which parts of one
+// MultiPolygon are taken to be separate parcels by SplitMultipartFeatures
+const DefaultMultipartSeparationMeters = 50.0
+
+// MultipartSplitReport summarises the features SplitMultipartFeatures split
+type MultipartSplitReport struct {
+	FeaturesSplit   int `json:"featuresSplit"`
+	FeaturesCreated int `json:"featuresCreated"`
+}
+
+// MultipartSplitResult is a FeatureCollection with the split report attached
+type MultipartSplitResult struct {
+	Type     string               `json:"type"`
+	Features []Feature            `json:"features"`
+	Report   MultipartSplitReport `json:"report"`
+}
+
+// SplitMultipartFeatures splits MultiPolygons whose parts look like separate
+// parcels that were merged by accident. Parts closer than separationMeters are
+// chained into one group, so an island next to its mainland or a parcel cut by
+// a narrow road stays together; each group further than that from every other
+// becomes its own feature with a copy of the properties and a zero-based
+// _split_index. Features with a single group, and non-MultiPolygon features,
+// are passed through unchanged.
+func SplitMultipartFeatures(features []Feature, separationMeters float64) (*MultipartSplitResult, error) {
+	if separationMeters <= 0 {
+		return nil, fmt.Errorf("separation must be greater than 0 meters, got %f", separationMeters)
+	}
+	separation := utils.CalculateWGS84ToleranceFromMeters(separationMeters)
+
+	result := &MultipartSplitResult{Type: "FeatureCollection", Features: make([]Feature, 0, len(features))}
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+		if geom.TypeID() != geos.TypeIDMultiPolygon || geom.NumGeometries() < 2 {
+			geom.Destroy()
+			result.Features = append(result.Features, feature)
+			continue
+		}
+
+		parts := utils.Explode(geom)
+		geom.Destroy()
+
+		groups := groupPartsByDistance(parts, separation)
+		if len(groups) == 1 {
+			for _, part := range parts {
+				part.Destroy()
+			}
+			result.Features = append(result.Features, feature)
+			continue
+		}
+
+		for splitIndex, group := range groups {
+			groupParts := make([]*geos.Geom, 0, len(group))
+			for _, partIndex := range group {
+				groupParts = append(groupParts, parts[partIndex])
+			}
+
+			// The collection takes ownership of the parts
+			var splitGeom *geos.Geom
+			if len(groupParts) == 1 {
+				splitGeom = groupParts[0]
+			} else {
+				splitGeom = geos.NewCollection(geos.TypeIDMultiPolygon, groupParts)
+			}
+
+			properties := copyProperties(feature.Properties)
+			properties["_split_index"] = splitIndex
+			result.Features = append(result.Features, newGeomFeature(splitGeom, properties))
+			splitGeom.Destroy()
+		}
+
+		log.Printf("Split feature %d into %d features", i, len(groups))
+		result.Report.FeaturesSplit++
+		result.Report.FeaturesCreated += len(groups)
+	}
+
+	return result, nil
+}
+
+// groupPartsByDistance groups part indices by single linkage: two parts are in
+// the same group when a chain of parts each within distance of the next links
+// them. Groups are ordered by their first part.
+func groupPartsByDistance(parts []*geos.Geom, distance float64) [][]int {
+	parent := make([]int, len(parts))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range parts {
+		for j := i + 1; j < len(parts); j++ {
+			if find(i) != find(j) && parts[i].DistanceWithin(parts[j], distance) {
+				parent[find(j)] = find(i)
+			}
+		}
+	}
+
+	groupIndex := make(map[int]int)
+	groups := make([][]int, 0)
+	for i := range parts {
+		root := find(i)
+		index, ok := groupIndex[root]
+		if !ok {
+			index = len(groups)
+			groupIndex[root] = index
+			groups = append(groups, nil)
+		}
+		groups[index] = append(groups[index], i)
+	}
+	return groups
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+)
+
+// squareRing returns the GeoJSON ring of a 0.001° square with its south-west corner at x, 0
+func squareRing(x float64) string {
+	return fmt.Sprintf("[[%[1]v,0],[%[2]v,0],[%[2]v,0.001],[%[1]v,0.001],[%[1]v,0]]", x, x+0.001)
+}
+
+// multiPolygon returns a GeoJSON MultiPolygon of squareRing squares
+func multiPolygon(xs ...float64) string {
+	polygons := make([]string, len(xs))
+	for i, x := range xs {
+		polygons[i] = "[" + squareRing(x) + "]"
+	}
+	return `{"type":"MultiPolygon","coordinates":[` + strings.Join(polygons, ",") + `]}`
+}
+
+func TestSplitMultipartFeatures(t *testing.T) {
+	// At the default 50 m separation, 0.0001° (about 11 m) apart is close and
+	// 0.0012° (about 130 m) is far
+	tests := []struct {
+		name     string
+		geometry string
+		parts    []int // polygons in each output feature
+		split    bool
+	}{
+		{"close parts stay merged", multiPolygon(0, 0.0011), []int{2}, false},
+		{"far parts are split", multiPolygon(0, 10), []int{1, 1}, true},
+		{"close parts split from a far one", multiPolygon(0, 10, 0.0011), []int{2, 1}, true},
+		{"a chain of close parts stays merged", multiPolygon(0, 0.0022, 0.0011), []int{3}, false},
+		{"single-part multipolygon", multiPolygon(0), []int{1}, false},
+		{"polygon", `{"type":"Polygon","coordinates":[` + squareRing(0) + `]}`, []int{1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := rawFeatures(westSquare, tt.geometry)
+			result, err := SplitMultipartFeatures(features, DefaultMultipartSeparationMeters)
+			if err != nil {
+				t.Fatalf("SplitMultipartFeatures: %v", err)
+			}
+
+			if len(result.Features) != 1+len(tt.parts) {
+				t.Fatalf("got %d features, want %d", len(result.Features), 1+len(tt.parts))
+			}
+			wantReport := MultipartSplitReport{}
+			if tt.split {
+				wantReport = MultipartSplitReport{FeaturesSplit: 1, FeaturesCreated: len(tt.parts)}
+			}
+			if result.Report != wantReport {
+				t.Errorf("report = %+v, want %+v", result.Report, wantReport)
+			}
+			if string(result.Features[0].Geometry) != westSquare {
+				t.Errorf("first feature geometry = %s, want it passed through", result.Features[0].Geometry)
+			}
+
+			for i, parts := range tt.parts {
+				feature := result.Features[1+i]
+				if id := feature.Properties["id"]; id != 1 {
+					t.Errorf("feature %d has id %v, want 1", 1+i, id)
+				}
+				splitIndex, ok := feature.Properties["_split_index"]
+				if tt.split && splitIndex != i {
+					t.Errorf("feature %d has _split_index %v, want %d", 1+i, splitIndex, i)
+				}
+				if !tt.split {
+					if ok {
+						t.Errorf("unsplit feature has _split_index %v", splitIndex)
+					}
+					if string(feature.Geometry) != tt.geometry {
+						t.Errorf("unsplit geometry = %s, want it unchanged", feature.Geometry)
+					}
+				}
+
+				geom, err := geos.NewGeomFromGeoJSON(string(feature.Geometry))
+				if err != nil {
+					t.Fatalf("parsing feature %d: %v", 1+i, err)
+				}
+				if got := geom.NumGeometries(); got != parts {
+					t.Errorf("feature %d has %d polygons, want %d", 1+i, got, parts)
+				}
+				geom.Destroy()
+			}
+		})
+	}
+}
+
+func TestSplitMultipartFeaturesRejectsSeparation(t *testing.T) {
+	for _, separation := range []float64{0, -5} {
+		if _, err := SplitMultipartFeatures(rawFeatures(westSquare), separation); err == nil {
+			t.Errorf("SplitMultipartFeatures accepted a separation of %v m", separation)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/twpayne/go-geos"
+)
+
+// SplitPolygon splits a polygon along a cutting line. The line is noded with the
+// polygon boundary, the resulting linework is polygonized, and only the faces
+// that fall inside the original polygon are kept. Each piece carries the
+// polygon's properties plus a _piece_index.
+func SplitPolygon(features []Feature) ([]Feature, error) {
+	var polygonFeature *Feature
+	var line *geos.Geom
+	for i := range features {
+		geom, err := parseFeatureGeometry(features[i])
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %v", i, err)
+		}
+
+		switch geom.TypeID() {
+		case geos.TypeIDPolygon:
+			if polygonFeature != nil {
+				geom.Destroy()
+				return nil, fmt.Errorf("expected a single polygon, found more than one")
+			}
+			polygonFeature = &features[i]
+			geom.Destroy()
+		case geos.TypeIDLineString:
+			if line != nil {
+				geom.Destroy()
+				line.Destroy()
+				return nil, fmt.Errorf("expected a single cutting LineString, found more than one")
+			}
+			line = geom
+		default:
+			geom.Destroy()
+		}
+	}
+
+	if polygonFeature == nil || line == nil {
+		if line != nil {
+			line.Destroy()
+		}
+		return nil, fmt.Errorf("expected one Polygon and one LineString feature")
+	}
+	defer line.Destroy()
+
+	polygon, err := parseFeatureGeometry(*polygonFeature)
+	if err != nil {
+		return nil, err
+	}
+	defer polygon.Destroy()
+
+	if !polygon.Intersects(line) {
+		return nil, fmt.Errorf("cutting line does not intersect the polygon")
+	}
+
+	// Union nodes the line with the boundary so polygonize sees every crossing
+	boundary := polygon.Boundary()
+	defer boundary.Destroy()
+	noded := boundary.Union(line)
+	if noded == nil {
+		return nil, fmt.Errorf("failed to node cutting line with polygon boundary")
+	}
+	defer noded.Destroy()
+
+	faces := geos.Polygonize([]*geos.Geom{noded})
+	if faces == nil {
+		return nil, fmt.Errorf("failed to polygonize split linework")
+	}
+	defer faces.Destroy()
+
+	pieces := make([]Feature, 0, faces.NumGeometries())
+	for i := range faces.NumGeometries() {
+		face := faces.Geometry(i)
+		interiorPoint := face.PointOnSurface()
+		inside := interiorPoint != nil && polygon.Contains(interiorPoint)
+		if interiorPoint != nil {
+			interiorPoint.Destroy()
+		}
+		if !inside {
+			continue
+		}
+
+		properties := copyProperties(polygonFeature.Properties)
+		properties["_piece_index"] = len(pieces)
+		pieces = append(pieces, newGeomFeature(face, properties))
+	}
+
+	if len(pieces) < 2 {
+		return nil, fmt.Errorf("cutting line does not fully cross the polygon")
+	}
+
+	return pieces, nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+// SymmetricDifference returns the areas covered by exactly one of two layers,
+// for change detection between vintages. Each layer is unioned, and the
+// symmetric difference is built as the two one-sided differences, which
+// together equal unionA.SymDifference(unionB) but keep track of where each
+// piece came from: every output polygon carries _layer 0 (only in layerA) or
+// 1 (only in layerB). An empty layer contributes nothing, so the other
+// layer's union is returned whole.
+func SymmetricDifference(layerA []Feature, layerB []Feature) ([]Feature, error) {
+	unionA, err := unionLayer(layerA)
+	if err != nil {
+		return nil, fmt.Errorf("layer 0: %v", err)
+	}
+	defer unionA.Destroy()
+
+	unionB, err := unionLayer(layerB)
+	if err != nil {
+		return nil, fmt.Errorf("layer 1: %v", err)
+	}
+	defer unionB.Destroy()
+
+	if unionA == nil && unionB == nil {
+		return nil, fmt.Errorf("no valid geometries found in either layer")
+	}
+
+	features := make([]Feature, 0)
+	for layer, pair := range [][2]*geos.Geom{{unionA, unionB}, {unionB, unionA}} {
+		only, other := pair[0], pair[1]
+		if only == nil {
+			continue
+		}
+
+		var difference *geos.Geom
+		if other == nil {
+			difference = only.Clone()
+		} else {
+			difference = only.Difference(other)
+		}
+		if difference == nil {
+			return nil, fmt.Errorf("failed to compute the difference of layer %d", layer)
+		}
+
+		// Slivers can collapse to lines or points; only areas are changes
+		for _, part := range utils.Explode(difference) {
+			if part.TypeID() == geos.TypeIDPolygon {
+				features = append(features, newGeomFeature(part, map[string]interface{}{"_layer": layer}))
+			}
+			part.Destroy()
+		}
+		difference.Destroy()
+	}
+
+	return features, nil
+}
+
+// unionLayer unions the geometries of a layer, returning nil for a layer with none
+func unionLayer(features []Feature) (*geos.Geom, error) {
+	geoms := make([]*geos.Geom, 0, len(features))
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+		geoms = append(geoms, geom)
+	}
+	if len(geoms) == 0 {
+		return nil, nil
+	}
+
+	return UnionGeometries(geoms, UnionMethodUnary)
+}
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+// cleanFeaturesInTiles cleans features in spatially coherent tiles of at most
+// options.BatchSize features, so peak memory is bounded by the tile rather
+// than the request, then snaps features along tile seams to their neighbours
+// in other tiles. The tradeoff is that the per-tile phases only see their own
+// tile: duplicates are only found within a tile, an estimated tolerance is
+// taken from the first tile, and overlaps created or left across a seam are
+// not rolled back or reported in coverage.
+func cleanFeaturesInTiles(features []Feature, options CleanTopologyOptions) (*TopologyCleaningResult, error) {
+	tiles, tileBounds := partitionIntoTiles(features, options.BatchSize)
+	log.Printf("Cleaning %d features in %d tiles of up to %d features", len(features), len(tiles), options.BatchSize)
+
+	// Output features are matched back to their tile through the input index
+	featureTiles := make(map[int]int, len(features))
+	for t, tile := range tiles {
+		for _, feature := range tile {
+			featureTiles[inputIndex(feature)] = t
+		}
+	}
+
+	var result *TopologyCleaningResult
+	for t, tile := range tiles {
+		log.Printf("Cleaning tile %d/%d (%d features)", t+1, len(tiles), len(tile))
+		tileResult, err := cleanFeatures(tile, options)
+		if err != nil {
+			return nil, fmt.Errorf("tile %d: %w", t, err)
+		}
+		if result == nil {
+			result = tileResult
+			continue
+		}
+		mergeTileResult(result, tileResult)
+	}
+	result.TileCount = len(tiles)
+
+	if options.Profile {
+		sort.SliceStable(result.Profile, func(i, j int) bool {
+			return result.Profile[i].TotalMs > result.Profile[j].TotalMs
+		})
+		if len(result.Profile) > options.ProfileTop {
+			result.Profile = result.Profile[:options.ProfileTop]
+		}
+	}
+
+	stitched, err := stitchTileSeams(result.Features, featureTiles, tileBounds, result.ToleranceMeters, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stitch tile seams: %v", err)
+	}
+	result.StitchedFeatures = stitched
+	log.Printf("Stitched %d features across %d tile seams", stitched, len(tiles))
+
+	return result, nil
+}
+
+// mergeTileResult adds the features and counters of a tile to the combined
+// result, which keeps the tolerances of the first tile
+func mergeTileResult(result, tile *TopologyCleaningResult) {
+	result.Features = append(result.Features, tile.Features...)
+	result.Original = append(result.Original, tile.Original...)
+	result.SpatialIndexGrid = append(result.SpatialIndexGrid, tile.SpatialIndexGrid...)
+	result.Profile = append(result.Profile, tile.Profile...)
+	result.BBox = utils.MergeBBox(result.BBox, tile.BBox)
+	if result.Report == nil {
+		result.Report = tile.Report
+	} else {
+		result.Report.merge(tile.Report)
+	}
+
+	result.SnapPasses = max(result.SnapPasses, tile.SnapPasses)
+	result.RolledBackSnaps += tile.RolledBackSnaps
+	result.SliverOverlapsResolved += tile.SliverOverlapsResolved
+	result.DuplicatesRemoved += tile.DuplicatesRemoved
+	result.UnfixedFeatures += tile.UnfixedFeatures
+	result.SkippedFeatures = append(result.SkippedFeatures, tile.SkippedFeatures...)
+	result.PrecisionReducedFeatures += tile.PrecisionReducedFeatures
+	result.SpikesRemoved += tile.SpikesRemoved
+	for method, count := range tile.RepairMethods {
+		if result.RepairMethods == nil {
+			result.RepairMethods = make(map[string]int)
+		}
+		result.RepairMethods[method] += count
+	}
+}
+
+// partitionIntoTiles orders features along a Z-order curve through their
+// bounding box centres and cuts the order into tiles of batchSize, so each tile
+// covers a compact area and most neighbours share a tile. Features whose
+// geometry cannot be parsed have no position and go to the last tiles. It
+// returns the tiles and the bounds of each; a tile without geometries has
+// empty bounds.
+func partitionIntoTiles(features []Feature, batchSize int) ([][]Feature, []*geos.Box2D) {
+	bounds := make([]*geos.Box2D, len(features))
+	extent := geos.NewBox2DEmpty()
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil || geom == nil {
+			continue
+		}
+		if !geom.IsEmpty() && utils.CheckFiniteBounds(geom) == nil {
+			bounds[i] = geom.Bounds()
+			extent = mergeBox2D(extent, bounds[i])
+		}
+		geom.Destroy()
+	}
+
+	codes := make([]uint64, len(features))
+	for i, box := range bounds {
+		codes[i] = math.MaxUint64
+		if box != nil {
+			codes[i] = mortonCode((box.MinX+box.MaxX)/2, (box.MinY+box.MaxY)/2, extent)
+		}
+	}
+
+	order := make([]int, len(features))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return codes[order[i]] < codes[order[j]]
+	})
+
+	tiles := make([][]Feature, 0, (len(features)+batchSize-1)/batchSize)
+	tileBounds := make([]*geos.Box2D, 0, cap(tiles))
+	for start := 0; start < len(order); start += batchSize {
+		end := min(start+batchSize, len(order))
+		tile := make([]Feature, 0, end-start)
+		box := geos.NewBox2DEmpty()
+		for _, i := range order[start:end] {
+			tile = append(tile, features[i])
+			if bounds[i] != nil {
+				box = mergeBox2D(box, bounds[i])
+			}
+		}
+		tiles = append(tiles, tile)
+		tileBounds = append(tileBounds, box)
+	}
+	return tiles, tileBounds
+}
+
+// mortonCode interleaves the bits of x and y, scaled to 16 bits each across
+// extent, so sorting by the code walks a Z-order curve through the extent
+func mortonCode(x, y float64, extent *geos.Box2D) uint64 {
+	scale := func(value, minimum, size float64) uint64 {
+		if size <= 0 {
+			return 0
+		}
+		return uint64(math.Min(math.Max((value-minimum)/size, 0), 1) * 0xffff)
+	}
+	ix := scale(x, extent.MinX, extent.Width())
+	iy := scale(y, extent.MinY, extent.Height())
+
+	var code uint64
+	for bit := 0; bit < 16; bit++ {
+		code |= (ix>>bit&1)<<(2*bit) | (iy>>bit&1)<<(2*bit+1)
+	}
+	return code
+}
+
+func mergeBox2D(a, b *geos.Box2D) *geos.Box2D {
+	return geos.NewBox2D(math.Min(a.MinX, b.MinX), math.Min(a.MinY, b.MinY), math.Max(a.MaxX, b.MaxX), math.Max(a.MaxY, b.MaxY))
+}
+
+// stitchTileSeams snaps cleaned features near a tile boundary to neighbours
+// from other tiles, the pairs tiling kept apart, repairing and re-truncating
+// every feature that moved. Features are updated in place; only those whose
+// bounds come within the snap search radius of another tile are parsed, and
+// the number of features changed is returned.
+func stitchTileSeams(features []Feature, featureTiles map[int]int, tileBounds []*geos.Box2D, toleranceMeters float64, options CleanTopologyOptions) (int, error) {
+	snapTolerance := utils.CalculateWGS84ToleranceFromMeters(toleranceMeters)
+	searchRadius := snapTolerance * options.SnapSearchFactor
+
+	candidates := make([]GeomFeature, 0)
+	positions := make([]int, 0)
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil || geom == nil {
+			continue
+		}
+		// Features passed through uncleaned, such as points, are never stitched
+		polygonal := geom.TypeID() == geos.TypeIDPolygon || geom.TypeID() == geos.TypeIDMultiPolygon
+		if !polygonal || geom.IsEmpty() || utils.CheckFiniteBounds(geom) != nil || !nearOtherTile(geom.Bounds(), featureTiles[inputIndex(feature)], tileBounds, searchRadius) {
+			geom.Destroy()
+			continue
+		}
+		candidates = append(candidates, GeomFeature{Geom: geom, Properties: feature.Properties})
+		positions = append(positions, i)
+	}
+	defer destroyGeomFeatures(candidates)
+	log.Printf("Found %d features on tile seams", len(candidates))
+
+	spatialIndex := buildSpatialIndex(candidates, snapTolerance*100, options.QuadSegs)
+	maxDistortion := snapTolerance * 0.1
+
+	// Every candidate snaps to its neighbours' tile-cleaned geometries, as in a
+	// single snapping pass, so the order candidates are visited in does not matter
+	stitched := make([]GeomFeature, 0)
+	stitchedPositions := make([]int, 0)
+	for c, candidate := range candidates {
+		tile := featureTiles[inputIndex(features[positions[c]])]
+		snappedGeom := candidate.Geom
+		for _, neighbor := range spatialIndex.FindNeighbors(candidate.Geom, searchRadius) {
+			if neighbor.Geom == nil || neighbor.Index == c || featureTiles[inputIndex(features[positions[neighbor.Index]])] == tile {
+				continue
+			}
+			tempSnapped, snapSuccessful := conservativeSnap(snappedGeom, neighbor.Geom, snapTolerance, maxDistortion)
+			if snapSuccessful && tempSnapped != snappedGeom {
+				if snappedGeom != candidate.Geom {
+					snappedGeom.Destroy()
+				}
+				snappedGeom = tempSnapped
+			}
+		}
+		if snappedGeom == candidate.Geom {
+			continue
+		}
+
+		stitchedGeom, err := finishStitchedGeometry(snappedGeom, inputIndex(features[positions[c]]), options)
+		if err != nil {
+			log.Printf("Keeping tile result for feature %d: %v", inputIndex(features[positions[c]]), err)
+			continue
+		}
+		stitched = append(stitched, GeomFeature{Geom: stitchedGeom, Properties: candidate.Properties})
+		stitchedPositions = append(stitchedPositions, positions[c])
+	}
+	defer destroyGeomFeatures(stitched)
+
+	serialized, failed, err := serializeGeometriesParallel(stitched, options.OutputPrecision, options.IncludeFeatureBBox, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	// A stitched geometry that fails to serialize keeps its tile result
+	unserialized := make(map[int]bool, len(failed))
+	for _, failure := range failed {
+		unserialized[failure.Index] = true
+	}
+	next := 0
+	for k, position := range stitchedPositions {
+		if unserialized[k] {
+			continue
+		}
+		features[position] = serialized[next]
+		next++
+	}
+	return len(serialized), nil
+}
+
+// nearOtherTile reports whether bounds, grown by radius, touch any tile other than tile
+func nearOtherTile(bounds *geos.Box2D, tile int, tileBounds []*geos.Box2D, radius float64) bool {
+	grown := geos.NewBox2D(bounds.MinX-radius, bounds.MinY-radius, bounds.MaxX+radius, bounds.MaxY+radius)
+	for t, other := range tileBounds {
+		if t != tile && !other.IsEmpty() && grown.Intersects(other) {
+			return true
+		}
+	}
+	return false
+}
+
+// finishStitchedGeometry repairs and truncates a geometry changed by seam
+// snapping the way the validation phase does, consuming geom
+func finishStitchedGeometry(geom *geos.Geom, index int, options CleanTopologyOptions) (*geos.Geom, error) {
+	if !geom.IsValid() {
+		repaired, _ := RepairGeometry(geom, index, options.RepairMethod)
+		geom.Destroy()
+		if repaired == nil {
+			return nil, fmt.Errorf("seam snapping left an invalid geometry that could not be repaired")
+		}
+		geom = repaired
+	}
+	defer geom.Destroy()
+
+	return utils.TruncateFullGeometry(geom, options.Precision)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"log"
+	"math"
+	"sort"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+)
+
+const (
+	// DefaultSnapToleranceMeters is the snap tolerance used when none is requested or estimated
+	DefaultSnapToleranceMeters = 0.4
+	// maxEstimatedToleranceMeters bounds the search for near-coincident vertices;
+	// spacing wider than this is treated as a real gap rather than digitizing noise
+	maxEstimatedToleranceMeters = 2.0
+	// minEstimatedToleranceMeters keeps the estimate above floating point noise
+	minEstimatedToleranceMeters = 0.01
+	// toleranceHeadroom places the estimate slightly above the median spacing
+	toleranceHeadroom = 1.25
+	// maxSampledGeometries and maxSampledVertices cap the cost of the estimate on large inputs
+	maxSampledGeometries = 500
+	maxSampledVertices   = 200
+)
+
+// Tolerance sources reported alongside the cleaning result
+const (
+	ToleranceSourceDefault  = "default"
+	ToleranceSourceExplicit = "explicit"
+	ToleranceSourceEstimate = "estimated"
+)
+
+// EstimateSnapToleranceMeters samples the distance from vertices of each geometry
+// to the nearest distinct vertex of its neighbours and returns a tolerance slightly
+// above the median, adapting snapping to the resolution the data was digitized at.
+// Shared vertices (distance zero) and spacing beyond maxEstimatedToleranceMeters
+// are ignored. It returns false when no adjacent vertices were found.
+func EstimateSnapToleranceMeters(geomFeatures []GeomFeature) (float64, bool) {
+	searchDistance := utils.CalculateWGS84ToleranceFromMeters(maxEstimatedToleranceMeters)
+	spatialIndex := utils.NewSpatialIndex(searchDistance * 50)
+	for i, geomFeature := range geomFeatures {
+		if err := spatialIndex.AddGeometry(geomFeature.Geom, i, geomFeature.Properties); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+
+	step := 1
+	if len(geomFeatures) > maxSampledGeometries {
+		step = len(geomFeatures) / maxSampledGeometries
+	}
+
+	distances := make([]float64, 0)
+	for i := 0; i < len(geomFeatures); i += step {
+		coordinates := sampleCoordinates(utils.Coordinates(geomFeatures[i].Geom))
+		for _, neighbor := range spatialIndex.FindNeighbors(geomFeatures[i].Geom, searchDistance) {
+			if neighbor.Index == i {
+				continue
+			}
+			neighborCoordinates := sampleCoordinates(utils.Coordinates(neighbor.Geom))
+			for _, coordinate := range coordinates {
+				nearest := nearestVertexDistance(coordinate, neighborCoordinates)
+				if nearest > 0 && nearest <= maxEstimatedToleranceMeters {
+					distances = append(distances, nearest)
+				}
+			}
+		}
+	}
+
+	if len(distances) == 0 {
+		return 0, false
+	}
+
+	sort.Float64s(distances)
+	median := distances[len(distances)/2]
+	return math.Max(median*toleranceHeadroom, minEstimatedToleranceMeters), true
+}
+
+// sampleCoordinates thins a coordinate list to at most maxSampledVertices entries
+func sampleCoordinates(coordinates [][]float64) [][]float64 {
+	if len(coordinates) <= maxSampledVertices {
+		return coordinates
+	}
+	step := len(coordinates) / maxSampledVertices
+	sampled := make([][]float64, 0, maxSampledVertices+1)
+	for i := 0; i < len(coordinates); i += step {
+		sampled = append(sampled, coordinates[i])
+	}
+	return sampled
+}
+
+// nearestVertexDistance returns the distance in meters from a coordinate to the closest of candidates
+func nearestVertexDistance(coordinate []float64, candidates [][]float64) float64 {
+	nearest := math.Inf(1)
+	for _, candidate := range candidates {
+		distance := utils.HaversineDistance(coordinate[0], coordinate[1], candidate[0], candidate[1])
+		if distance < nearest {
+			nearest = distance
+		}
+	}
+	return nearest
+}
@@ -4,24 +4,257 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
 	"runtime"
+	"sort"
+	"sync"
 
 	"github.com/bsaid97/go-polygon-fixer/utils"
 	"github.com/twpayne/go-geos"
 )
 
 type TopologyCleaningResult struct {
-	Type     string    `json:"type"`
-	Features []Feature `json:"features"`
+	Type string `json:"type"`
+	// CRS names the output crs when it is not WGS84, as a legacy GeoJSON crs member
+	CRS      map[string]interface{} `json:"crs,omitempty"`
+	BBox     []float64              `json:"bbox,omitempty"`
+	Features []Feature              `json:"features"`
+	// MercatorClampedPositions counts positions clamped to the Web Mercator
+	// latitude range when OutputCRS is OutputCRSWebMercator
+	MercatorClampedPositions int `json:"mercatorClampedPositions,omitempty"`
+	// SkippedFeatures lists the input positions of features that could not be
+	// decoded, or whose cleaned geometry could not be serialized outside lenient mode
+	SkippedFeatureCount int   `json:"skippedFeatureCount,omitempty"`
+	SkippedFeatures     []int `json:"skippedFeatures,omitempty"`
+	// ToleranceMeters is the snap tolerance used and ToleranceSource how it was chosen
+	ToleranceMeters float64 `json:"toleranceMeters,omitempty"`
+	ToleranceSource string  `json:"toleranceSource,omitempty"`
+	// AdjacencyToleranceMeters is the tolerance gaps and overlaps were reported at
+	AdjacencyToleranceMeters float64 `json:"adjacencyToleranceMeters,omitempty"`
+	// SnapPasses counts the snapping passes that changed at least one geometry
+	SnapPasses int `json:"snapPasses,omitempty"`
+	// RolledBackSnaps counts features restored to their pre-snap geometry because
+	// snapping made them overlap another geometry
+	RolledBackSnaps int `json:"rolledBackSnaps,omitempty"`
+	// SliverOverlapsResolved counts overlaps clipped from one polygon of the pair
+	// when ResolveSliverOverlaps is requested
+	SliverOverlapsResolved int `json:"sliverOverlapsResolved,omitempty"`
+	// DuplicatesRemoved counts features dropped as exact geometric duplicates
+	DuplicatesRemoved int `json:"duplicatesRemoved,omitempty"`
+	// UnfixedFeatures counts features passed through unchanged in lenient mode
+	UnfixedFeatures int `json:"unfixedFeatures,omitempty"`
+	// PrecisionReducedFeatures counts geometries that lost vertices when snapped
+	// to the fixed precision model
+	PrecisionReducedFeatures int `json:"precisionReducedFeatures,omitempty"`
+	// SpikesRemoved counts spike vertices dropped when RemoveSpikes is requested
+	SpikesRemoved int `json:"spikesRemoved,omitempty"`
+	// RepairMethods counts the invalid geometries each repair strategy made
+	// valid, by strategy name
+	RepairMethods map[string]int `json:"repairMethods,omitempty"`
+	// Profile lists the slowest features when profiling is requested
+	Profile []utils.FeatureTiming `json:"profile,omitempty"`
+	// Original holds the input geometries when IncludeOriginal is requested
+	Original []Feature `json:"-"`
+	// SpatialIndexGrid holds the occupied spatial index cells when DebugSpatialIndex is requested
+	SpatialIndexGrid []Feature `json:"-"`
+	// Report holds the coverage and boundary preservation checks for report.json
+	Report *CleaningReport `json:"-"`
+	// TileCount is the number of tiles cleaned when BatchSize splits the request,
+	// and StitchedFeatures how many features seam snapping then changed
+	TileCount        int `json:"tileCount,omitempty"`
+	StitchedFeatures int `json:"stitchedFeatures,omitempty"`
+}
+
+// CleanTopologyOptions controls optional behaviour of the topology cleaning pipeline
+type CleanTopologyOptions struct {
+	// IncludeOriginal returns the parsed input geometries alongside the cleaned ones
+	IncludeOriginal bool
+	// Flatten drops Z ordinates from features passed through uncleaned, the
+	// only ones that can still carry them: truncation rebuilds every cleaned
+	// ring from X/Y alone
+	Flatten bool
+	// Precision is the number of decimal places coordinates are truncated to
+	Precision int
+	// SortBy orders output features by a property name; empty or "index" keeps input order
+	SortBy string
+	// KeepNullGeometries passes features with a null geometry through to the output untouched
+	KeepNullGeometries bool
+	// PassThroughNonPolygons returns points, lines and other non-polygonal
+	// features unchanged alongside the cleaned polygons instead of dropping
+	// them. They are left out of the shapefile, which holds one shape type.
+	PassThroughNonPolygons bool
+	// IncludeInputIndex keeps the InputIndexProperty on every output feature, so
+	// features can be joined back to the request by position even when some
+	// were dropped. Output is in input order either way.
+	IncludeInputIndex bool
+	// PrecisionScale fixes the precision model of every geometry to a grid of
+	// 1/PrecisionScale degrees before cleaning, so GEOS overlay operations round
+	// to that grid deterministically instead of working in full floating point.
+	// 1e7 matches 7-decimal truncation (about 1.1cm at the equator); the grid
+	// size in meters is roughly 111000/PrecisionScale and must stay well below
+	// the snap tolerance. Zero keeps the floating precision model.
+	PrecisionScale float64
+	// SimplifyToleranceMeters simplifies shapefile geometries with
+	// SimplifyPreserveTopology just before export; the GeoJSON keeps full
+	// fidelity. Zero disables simplification.
+	SimplifyToleranceMeters float64
+	// DBFDecimals overrides the decimal places of numeric shapefile properties
+	// by property name, zero storing integers; other properties are sized from
+	// their value in the first feature
+	DBFDecimals map[string]int
+	// Lenient never drops a feature it cannot clean: the original geometry is
+	// passed through unchanged and flagged with _unfixed and _unfixed_reason
+	// properties. Only undecodable features (see SkippedFeatures) and, when
+	// requested, removed duplicates are still missing from the output.
+	Lenient bool
+	// QuadSegs is the buffer quadrant segment count used for neighbour search and gap analysis
+	QuadSegs int
+	// ToleranceMeters is an explicit snap tolerance; zero means use the default or an estimate
+	ToleranceMeters float64
+	// AdjacencyToleranceMeters is the tolerance at which coverage validation
+	// treats polygons as adjacent when reporting gaps and overlaps, independent
+	// of how tightly boundaries are snapped. Zero uses the snap tolerance.
+	AdjacencyToleranceMeters float64
+	// AutoTolerance estimates the snap tolerance from vertex spacing between adjacent
+	// geometries when ToleranceMeters is not set
+	AutoTolerance bool
+	// StrictCoverage fails the request instead of producing output when any pair
+	// of geometries overlaps by more than StrictOverlapAreaM2
+	StrictCoverage      bool
+	StrictOverlapAreaM2 float64
+	// ResolveSliverOverlaps clips each overlap no larger than SliverMaxAreaRatio
+	// of a polygon's area from the polygon SliverClipRule picks (SliverClipSmaller
+	// or SliverClipLarger), leaving a clean shared boundary. Larger overlaps are
+	// reported as before.
+	ResolveSliverOverlaps bool
+	SliverMaxAreaRatio    float64
+	SliverClipRule        string
+	// RepairMethod selects how invalid geometries are repaired: RepairMethodMakeValid or RepairMethodBuffer0
+	RepairMethod string
+	// RemoveSpikes drops vertices whose angle is below SpikeAngleDegrees (see
+	// utils.RemoveSpikes) before cleaning, so digitizing spikes are neither
+	// kept by repair nor snapped to
+	RemoveSpikes      bool
+	SpikeAngleDegrees float64
+	// Dedupe collapses geometrically equal features, combining their properties
+	// with DedupeStrategy (DedupeKeepFirst, DedupeKeepLast or DedupeMerge)
+	Dedupe         bool
+	DedupeStrategy string
+	// OutputPrecision is the number of decimal places coordinates are emitted with;
+	// negative keeps the processing Precision
+	OutputPrecision int
+	// OutputCRS is the crs of the output, OutputCRSWGS84 or OutputCRSWebMercator
+	// (the latter with OutputPrecision counting decimals of meters)
+	OutputCRS string
+	// Profile records per-feature processing time and reports the ProfileTop slowest features
+	Profile    bool
+	ProfileTop int
+	// IncludeBBox adds an RFC 7946 bbox to the collection, and IncludeFeatureBBox to every cleaned feature
+	IncludeBBox        bool
+	IncludeFeatureBBox bool
+	// SnapSearchFactor and CoverageSearchFactor scale the snap tolerance into the
+	// neighbour search radius used when snapping and when analysing gaps between
+	// pairs. Larger factors consider more distant neighbours, catching wider
+	// misalignments at the cost of more geometry comparisons; smaller factors are
+	// faster but can miss neighbours whose boundaries are far apart.
+	SnapSearchFactor     float64
+	CoverageSearchFactor float64
+	// SnapPasses is the maximum number of snapping passes. Snapping A to B and
+	// then B to C can reopen the A-B seam, so later passes re-index the snapped
+	// geometries and snap again, stopping early once a pass changes nothing.
+	SnapPasses int
+	// OutputName is the base name of the zip members; empty means utils.DefaultOutputName
+	OutputName string
+	// Output selects the zip layout: OutputShapefile, or OutputFeatureFiles for
+	// one GeoJSON file per cleaned feature named by its FileKey property
+	Output  string
+	FileKey string
+	// KeepProperties lists the input properties kept on output features,
+	// dropping the rest; empty keeps them all. Sorting happens before the
+	// properties are dropped, so SortBy may name an unlisted key.
+	KeepProperties []string
+	// DebugSpatialIndex adds the occupied spatial index grid cells to the output
+	// as rectangle features, for diagnosing unexpected snapping neighbours
+	DebugSpatialIndex bool
+	// ValidatePreservation compares every cleaned geometry with a copy of its
+	// input to report boundary distortion. The copies double geometry memory,
+	// so turning it off skips them, and with them snap rollback, unless Lenient
+	// or IncludeOriginal need them anyway.
+	ValidatePreservation bool
+	// BatchSize cleans requests with more features in spatial tiles of at most
+	// BatchSize features, capping peak memory, then snaps features along tile
+	// seams to their neighbours across the seam. Duplicate removal, tolerance
+	// estimation, snap rollback and coverage reporting only see one tile at a
+	// time, so results can differ from an untiled run near seams. Zero cleans
+	// the whole request at once.
+	BatchSize int
+}
+
+// Default neighbour search radii, as multiples of the snap tolerance
+const (
+	DefaultSnapSearchFactor     = 5.0
+	DefaultCoverageSearchFactor = 50.0
+)
+
+// Repair methods for invalid geometries. MakeValid keeps every vertex and all of
+// the input's area, so it is the safe default. A zero-width buffer rebuilds the
+// polygon from its outline: it often gives a cleaner result for simple
+// self-intersections such as bow-ties or small spikes, but it discards the
+// smaller lobe of a figure-eight and can drop slivers, so use it only when such
+// losses are acceptable.
+const (
+	RepairMethodMakeValid = "makeValid"
+	RepairMethodBuffer0   = "buffer0"
+)
+
+// Zip layouts for cleaned output. The shapefile zip holds the whole result as
+// JSON and a shapefile; the feature files zip holds only the cleaned features,
+// one GeoJSON Feature per file, for reviewing parcels one at a time.
+const (
+	OutputShapefile    = "shapefile"
+	OutputFeatureFiles = "featureFiles"
+)
+
+// CoverageViolationError is returned by CleanTopology in strict coverage mode
+// when the input contains overlaps that must be fixed upstream
+type CoverageViolationError struct {
+	ThresholdM2 float64       `json:"thresholdM2"`
+	Overlaps    []OverlapPair `json:"overlaps"`
+}
+
+func (e *CoverageViolationError) Error() string {
+	return fmt.Sprintf("strict coverage violated: %d overlapping pair(s) exceed %.3f m²", len(e.Overlaps), e.ThresholdM2)
+}
+
+// DefaultCleanTopologyOptions returns the options used when a request sets none
+func DefaultCleanTopologyOptions() CleanTopologyOptions {
+	return CleanTopologyOptions{
+		Precision:            utils.DefaultPrecision,
+		QuadSegs:             utils.DefaultQuadSegs,
+		RepairMethod:         RepairMethodMakeValid,
+		SliverMaxAreaRatio:   DefaultSliverMaxAreaRatio,
+		SliverClipRule:       SliverClipSmaller,
+		Output:               OutputShapefile,
+		SpikeAngleDegrees:    utils.DefaultSpikeAngleDegrees,
+		ProfileTop:           10,
+		OutputPrecision:      -1,
+		OutputCRS:            OutputCRSWGS84,
+		DedupeStrategy:       DedupeKeepFirst,
+		SnapSearchFactor:     DefaultSnapSearchFactor,
+		CoverageSearchFactor: DefaultCoverageSearchFactor,
+		SnapPasses:           1,
+		ValidatePreservation: true,
+	}
 }
 
 type Feature struct {
 	Type       string                 `json:"type"`
+	BBox       []float64              `json:"bbox,omitempty"`
 	Geometry   json.RawMessage        `json:"geometry"`
 	Properties map[string]interface{} `json:"properties"`
 }
 
-func CleanTopology(geometryPayload string) (*TopologyCleaningResult, error) {
+func CleanTopology(geometryPayload string, options CleanTopologyOptions) (*TopologyCleaningResult, error) {
 	// Add panic recovery to prevent server crashes
 	defer func() {
 		if r := recover(); r != nil {
@@ -31,109 +264,471 @@ func CleanTopology(geometryPayload string) (*TopologyCleaningResult, error) {
 	
 	log.Printf("=== CleanTopology function started ===")
 	log.Printf("Payload length: %d characters", len(geometryPayload))
-	var featureCollection struct {
-		Type     string    `json:"type"`
-		Features []Feature `json:"features"`
+	featureCollection, skippedFeatures, err := decodeFeaturesTolerant(geometryPayload)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := json.Unmarshal([]byte(geometryPayload), &featureCollection); err != nil {
-		return nil, fmt.Errorf("failed to parse feature collection: %v", err)
+	fmt.Printf("Processing %d features for topology cleaning\n", len(featureCollection.Features))
+
+	// Tag every feature with its request position so the output can be put
+	// back in input order after phases that drop or reorder features
+	for i := range featureCollection.Features {
+		properties := copyProperties(featureCollection.Features[i].Properties)
+		properties[InputIndexProperty] = inputPosition(i, skippedFeatures)
+		featureCollection.Features[i].Properties = properties
 	}
 
-	fmt.Printf("Processing %d features for topology cleaning\n", len(featureCollection.Features))
+	var result *TopologyCleaningResult
+	if options.BatchSize > 0 && len(featureCollection.Features) > options.BatchSize {
+		result, err = cleanFeaturesInTiles(featureCollection.Features, options)
+	} else {
+		result, err = cleanFeatures(featureCollection.Features, options)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Features whose cleaned geometry failed to serialize are already listed
+	result.SkippedFeatures = append(skippedFeatures, result.SkippedFeatures...)
+	sort.Ints(result.SkippedFeatures)
+	result.SkippedFeatureCount = len(result.SkippedFeatures)
+
+	restoreInputOrder(result.Features, options.IncludeInputIndex)
+	if !options.IncludeInputIndex {
+		for _, feature := range result.Original {
+			delete(feature.Properties, InputIndexProperty)
+		}
+	}
+	SortFeatures(result.Features, options.SortBy)
+	if len(options.KeepProperties) > 0 {
+		for _, features := range [][]Feature{result.Features, result.Original} {
+			for i := range features {
+				features[i].Properties = SelectProperties(features[i].Properties, options.KeepProperties)
+			}
+		}
+	}
 
-	// Calculate snap tolerance based on 40cm gaps in real-world data
-	snapTolerance := utils.CalculateWGS84ToleranceFromMeters(0.4) // 40cm tolerance
-	fmt.Printf("Using snap tolerance: %e degrees (40cm)\n", snapTolerance)
+	if options.OutputCRS == OutputCRSWebMercator {
+		if err := projectToWebMercator(result, options.OutputPrecision); err != nil {
+			return nil, fmt.Errorf("failed to reproject to EPSG:3857: %v", err)
+		}
+	}
 
-	// Create spatial index for efficient neighbor detection
-	spatialIndex := utils.NewSpatialIndex(snapTolerance * 100) // Use larger cells for efficiency
+	fmt.Printf("Topology cleaning complete. Processed %d features\n", len(result.Features))
+	return result, nil
+}
+
+// cleanFeatures runs the cleaning pipeline over decoded features, each tagged
+// with its InputIndexProperty. Features come back in pipeline order with
+// pass-through features last; profiled features are reported by request position.
+func cleanFeatures(features []Feature, options CleanTopologyOptions) (*TopologyCleaningResult, error) {
+	// A nil profiler makes every timing call a no-op
+	var profiler *utils.FeatureProfiler
+	if options.Profile {
+		profiler = utils.NewFeatureProfiler()
+	}
 
 	// Parse geometries in parallel
-	geomFeatures, err := parseGeometriesParallel(featureCollection.Features)
+	geomFeatures, passThroughFeatures, unfixedCount, err := parseGeometriesParallel(features, options, profiler)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse geometries: %v", err)
 	}
-	
-	// Keep a copy of original geometries for boundary preservation validation
-	originalGeomFeatures := make([]GeomFeature, len(geomFeatures))
-	for i, geomFeature := range geomFeatures {
-		if geomFeature.Geom != nil {
-			clonedGeom := geomFeature.Geom.Clone()
-			originalGeomFeatures[i] = GeomFeature{
-				Geom:       clonedGeom,
-				Properties: geomFeature.Properties,
-			}
+
+	// Coincident duplicates would otherwise show up as zero-width overlaps
+	duplicatesRemoved := 0
+	if options.Dedupe {
+		var kept []int
+		parsedCount := len(geomFeatures)
+		geomFeatures, kept = DedupeGeometries(geomFeatures, options.DedupeStrategy)
+		profiler.CompactIndexMap(kept)
+		duplicatesRemoved = parsedCount - len(geomFeatures)
+		log.Printf("Removed %d duplicate geometries", duplicatesRemoved)
+	}
+
+	// A fixed grid makes overlay results reproducible on dense shared boundaries
+	precisionReduced := 0
+	if options.PrecisionScale > 0 {
+		precisionReduced = applyPrecisionModel(geomFeatures, 1/options.PrecisionScale)
+		log.Printf("Applied precision model (scale %g): %d geometries reduced", options.PrecisionScale, precisionReduced)
+	}
+
+	spikesRemoved := 0
+	if options.RemoveSpikes {
+		spikesRemoved = removeSpikes(geomFeatures, options.SpikeAngleDegrees)
+		log.Printf("Removed %d spike vertices (angle below %g°)", spikesRemoved, options.SpikeAngleDegrees)
+	}
+
+	// Default to 40cm gaps in real-world data unless told or asked to estimate otherwise
+	toleranceMeters, toleranceSource := DefaultSnapToleranceMeters, ToleranceSourceDefault
+	if options.ToleranceMeters > 0 {
+		toleranceMeters, toleranceSource = options.ToleranceMeters, ToleranceSourceExplicit
+	} else if options.AutoTolerance {
+		if estimated, ok := EstimateSnapToleranceMeters(geomFeatures); ok {
+			toleranceMeters, toleranceSource = estimated, ToleranceSourceEstimate
+		} else {
+			log.Printf("No adjacent vertices found to estimate tolerance, using default")
 		}
 	}
+	snapTolerance := utils.CalculateWGS84ToleranceFromMeters(toleranceMeters)
+	fmt.Printf("Using snap tolerance: %e degrees (%.3fm, %s)\n", snapTolerance, toleranceMeters, toleranceSource)
 
-	// Build spatial index
-	for i, geomFeature := range geomFeatures {
-		spatialIndex.AddGeometry(geomFeature.Geom, i, geomFeature.Properties)
+	adjacencyToleranceMeters := toleranceMeters
+	if options.AdjacencyToleranceMeters > 0 {
+		adjacencyToleranceMeters = options.AdjacencyToleranceMeters
 	}
+	adjacencyTolerance := utils.CalculateWGS84ToleranceFromMeters(adjacencyToleranceMeters)
+	fmt.Printf("Using adjacency tolerance: %e degrees (%.3fm)\n", adjacencyTolerance, adjacencyToleranceMeters)
+
+	// Larger cells than the tolerance keep the index small
+	cellSize := snapTolerance * 100
+	
+	// Keep a copy of original geometries for boundary preservation validation,
+	// snap rollback, lenient fallback and the original layer, when any is wanted
+	var originalGeomFeatures []GeomFeature
+	if keepsOriginals(options) {
+		originalGeomFeatures = cloneGeomFeatures(geomFeatures)
+	} else {
+		log.Printf("Skipping original geometry copies: boundary preservation validation and snap rollback are off")
+	}
+
+	// Create spatial index for efficient neighbor detection
+	spatialIndex := buildSpatialIndex(geomFeatures, cellSize, options.QuadSegs)
 
 	fmt.Printf("Successfully indexed %d polygon geometries\n", len(geomFeatures))
 
+	var spatialIndexGrid []Feature
+	if options.DebugSpatialIndex {
+		spatialIndexGrid = spatialIndexGridFeatures(spatialIndex)
+	}
+
 	// Clean topology by snapping nearby boundaries in parallel
 	log.Printf("About to start boundary snapping...")
-	cleanedGeometries, err := snapBoundariesParallel(geomFeatures, spatialIndex, snapTolerance)
+	cleanedGeometries, snapped, snapPasses, err := snapBoundariesInPasses(geomFeatures, spatialIndex, cellSize, snapTolerance, options.SnapSearchFactor, options.SnapPasses, options.QuadSegs, profiler)
 	if err != nil {
 		return nil, fmt.Errorf("failed to snap boundaries: %v", err)
 	}
 
 	// Validate and repair geometries in parallel
 	log.Printf("About to start geometry validation and repair...")
-	validatedGeometries, err := validateAndRepairGeometriesParallel(cleanedGeometries)
+	validatedGeometries, unfixed, repairMethods, err := validateAndRepairGeometriesParallel(cleanedGeometries, options.Precision, options.RepairMethod, profiler)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate geometries: %v", err)
 	}
 
+	// In lenient mode geometries that could not be repaired revert to their
+	// input, and are kept out of snap rollback so they stay that way
+	if options.Lenient {
+		for index, reason := range unfixed {
+			if validatedGeometries[index].Geom != nil {
+				validatedGeometries[index].Geom.Destroy()
+			}
+			validatedGeometries[index] = GeomFeature{
+				Geom:       originalGeomFeatures[index].Geom.Clone(),
+				Properties: UnfixedProperties(originalGeomFeatures[index].Properties, reason),
+			}
+			snapped[index] = false
+		}
+		unfixedCount += len(unfixed)
+	}
+
 	// Perform coverage validation in parallel
 	log.Printf("About to start coverage validation...")
-	coverageReport := validateCoverageParallel(validatedGeometries, snapTolerance)
-	log.Printf("Coverage validation finished: %d gaps (%d boundary segments), %d overlaps", 
-		coverageReport.GapCount, coverageReport.BoundaryGaps, coverageReport.OverlapCount)
-	log.Printf("Gap details: max width: %f, total length: %f", 
-		coverageReport.MaxGapWidth, coverageReport.TotalGapLength)
+	coverageReport := validateCoverageParallel(validatedGeometries, adjacencyTolerance, options.CoverageSearchFactor, options.QuadSegs, profiler)
+
+	// Snapping must not make topology worse; undo snaps that introduced overlaps
+	rolledBackSnaps := 0
+	if originalGeomFeatures != nil {
+		rolledBackSnaps = rollBackOverlappingSnaps(validatedGeometries, originalGeomFeatures, snapped, coverageReport, toleranceMeters, options.Precision, options.RepairMethod)
+	}
+	if rolledBackSnaps > 0 {
+		log.Printf("Rolled back %d snap(s) that increased overlaps, re-validating coverage...", rolledBackSnaps)
+		coverageReport = validateCoverageParallel(validatedGeometries, adjacencyTolerance, options.CoverageSearchFactor, options.QuadSegs, profiler)
+	}
+	log.Printf("Coverage validation finished: %d gaps (%d boundary segments), %d overlaps, %d containments", 
+		coverageReport.GapCount, coverageReport.BoundaryGaps, coverageReport.OverlapCount, coverageReport.ContainmentCount)
+	log.Printf("Gap details: max width: %.3f m, total length: %.3f m, area: %.3f m², overlap area: %.3f m²",
+		coverageReport.MaxGapWidthM, coverageReport.TotalGapLengthM, coverageReport.GapAreaM2, coverageReport.OverlapAreaM2)
+
+	// Overlaps are a data contract violation in strict mode, not something to clean over
+	if options.StrictCoverage {
+		if violation := strictCoverageViolation(coverageReport, options.StrictOverlapAreaM2); violation != nil {
+			destroyGeomFeatures(originalGeomFeatures)
+			destroyGeomFeatures(validatedGeometries)
+			return nil, violation
+		}
+	}
+
+	// Clip sliver overlaps along shared edges once strict mode has had its say
+	sliverOverlapsResolved := 0
+	if options.ResolveSliverOverlaps && coverageReport.OverlapCount > 0 {
+		sliverOverlapsResolved = resolveSliverOverlaps(validatedGeometries, coverageReport, options.SliverMaxAreaRatio, options.SliverClipRule)
+		if sliverOverlapsResolved > 0 {
+			log.Printf("Resolved %d sliver overlap(s), re-validating coverage...", sliverOverlapsResolved)
+			coverageReport = validateCoverageParallel(validatedGeometries, adjacencyTolerance, options.CoverageSearchFactor, options.QuadSegs, profiler)
+		}
+	}
 
 	// Validate boundary preservation
-	log.Printf("Validating boundary preservation...")
-	preservationReport := validateBoundaryPreservation(originalGeomFeatures, validatedGeometries, snapTolerance)
-	log.Printf("Boundary preservation: %d/%d geometries had significant changes (avg distortion: %f, max: %f)", 
-		preservationReport.SignificantChanges, preservationReport.TotalGeometries, 
-		preservationReport.AverageDistortion, preservationReport.MaxDistortion)
+	var preservationReport *BoundaryPreservationReport
+	if options.ValidatePreservation {
+		log.Printf("Validating boundary preservation...")
+		report := validateBoundaryPreservation(originalGeomFeatures, validatedGeometries, snapTolerance)
+		preservationReport = &report
+		log.Printf("Boundary preservation: %d/%d geometries had significant changes (avg distortion: %f, max: %f)",
+			preservationReport.SignificantChanges, preservationReport.TotalGeometries,
+			preservationReport.AverageDistortion, preservationReport.MaxDistortion)
+	}
+
+	// Convert back to GeoJSON feature collection
+	result := &TopologyCleaningResult{
+		Type:                     "FeatureCollection",
+		Features:                 make([]Feature, 0),
+		ToleranceMeters:          toleranceMeters,
+		ToleranceSource:          toleranceSource,
+		AdjacencyToleranceMeters: adjacencyToleranceMeters,
+		SnapPasses:               snapPasses,
+		RolledBackSnaps:          rolledBackSnaps,
+		SliverOverlapsResolved:   sliverOverlapsResolved,
+		DuplicatesRemoved:        duplicatesRemoved,
+		UnfixedFeatures:          unfixedCount,
+		PrecisionReducedFeatures: precisionReduced,
+		SpikesRemoved:            spikesRemoved,
+		RepairMethods:            repairMethods,
+		SpatialIndexGrid:         spatialIndexGrid,
+		Report:                   newCleaningReport(coverageReport, preservationReport, validatedGeometries),
+	}
+
+	// Serialize the originals before they are freed so reviewers can diff before/after
+	if options.IncludeOriginal {
+		result.Original, _, err = serializeGeometriesParallel(originalGeomFeatures, options.OutputPrecision, options.IncludeFeatureBBox, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to serialize original geometries: %v", err)
+		}
+	}
 
 	// Clean up original geometry copies
-	for _, geomFeature := range originalGeomFeatures {
+	destroyGeomFeatures(originalGeomFeatures)
+
+	var unserialized []SerializationResult
+	result.Features, unserialized, err = serializeGeometriesParallel(validatedGeometries, options.OutputPrecision, options.IncludeFeatureBBox, profiler)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize geometries: %v", err)
+	}
+	passThroughFeatures = append(passThroughFeatures, passUnserialized(result, features, validatedGeometries, unserialized, options.Lenient)...)
+
+	if options.IncludeBBox {
+		for _, geomFeature := range validatedGeometries {
+			result.BBox = utils.MergeBBox(result.BBox, utils.BBox(geomFeature.Geom, options.OutputPrecision))
+		}
+	}
+
+	// Report profiled features by their position in the request, including undecodable ones
+	if options.Profile {
+		result.Profile = profiler.Slowest(options.ProfileTop)
+		for i := range result.Profile {
+			result.Profile[i].Index = inputIndex(features[result.Profile[i].Index])
+		}
+	}
+
+	if options.Flatten {
+		flattenFeatures(passThroughFeatures)
+	}
+
+	// Attribute-only features bypass the cleaning phases
+	result.Features = append(result.Features, passThroughFeatures...)
+
+	return result, nil
+}
+
+// spatialIndexGridFeatures returns a rectangle feature for every occupied cell
+// of spatialIndex, tagged with the indices of the geometries it holds. Indices
+// are positions in the cleaning pipeline, after undecodable and duplicate
+// features have been removed.
+func spatialIndexGridFeatures(spatialIndex *utils.SpatialIndex) []Feature {
+	cells := spatialIndex.Cells()
+	features := make([]Feature, 0, len(cells))
+	for _, cell := range cells {
+		bounds := cell.Bounds
+		geom := geos.NewPolygon([][][]float64{{
+			{bounds.MinX, bounds.MinY},
+			{bounds.MaxX, bounds.MinY},
+			{bounds.MaxX, bounds.MaxY},
+			{bounds.MinX, bounds.MaxY},
+			{bounds.MinX, bounds.MinY},
+		}})
+		features = append(features, newGeomFeature(geom, map[string]interface{}{
+			"geometryCount":   len(cell.Indices),
+			"geometryIndices": cell.Indices,
+		}))
+		geom.Destroy()
+	}
+	return features
+}
+
+// strictCoverageViolation returns the overlaps in report larger than thresholdM2, or nil if there are none
+func strictCoverageViolation(report CoverageReport, thresholdM2 float64) *CoverageViolationError {
+	violation := &CoverageViolationError{ThresholdM2: thresholdM2, Overlaps: make([]OverlapPair, 0)}
+	for _, pair := range report.OverlapPairs {
+		if pair.AreaM2 > thresholdM2 {
+			violation.Overlaps = append(violation.Overlaps, pair)
+		}
+	}
+	if len(violation.Overlaps) == 0 {
+		return nil
+	}
+	sort.Slice(violation.Overlaps, func(i, j int) bool {
+		if violation.Overlaps[i].A != violation.Overlaps[j].A {
+			return violation.Overlaps[i].A < violation.Overlaps[j].A
+		}
+		return violation.Overlaps[i].B < violation.Overlaps[j].B
+	})
+	return violation
+}
+
+// applyPrecisionModel snaps each geometry to a fixed grid of gridSize degrees,
+// keeping the output valid. It returns how many geometries were reduced, i.e.
+// lost vertices because near-coincident ones merged or parts collapsed.
+// Geometries that would collapse entirely keep their floating precision.
+func applyPrecisionModel(geomFeatures []GeomFeature, gridSize float64) int {
+	reduced := 0
+	for i, geomFeature := range geomFeatures {
+		if geomFeature.Geom == nil {
+			continue
+		}
+
+		fixed := geomFeature.Geom.SetPrecision(gridSize, geos.PrecisionRuleValidOutput)
+		if fixed == nil || fixed.IsEmpty() {
+			log.Printf("Precision model would collapse geometry %d, keeping floating precision", i)
+			if fixed != nil {
+				fixed.Destroy()
+			}
+			continue
+		}
+
+		if fixed.NumCoordinates() < geomFeature.Geom.NumCoordinates() {
+			log.Printf("Precision model reduced geometry %d from %d to %d vertices", i, geomFeature.Geom.NumCoordinates(), fixed.NumCoordinates())
+			reduced++
+		}
+		geomFeature.Geom.Destroy()
+		geomFeatures[i].Geom = fixed
+	}
+	return reduced
+}
+
+// removeSpikes drops spike vertices sharper than minAngleDeg from every
+// geometry, returning the number of vertices removed. Geometries that would
+// collapse entirely are kept as they are.
+func removeSpikes(geomFeatures []GeomFeature, minAngleDeg float64) int {
+	total := 0
+	for i, geomFeature := range geomFeatures {
+		if geomFeature.Geom == nil {
+			continue
+		}
+
+		despiked, removed := utils.RemoveSpikes(geomFeature.Geom, minAngleDeg)
+		if despiked == nil {
+			log.Printf("Removing spikes would collapse geometry %d, keeping it unchanged", i)
+			continue
+		}
+		if removed == 0 {
+			despiked.Destroy()
+			continue
+		}
+
+		log.Printf("Removed %d spike vertices from geometry %d", removed, i)
+		total += removed
+		geomFeature.Geom.Destroy()
+		geomFeatures[i].Geom = despiked
+	}
+	return total
+}
+
+// destroyGeomFeatures frees the GEOS geometries of a feature slice
+func destroyGeomFeatures(geomFeatures []GeomFeature) {
+	for _, geomFeature := range geomFeatures {
 		if geomFeature.Geom != nil {
 			geomFeature.Geom.Destroy()
 		}
 	}
+}
 
-	// Convert back to GeoJSON feature collection
-	result := &TopologyCleaningResult{
-		Type:     "FeatureCollection",
-		Features: make([]Feature, 0),
+// RepairLocationProperty holds, on repaired features, the GeoJSON Point where
+// GEOS found the geometry invalid before repair
+const RepairLocationProperty = "_repair_location"
+
+// RepairMethodProperty names, on features repaired by /v2/fix-geometry and
+// /fix, the repair strategy whose result was valid
+const RepairMethodProperty = "_repair_method"
+
+// InputIndexProperty holds a feature's position in the /clean-topology request
+const InputIndexProperty = "_input_index"
+
+// restoreInputOrder stably sorts features by their InputIndexProperty, so
+// cleaned and passed-through features come out in request order, and removes
+// the property unless keep is set
+func restoreInputOrder(features []Feature, keep bool) {
+	sort.SliceStable(features, func(i, j int) bool {
+		return inputIndex(features[i]) < inputIndex(features[j])
+	})
+
+	if !keep {
+		for _, feature := range features {
+			delete(feature.Properties, InputIndexProperty)
+		}
 	}
+}
 
-	for _, geomFeature := range validatedGeometries {
-		if geomFeature.Geom != nil {
-			jsonString := geomFeature.Geom.ToGeoJSON(-1)
-			feature := Feature{
-				Type:       "Feature",
-				Properties: geomFeature.Properties,
-				Geometry:   json.RawMessage(jsonString),
-			}
-			result.Features = append(result.Features, feature)
+// inputIndex returns the InputIndexProperty of a feature, sorting features
+// without one last
+func inputIndex(feature Feature) int {
+	index, ok := feature.Properties[InputIndexProperty].(int)
+	if !ok {
+		return math.MaxInt
+	}
+	return index
+}
+
+// inputPosition maps an index into the decoded features back to the feature's
+// position in the request, given the ascending positions of skipped features
+func inputPosition(decodedIndex int, skipped []int) int {
+	position := decodedIndex
+	for _, skippedPosition := range skipped {
+		if skippedPosition <= position {
+			position++
 		}
 	}
+	return position
+}
 
-	fmt.Printf("Topology cleaning complete. Processed %d features\n", len(result.Features))
-	return result, nil
+// decodeFeaturesTolerant decodes each feature of the collection individually so a
+// single malformed feature is skipped instead of failing the whole request. It
+// returns the decoded features and the input positions of the skipped ones.
+func decodeFeaturesTolerant(geometryPayload string) (*FeatureCollection, []int, error) {
+	rawFeatures, err := utils.ScanFeatureArray([]byte(geometryPayload))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse feature collection: %v", err)
+	}
+
+	featureCollection := NewFeatureCollection(make([]Feature, 0, len(rawFeatures)))
+	skipped := make([]int, 0)
+	for i, rawFeature := range rawFeatures {
+		var feature Feature
+		if err := json.Unmarshal(rawFeature, &feature); err != nil {
+			log.Printf("Skipping malformed feature %d: %v", i, err)
+			skipped = append(skipped, i)
+			continue
+		}
+		featureCollection.Features = append(featureCollection.Features, feature)
+	}
+
+	if len(skipped) > 0 {
+		log.Printf("Skipped %d malformed features at positions %v", len(skipped), skipped)
+	}
+	return featureCollection, skipped, nil
 }
 
 // CleanTopologyWithShapefile performs topology cleaning and returns both JSON and shapefile in a zip
-func CleanTopologyWithShapefile(geometryPayload string) ([]byte, error) {
+func CleanTopologyWithShapefile(geometryPayload string, options CleanTopologyOptions) ([]byte, error) {
 	// Add panic recovery to prevent server crashes
 	defer func() {
 		if r := recover(); r != nil {
@@ -144,9 +739,13 @@ func CleanTopologyWithShapefile(geometryPayload string) ([]byte, error) {
 	log.Printf("=== CleanTopologyWithShapefile function started ===")
 	log.Printf("Payload length: %d characters", len(geometryPayload))
 	// First get the cleaned topology result
-	result, err := CleanTopology(geometryPayload)
+	result, err := CleanTopology(geometryPayload, options)
 	if err != nil {
-		return nil, fmt.Errorf("topology cleaning failed: %v", err)
+		return nil, fmt.Errorf("topology cleaning failed: %w", err)
+	}
+
+	if options.Output == OutputFeatureFiles {
+		return featureFilesZip(result.Features, options.FileKey)
 	}
 
 	// Convert result to JSON
@@ -155,26 +754,161 @@ func CleanTopologyWithShapefile(geometryPayload string) ([]byte, error) {
 		return nil, fmt.Errorf("failed to marshal result to JSON: %v", err)
 	}
 
-	// Convert features to interface{} slice for shapefile generation
-	features := make([]interface{}, len(result.Features))
-	for i, feature := range result.Features {
-		featureMap := map[string]interface{}{
-			"type":       feature.Type,
-			"geometry":   json.RawMessage(feature.Geometry),
-			"properties": feature.Properties,
+	// Add before/after layers for diffing when requested
+	var extraEntries []utils.ZipEntry
+	if options.IncludeOriginal {
+		originalData, err := json.Marshal(&TopologyCleaningResult{
+			Type:     "FeatureCollection",
+			Features: result.Original,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal original geometries to JSON: %v", err)
+		}
+		extraEntries = append(extraEntries,
+			utils.ZipEntry{Name: "original.geojson", Data: originalData},
+			utils.ZipEntry{Name: "cleaned.geojson", Data: jsonData},
+		)
+	}
+	if options.DebugSpatialIndex {
+		gridData, err := json.Marshal(&TopologyCleaningResult{
+			Type:     "FeatureCollection",
+			Features: result.SpatialIndexGrid,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal spatial index grid to JSON: %v", err)
+		}
+		extraEntries = append(extraEntries, utils.ZipEntry{Name: "spatial_index_grid.geojson", Data: gridData})
+	}
+
+	// The QA reports make the zip self-describing
+	if result.Report != nil {
+		reportData, err := json.MarshalIndent(result.Report, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal cleaning report to JSON: %v", err)
+		}
+		extraEntries = append(extraEntries, utils.ZipEntry{Name: "report.json", Data: reportData})
+	}
+
+	// Hand features to the shapefile writer one at a time rather than copying the collection
+	// Web Mercator output is already in meters
+	simplifyTolerance := utils.CalculateWGS84ToleranceFromMeters(options.SimplifyToleranceMeters)
+	if options.OutputCRS == OutputCRSWebMercator {
+		simplifyTolerance = options.SimplifyToleranceMeters
+	}
+	features := func(write func(feature utils.ShapefileFeature) error) error {
+		for i, feature := range result.Features {
+			geometry := feature.Geometry
+			if options.PassThroughNonPolygons && !utils.IsNullGeometry(geometry) && !utils.IsPolygonalGeometry(geometry) {
+				log.Printf("Leaving non-polygon feature %d out of the shapefile", i)
+				continue
+			}
+			if options.SimplifyToleranceMeters > 0 {
+				var err error
+				geometry, err = simplifyGeometry(geometry, simplifyTolerance)
+				if err != nil {
+					return fmt.Errorf("failed to simplify geometry for feature %d: %v", i, err)
+				}
+			}
+
+			if err := write(utils.ShapefileFeature{Geometry: geometry, Properties: feature.Properties}); err != nil {
+				return err
+			}
 		}
-		features[i] = featureMap
+		return nil
 	}
 
 	// Generate zip file with both JSON and shapefile
-	zipData, err := utils.GenerateShapefileZip(jsonData, features)
+	outputName := options.OutputName
+	if outputName == "" {
+		outputName = utils.DefaultOutputName
+	}
+	zipData, err := utils.GenerateShapefileZip(outputName, jsonData, features, options.DBFDecimals, extraEntries...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate shapefile zip: %v", err)
+		return nil, fmt.Errorf("failed to generate shapefile zip: %w", err)
+	}
+
+	return zipData, nil
+}
+
+// featureFilesZip packs each feature into its own GeoJSON file, named by its
+// key property or by its position when the property is missing
+func featureFilesZip(features []Feature, key string) ([]byte, error) {
+	names := utils.NewFeatureFileNames()
+	entries := make([]utils.ZipEntry, 0, len(features))
+	for i, feature := range features {
+		data, err := json.Marshal(feature)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal feature %d to JSON: %v", i, err)
+		}
+		keyValue, _ := propertyKey(feature, key)
+		entries = append(entries, utils.ZipEntry{Name: names.Name(keyValue, i), Data: data})
 	}
 
+	zipData, err := utils.GenerateZip(entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate feature files zip: %w", err)
+	}
+	log.Printf("Packed %d features into per-feature GeoJSON files", len(entries))
 	return zipData, nil
 }
 
+// flattenFeatures replaces, in place, geometries carrying Z ordinates by their
+// 2D form. Null geometries and geometries GEOS cannot parse are left as they are.
+func flattenFeatures(features []Feature) {
+	for i, feature := range features {
+		if utils.IsNullGeometry(feature.Geometry) {
+			continue
+		}
+		flattened, err := flattenGeometry(feature.Geometry)
+		if err != nil {
+			log.Printf("Keeping Z ordinates of feature %d: %v", inputIndex(feature), err)
+			continue
+		}
+		features[i].Geometry = flattened
+	}
+}
+
+// flattenGeometry strips Z/M ordinates from a GeoJSON geometry so shapefile export is strictly 2D
+func flattenGeometry(geometry json.RawMessage) (json.RawMessage, error) {
+	geom, err := geos.NewGeomFromGeoJSON(string(geometry))
+	if err != nil {
+		return nil, err
+	}
+	defer geom.Destroy()
+
+	if !geom.HasZ() {
+		return geometry, nil
+	}
+
+	flattened := utils.Force2D(geom)
+	defer flattened.Destroy()
+
+	return json.RawMessage(flattened.ToGeoJSON(-1)), nil
+}
+
+// simplifyGeometry simplifies a GeoJSON geometry to tolerance degrees while
+// keeping it valid. Each geometry is simplified on its own, so boundaries
+// shared with neighbours may no longer coincide exactly.
+func simplifyGeometry(geometry json.RawMessage, tolerance float64) (json.RawMessage, error) {
+	if utils.IsNullGeometry(geometry) {
+		return geometry, nil
+	}
+
+	geom, err := geos.NewGeomFromGeoJSON(string(geometry))
+	if err != nil {
+		return nil, err
+	}
+	defer geom.Destroy()
+
+	simplified := geom.TopologyPreserveSimplify(tolerance)
+	if simplified == nil {
+		return nil, fmt.Errorf("simplification failed")
+	}
+	defer simplified.Destroy()
+
+	return json.RawMessage(simplified.ToGeoJSON(-1)), nil
+}
+
 type GeomFeature struct {
 	Geom       *geos.Geom
 	Properties map[string]interface{}
@@ -190,6 +924,7 @@ type ParsingJob struct {
 type ParsingResult struct {
 	GeomFeature GeomFeature
 	Index       int
+	PassThrough *Feature // Feature to emit unchanged instead of cleaning
 	Error       error
 }
 
@@ -199,6 +934,7 @@ type SnappingJob struct {
 	Index         int
 	SpatialIndex  *utils.SpatialIndex
 	Tolerance     float64
+	SearchFactor  float64
 }
 
 // SnappingResult represents the result of parallel boundary snapping
@@ -220,16 +956,22 @@ type ValidationResult struct {
 	GeomFeature GeomFeature
 	Index       int
 	WasRepaired bool
-	Error       error
+	// RepairMethod names the repair strategy used when WasRepaired is set
+	RepairMethod string
+	// Unfixed is set when the geometry is invalid and could not be repaired
+	Unfixed bool
+	Error   error
 }
 
 // CoverageJob represents a job for parallel coverage validation
 type CoverageJob struct {
-	GeomI     *geos.Geom
-	GeomJ     *geos.Geom
-	IndexI    int
-	IndexJ    int
-	Tolerance float64
+	GeomI        *geos.Geom
+	GeomJ        *geos.Geom
+	IndexI       int
+	IndexJ       int
+	Tolerance    float64
+	SearchFactor float64
+	QuadSegs     int
 }
 
 // CoverageResult represents the result of parallel coverage validation
@@ -238,17 +980,164 @@ type CoverageResult struct {
 	IndexJ         int
 	HasOverlap     bool
 	OverlapArea    float64
+	OverlapAreaM2  float64 // Geodesic overlap area in square meters
 	HasGap         bool
 	GapDistance    float64
 	MaxGapWidth    float64
+	GapDistanceM   float64  // Geodesic gap distance in meters
+	GapAreaM2      float64  // Geodesic area of the gap region in square meters
 	BoundaryGaps   int      // Number of boundary segments with gaps
+	HasContainment bool     // One geometry lies entirely inside the other
+	ContainerIndex int
+	ContainedIndex int
 	Error          error
 }
 
-// parseGeometriesParallel parses geometries in parallel using worker pool
-func parseGeometriesParallel(features []Feature) ([]GeomFeature, error) {
+// SerializationJob represents a geometry to be converted back to a GeoJSON feature
+type SerializationJob struct {
+	GeomFeature GeomFeature
+	Index       int
+}
+
+// SerializationResult represents the result of parallel GeoJSON serialization
+type SerializationResult struct {
+	Feature Feature
+	Index   int
+	Error   error
+}
+
+// serializationContexts hands each serialization worker a GEOS context of its own.
+// Every pipeline geometry lives in the default context, whose mutex would make
+// parallel ToGeoJSON calls run one at a time.
+var serializationContexts = sync.Pool{
+	New: func() interface{} { return geos.NewContext() },
+}
+
+// serializeGeometriesParallel converts geometries to GeoJSON features in parallel,
+// preserving input order and skipping nil geometries. Coordinates are rounded
+// to outputPrecision decimals unless it is negative, and includeBBox adds each
+// feature's bbox. Each worker clones its
+// geometry into a private context (a WKB round trip, far cheaper than writing
+// GeoJSON) so the GeoJSON writing itself runs concurrently. Geometries GEOS
+// fails to serialize are left out of the features and returned as failures,
+// indexed into geomFeatures, for the caller to account for.
+func serializeGeometriesParallel(geomFeatures []GeomFeature, outputPrecision int, includeBBox bool, profiler *utils.FeatureProfiler) ([]Feature, []SerializationResult, error) {
+	jobs := make([]interface{}, 0, len(geomFeatures))
+	for i, geomFeature := range geomFeatures {
+		if geomFeature.Geom != nil {
+			jobs = append(jobs, SerializationJob{GeomFeature: geomFeature, Index: i})
+		}
+	}
+
+	serializeGeometry := func(job interface{}) (result interface{}) {
+		serializationJob := job.(SerializationJob)
+		defer profiler.Track("serialize", serializationJob.Index)()
+
+		// go-geos panics on GEOS errors; only this goroutine can recover them
+		defer func() {
+			if r := recover(); r != nil {
+				result = SerializationResult{Index: serializationJob.Index, Error: fmt.Errorf("%v", r)}
+			}
+		}()
+
+		context := serializationContexts.Get().(*geos.Context)
+		defer serializationContexts.Put(context)
+
+		clone := context.Clone(serializationJob.GeomFeature.Geom)
+		defer clone.Destroy()
+
+		geometry := json.RawMessage(clone.ToGeoJSON(-1))
+		if outputPrecision >= 0 {
+			rounded, err := utils.RoundGeoJSONCoordinates(geometry, outputPrecision)
+			if err != nil {
+				log.Printf("Failed to round output coordinates for geometry %d: %v", serializationJob.Index, err)
+			} else {
+				geometry = rounded
+			}
+		}
+
+		feature := Feature{
+			Type:       "Feature",
+			Properties: serializationJob.GeomFeature.Properties,
+			Geometry:   geometry,
+		}
+		if includeBBox {
+			feature.BBox = utils.BBox(clone, outputPrecision)
+		}
+
+		return SerializationResult{
+			Feature: feature,
+			Index:   serializationJob.Index,
+		}
+	}
+
+	processor := utils.NewParallelProcessor(runtime.NumCPU())
+	results, err := processor.ProcessBatch(jobs, serializeGeometry, "Serializing geometries")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Results arrive in completion order
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].(SerializationResult).Index < results[j].(SerializationResult).Index
+	})
+
+	features := make([]Feature, 0, len(results))
+	var failed []SerializationResult
+	for _, result := range results {
+		serializationResult := result.(SerializationResult)
+		if serializationResult.Error != nil {
+			log.Printf("Geometry %d failed to serialize: %v", serializationResult.Index, serializationResult.Error)
+			failed = append(failed, serializationResult)
+			continue
+		}
+		features = append(features, serializationResult.Feature)
+	}
+	return features, failed, nil
+}
+
+// passUnserialized accounts for cleaned geometries that failed to serialize,
+// given the failures serializeGeometriesParallel returned for geomFeatures and
+// the features the pipeline started from. In lenient mode the feature's input
+// geometry is returned to be passed through flagged unfixed; otherwise its
+// request position is added to the result's SkippedFeatures.
+func passUnserialized(result *TopologyCleaningResult, features []Feature, geomFeatures []GeomFeature, failed []SerializationResult, lenient bool) []Feature {
+	if len(failed) == 0 {
+		return nil
+	}
+
+	inputs := make(map[int]Feature, len(features))
+	for _, feature := range features {
+		inputs[inputIndex(feature)] = feature
+	}
+
+	var passThrough []Feature
+	for _, failure := range failed {
+		properties := geomFeatures[failure.Index].Properties
+		index := inputIndex(Feature{Properties: properties})
+		input, ok := inputs[index]
+		if !lenient || !ok {
+			result.SkippedFeatures = append(result.SkippedFeatures, index)
+			continue
+		}
+
+		passThrough = append(passThrough, Feature{
+			Type:       "Feature",
+			Geometry:   input.Geometry,
+			Properties: UnfixedProperties(properties, fmt.Sprintf("failed to serialize the cleaned geometry: %v", failure.Error)),
+		})
+		result.UnfixedFeatures++
+	}
+	sort.Ints(result.SkippedFeatures)
+	return passThrough
+}
+
+// parseGeometriesParallel parses geometries in parallel using worker pool.
+// Features that should bypass cleaning are returned separately, unchanged,
+// along with how many of those were flagged unfixed in lenient mode.
+func parseGeometriesParallel(features []Feature, options CleanTopologyOptions, profiler *utils.FeatureProfiler) ([]GeomFeature, []Feature, int, error) {
 	if len(features) == 0 {
-		return []GeomFeature{}, nil
+		return []GeomFeature{}, []Feature{}, 0, nil
 	}
 
 	// Create parallel processor
@@ -263,17 +1152,41 @@ func parseGeometriesParallel(features []Feature) ([]GeomFeature, error) {
 	// Define parsing work function
 	parseGeometry := func(job interface{}) interface{} {
 		parsingJob := job.(ParsingJob)
+		defer profiler.Track("parse", parsingJob.Index)()
+		
+		// Attribute-only features have no geometry for GEOS to parse
+		if utils.IsNullGeometry(parsingJob.Feature.Geometry) {
+			if options.KeepNullGeometries {
+				feature := parsingJob.Feature
+				return ParsingResult{Index: parsingJob.Index, PassThrough: &feature}
+			}
+			return ParsingResult{Index: parsingJob.Index, Error: fmt.Errorf("skipping null geometry at feature %d", parsingJob.Index)}
+		}
+		
+		// Cheap structural check so malformed input gets an actionable message
+		if err := utils.ValidateGeoJSONGeometry(parsingJob.Feature.Geometry); err != nil {
+			return ParsingResult{Index: parsingJob.Index, Error: fmt.Errorf("feature %d: %v", parsingJob.Index, err)}
+		}
+		
+		// Close any open rings first; GEOS rejects them outright
+		geometry, ringsClosed, err := utils.CloseRings(parsingJob.Feature.Geometry)
+		if err != nil {
+			return ParsingResult{Index: parsingJob.Index, Error: fmt.Errorf("feature %d: %v", parsingJob.Index, err)}
+		}
+		if ringsClosed {
+			log.Printf("Closed open ring(s) in feature %d", parsingJob.Index)
+		}
 		
 		// Marshal geometry to JSON
-		jsonString, err := json.Marshal(parsingJob.Feature.Geometry)
+		jsonString, err := json.Marshal(geometry)
 		if err != nil {
-			return ParsingResult{Error: fmt.Errorf("error marshalling geometry for feature %d: %v", parsingJob.Index, err)}
+			return ParsingResult{Index: parsingJob.Index, Error: fmt.Errorf("error marshalling geometry for feature %d: %v", parsingJob.Index, err)}
 		}
 
 		// Create GEOS geometry from JSON
 		geom, err := geos.NewGeomFromGeoJSON(string(jsonString))
 		if err != nil {
-			return ParsingResult{Error: fmt.Errorf("error creating geometry for feature %d: %v", parsingJob.Index, err)}
+			return ParsingResult{Index: parsingJob.Index, Error: fmt.Errorf("error creating geometry for feature %d: %v", parsingJob.Index, err)}
 		}
 
 		// Only process valid polygon geometries
@@ -281,7 +1194,13 @@ func parseGeometriesParallel(features []Feature) ([]GeomFeature, error) {
 			// Check for empty or null geometries
 			if geom.IsEmpty() {
 				geom.Destroy()
-				return ParsingResult{Error: fmt.Errorf("skipping empty geometry at feature %d", parsingJob.Index)}
+				return ParsingResult{Index: parsingJob.Index, Error: fmt.Errorf("skipping empty geometry at feature %d", parsingJob.Index)}
+			}
+
+			// NaN/Inf coordinates from a bad source cannot be indexed or snapped
+			if err := utils.CheckFiniteBounds(geom); err != nil {
+				geom.Destroy()
+				return ParsingResult{Index: parsingJob.Index, Error: fmt.Errorf("skipping feature %d: %v", parsingJob.Index, err)}
 			}
 			
 			geomFeature := GeomFeature{
@@ -295,46 +1214,88 @@ func parseGeometriesParallel(features []Feature) ([]GeomFeature, error) {
 				Error:       nil,
 			}
 		} else {
+			geometryType := geom.Type()
 			geom.Destroy()
-			return ParsingResult{Error: fmt.Errorf("skipping non-polygon geometry at feature %d (type: %d)", parsingJob.Index, geom.TypeID())}
+			if options.PassThroughNonPolygons {
+				feature := parsingJob.Feature
+				return ParsingResult{Index: parsingJob.Index, PassThrough: &feature}
+			}
+			return ParsingResult{Index: parsingJob.Index, Error: fmt.Errorf("skipping non-polygon geometry at feature %d (type: %s)", parsingJob.Index, geometryType)}
 		}
 	}
 	
 	// Process jobs in parallel
 	results, err := processor.ProcessBatch(jobs, parseGeometry, "Parsing geometries")
 	if err != nil {
-		return nil, err
+		return nil, nil, 0, err
+	}
+	
+	// Workers finish in arbitrary order; restore input order so every later
+	// phase (and the output) sees stable indices
+	parsingResults := make([]ParsingResult, 0, len(results))
+	for _, result := range results {
+		if result != nil {
+			parsingResults = append(parsingResults, result.(ParsingResult))
+		}
 	}
+	sort.SliceStable(parsingResults, func(i, j int) bool {
+		return parsingResults[i].Index < parsingResults[j].Index
+	})
 	
 	// Collect valid results
 	validGeomFeatures := make([]GeomFeature, 0)
+	featureIndices := make([]int, 0)
+	passThroughFeatures := make([]Feature, 0)
 	invalidCount := 0
-	
-	for _, result := range results {
-		if result != nil {
-			parsingResult := result.(ParsingResult)
-			if parsingResult.Error != nil {
-				invalidCount++
-				log.Printf("Parsing error: %v", parsingResult.Error)
-			} else {
-				validGeomFeatures = append(validGeomFeatures, parsingResult.GeomFeature)
+	unfixedCount := 0
+	
+	for _, parsingResult := range parsingResults {
+		if parsingResult.PassThrough != nil {
+			passThroughFeatures = append(passThroughFeatures, *parsingResult.PassThrough)
+		} else if parsingResult.Error != nil {
+			invalidCount++
+			log.Printf("Parsing error: %v", parsingResult.Error)
+			if options.Lenient {
+				feature := features[parsingResult.Index]
+				feature.Properties = UnfixedProperties(feature.Properties, parsingResult.Error.Error())
+				passThroughFeatures = append(passThroughFeatures, feature)
+				unfixedCount++
 			}
+		} else {
+			validGeomFeatures = append(validGeomFeatures, parsingResult.GeomFeature)
+			featureIndices = append(featureIndices, parsingResult.Index)
 		}
 	}
 	
+	// Later phases index the compacted slice; profile them against the feature's position
+	profiler.SetIndexMap(featureIndices)
+	
 	if invalidCount > 0 {
 		fmt.Printf("Skipped %d invalid geometries during parsing\n", invalidCount)
 	}
+	if len(passThroughFeatures) > 0 {
+		fmt.Printf("Passing through %d features without cleaning\n", len(passThroughFeatures))
+	}
 	
-	return validGeomFeatures, nil
+	return validGeomFeatures, passThroughFeatures, unfixedCount, nil
 }
 
-// snapBoundariesParallel performs boundary snapping in parallel using worker pool
-func snapBoundariesParallel(geomFeatures []GeomFeature, spatialIndex *utils.SpatialIndex, tolerance float64) ([]GeomFeature, error) {
+// UnfixedProperties copies properties and flags the feature as passed through
+// unchanged, with the reason it could not be cleaned
+func UnfixedProperties(properties map[string]interface{}, reason string) map[string]interface{} {
+	flagged := copyProperties(properties)
+	flagged["_unfixed"] = true
+	flagged["_unfixed_reason"] = reason
+	return flagged
+}
+
+// snapBoundariesParallel snaps each geometry to its neighbours in parallel. It
+// also reports, per geometry, whether snapping changed it.
+func snapBoundariesParallel(geomFeatures []GeomFeature, spatialIndex *utils.SpatialIndex, tolerance float64, searchFactor float64, profiler *utils.FeatureProfiler) ([]GeomFeature, []bool, error) {
 	fmt.Printf("Starting parallel boundary snapping with tolerance: %e\n", tolerance)
 	
 	if len(geomFeatures) == 0 {
-		return []GeomFeature{}, nil
+		return []GeomFeature{}, []bool{}, nil
 	}
 
 	// Create parallel processor
@@ -348,12 +1309,14 @@ func snapBoundariesParallel(geomFeatures []GeomFeature, spatialIndex *utils.Spat
 			Index:        i,
 			SpatialIndex: spatialIndex,
 			Tolerance:    tolerance,
+			SearchFactor: searchFactor,
 		}
 	}
 	
 	// Define snapping work function
 	snapGeometry := func(job interface{}) interface{} {
 		snappingJob := job.(SnappingJob)
+		defer profiler.Track("snap", snappingJob.Index)()
 		
 		if snappingJob.GeomFeature.Geom == nil {
 			return SnappingResult{
@@ -367,7 +1330,7 @@ func snapBoundariesParallel(geomFeatures []GeomFeature, spatialIndex *utils.Spat
 		// Find neighboring geometries
 		neighbors := snappingJob.SpatialIndex.FindNeighbors(
 			snappingJob.GeomFeature.Geom, 
-			snappingJob.Tolerance*5, // Use larger search radius
+			snappingJob.Tolerance*snappingJob.SearchFactor, // Use larger search radius
 		)
 		
 		if len(neighbors) == 0 {
@@ -416,11 +1379,12 @@ func snapBoundariesParallel(geomFeatures []GeomFeature, spatialIndex *utils.Spat
 	// Process jobs in parallel
 	results, err := processor.ProcessBatch(jobs, snapGeometry, "Snapping boundaries")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	
 	// Collect results in order
 	resultGeometries := make([]GeomFeature, len(geomFeatures))
+	snapped := make([]bool, len(geomFeatures))
 	snappedCount := 0
 	
 	for _, result := range results {
@@ -433,6 +1397,7 @@ func snapBoundariesParallel(geomFeatures []GeomFeature, spatialIndex *utils.Spat
 			} else {
 				resultGeometries[snappingResult.Index] = snappingResult.GeomFeature
 				if snappingResult.WasSnapped {
+					snapped[snappingResult.Index] = true
 					snappedCount++
 				}
 			}
@@ -440,15 +1405,225 @@ func snapBoundariesParallel(geomFeatures []GeomFeature, spatialIndex *utils.Spat
 	}
 	
 	fmt.Printf("Parallel boundary snapping complete. Snapped %d of %d geometries\n", snappedCount, len(geomFeatures))
-	return resultGeometries, nil
+	return resultGeometries, snapped, nil
+}
+
+// buildSpatialIndex indexes every geometry of geomFeatures under its position
+func buildSpatialIndex(geomFeatures []GeomFeature, cellSize float64, quadSegs int) *utils.SpatialIndex {
+	spatialIndex := utils.NewSpatialIndex(cellSize)
+	spatialIndex.SetQuadSegs(quadSegs)
+	for i, geomFeature := range geomFeatures {
+		if err := spatialIndex.AddGeometry(geomFeature.Geom, i, geomFeature.Properties); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}
+	return spatialIndex
+}
+
+// snapBoundariesInPasses runs up to passes rounds of snapBoundariesParallel,
+// the first against spatialIndex and each later one against a fresh index of
+// the previous round's output. It stops early once a round leaves every
+// geometry unchanged, and returns the snapped geometries, which features were
+// snapped in any round, and how many rounds changed something.
+func snapBoundariesInPasses(geomFeatures []GeomFeature, spatialIndex *utils.SpatialIndex, cellSize float64, tolerance float64, searchFactor float64, passes int, quadSegs int, profiler *utils.FeatureProfiler) ([]GeomFeature, []bool, int, error) {
+	current := geomFeatures
+	snapped := make([]bool, len(geomFeatures))
+	changedPasses := 0
+
+	for pass := 1; pass <= max(passes, 1); pass++ {
+		if pass > 1 {
+			spatialIndex = buildSpatialIndex(current, cellSize, quadSegs)
+		}
+
+		result, passSnapped, err := snapBoundariesParallel(current, spatialIndex, tolerance, searchFactor, profiler)
+		if err != nil {
+			return nil, nil, changedPasses, err
+		}
+
+		changed := 0
+		for i := range result {
+			if passSnapped[i] {
+				snapped[i] = true
+			}
+			if result[i].Geom != current[i].Geom {
+				if !result[i].Geom.EqualsExact(current[i].Geom, 0) {
+					changed++
+				}
+				// Intermediate rounds' geometries are no longer referenced; the
+				// caller's input is left to the caller
+				if current[i].Geom != geomFeatures[i].Geom {
+					current[i].Geom.Destroy()
+				}
+			}
+		}
+		current = result
+
+		log.Printf("Snapping pass %d changed %d geometries", pass, changed)
+		if changed == 0 {
+			break
+		}
+		changedPasses++
+	}
+
+	return current, snapped, changedPasses, nil
+}
+
+// rollBackOverlappingSnaps restores the pre-snap geometry of snapped features
+// whose snapping created or enlarged an overlap. Each feature is snapped against
+// its neighbours independently, which can push it into a third geometry, so
+// every post-snap overlap involving a snapped feature is compared with the same
+// pair's overlap before snapping. Overlap growth up to toleranceMeters² is
+// treated as noise. validated is updated in place; the number of features
+// rolled back is returned.
+func rollBackOverlappingSnaps(validated, originals []GeomFeature, snapped []bool, report CoverageReport, toleranceMeters float64, precision int, repairMethod string) int {
+	// Pre-snap geometries are repaired on demand so the comparison uses valid input
+	repaired := make(map[int]*geos.Geom)
+	defer func() {
+		for _, geom := range repaired {
+			if geom != nil {
+				geom.Destroy()
+			}
+		}
+	}()
+	repairedOriginal := func(i int) *geos.Geom {
+		if geom, ok := repaired[i]; ok {
+			return geom
+		}
+		var geom *geos.Geom
+		if originals[i].Geom != nil {
+			result, err := validateAndRepairGeometries([]GeomFeature{{Geom: originals[i].Geom.Clone()}}, precision, repairMethod)
+			if err == nil && len(result) == 1 {
+				geom = result[0].Geom
+			}
+		}
+		repaired[i] = geom
+		return geom
+	}
+
+	rollBack := make(map[int]bool)
+	for _, pair := range report.OverlapPairs {
+		if !snapped[pair.A] && !snapped[pair.B] {
+			continue
+		}
+
+		beforeM2 := 0.0
+		geomA, geomB := repairedOriginal(pair.A), repairedOriginal(pair.B)
+		if geomA == nil || geomB == nil {
+			continue
+		}
+		if geomA.Overlaps(geomB) {
+			intersection := geomA.Intersection(geomB)
+			if intersection != nil {
+				beforeM2 = utils.GeodesicArea(intersection)
+				intersection.Destroy()
+			}
+		}
+		if pair.AreaM2 <= beforeM2+toleranceMeters*toleranceMeters {
+			continue
+		}
+
+		log.Printf("Snapping increased overlap between features %d and %d (%.3f m² -> %.3f m²)", pair.A, pair.B, beforeM2, pair.AreaM2)
+		for _, index := range []int{pair.A, pair.B} {
+			if snapped[index] {
+				rollBack[index] = true
+			}
+		}
+	}
+
+	for index := range rollBack {
+		// Hand ownership of the repaired original over to the result
+		if validated[index].Geom != nil {
+			validated[index].Geom.Destroy()
+		}
+		validated[index].Geom = repaired[index]
+		delete(repaired, index)
+	}
+
+	return len(rollBack)
+}
+
+// repairStrategy is one way of making an invalid geometry valid
+type repairStrategy struct {
+	name   string
+	repair func(*geos.Geom) *geos.Geom
+}
+
+var (
+	makeValidLinework = repairStrategy{"makeValid(linework)", func(geom *geos.Geom) *geos.Geom {
+		return geom.MakeValidWithParams(geos.MakeValidLinework, geos.MakeValidDiscardCollapsed)
+	}}
+	makeValidStructure = repairStrategy{"makeValid(structure)", func(geom *geos.Geom) *geos.Geom {
+		return geom.MakeValidWithParams(geos.MakeValidStructure, geos.MakeValidDiscardCollapsed)
+	}}
+	bufferZero = repairStrategy{"buffer(0)", func(geom *geos.Geom) *geos.Geom {
+		return geom.Buffer(0, utils.DefaultQuadSegs)
+	}}
+)
+
+// repairStrategiesFor returns the strategies to try, in order, for a repair
+// method. GEOS occasionally returns nil for MakeValid (e.g. under memory
+// pressure), and the structure method or a zero-width buffer often succeed
+// where linework fails, so the other strategies remain as fallbacks.
+func repairStrategiesFor(repairMethod string) []repairStrategy {
+	if repairMethod == RepairMethodBuffer0 {
+		return []repairStrategy{bufferZero, makeValidLinework, makeValidStructure}
+	}
+	return []repairStrategy{makeValidLinework, makeValidStructure, bufferZero}
+}
+
+// RepairGeometry makes geom valid using the first strategy for repairMethod
+// whose result is valid, returning it with the strategy's name, or nil and ""
+// if all of them fail. MakeValid can return a geometry that is still invalid,
+// so every result is checked before it is accepted. geom itself is left
+// untouched; index only identifies the geometry in logs.
+func RepairGeometry(geom *geos.Geom, index int, repairMethod string) (*geos.Geom, string) {
+	return repairWithStrategies(geom, index, repairStrategiesFor(repairMethod))
+}
+
+// repairWithStrategies tries strategies in order as described for RepairGeometry
+func repairWithStrategies(geom *geos.Geom, index int, strategies []repairStrategy) (*geos.Geom, string) {
+	for attempt, strategy := range strategies {
+		repaired := tryRepair(strategy, geom, index)
+		if repaired == nil {
+			continue
+		}
+		if !repaired.IsValid() {
+			log.Printf("Repair %s left geometry at index %d invalid: %s", strategy.name, index, repaired.IsValidReason())
+			repaired.Destroy()
+			continue
+		}
+		if attempt > 0 {
+			log.Printf("Repaired geometry at index %d with fallback %s", index, strategy.name)
+		}
+		return repaired, strategy.name
+	}
+
+	log.Printf("All repair strategies failed for geometry at index %d", index)
+	return nil, ""
+}
+
+// tryRepair runs a single repair strategy, treating a GEOS panic as failure
+func tryRepair(strategy repairStrategy, geom *geos.Geom, index int) (repaired *geos.Geom) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Repair %s panicked for geometry at index %d: %v", strategy.name, index, r)
+			repaired = nil
+		}
+	}()
+
+	repaired = strategy.repair(geom)
+	if repaired == nil {
+		log.Printf("Repair %s returned nil for geometry at index %d", strategy.name, index)
+	}
+	return repaired
 }
 
 // validateAndRepairGeometriesParallel validates and repairs geometries in parallel
-func validateAndRepairGeometriesParallel(geomFeatures []GeomFeature) ([]GeomFeature, error) {
+func validateAndRepairGeometriesParallel(geomFeatures []GeomFeature, precision int, repairMethod string, profiler *utils.FeatureProfiler) ([]GeomFeature, map[int]string, map[string]int, error) {
 	fmt.Printf("Starting parallel geometry validation and repair\n")
 	
 	if len(geomFeatures) == 0 {
-		return []GeomFeature{}, nil
+		return []GeomFeature{}, map[int]string{}, map[string]int{}, nil
 	}
 
 	// Create parallel processor
@@ -466,6 +1641,7 @@ func validateAndRepairGeometriesParallel(geomFeatures []GeomFeature) ([]GeomFeat
 	// Define validation work function
 	validateGeometry := func(job interface{}) interface{} {
 		validationJob := job.(ValidationJob)
+		defer profiler.Track("repair", validationJob.Index)()
 		
 		if validationJob.GeomFeature.Geom == nil {
 			return ValidationResult{
@@ -477,33 +1653,48 @@ func validateAndRepairGeometriesParallel(geomFeatures []GeomFeature) ([]GeomFeat
 		}
 		
 		geom := validationJob.GeomFeature.Geom
+		properties := validationJob.GeomFeature.Properties
 		wasRepaired := false
+		usedMethod := ""
+		var repairErr error
 		
 		// Check if geometry is valid
 		if !geom.IsValid() {
-			fmt.Printf("Repairing invalid geometry at index %d: %s\n", validationJob.Index, geom.IsValidReason())
+			reason := geom.IsValidReason()
+			fmt.Printf("Repairing invalid geometry at index %d: %s\n", validationJob.Index, reason)
 			
 			// Make geometry valid
-			repairedGeom := geom.MakeValidWithParams(geos.MakeValidLinework, geos.MakeValidDiscardCollapsed)
+			repairedGeom, method := RepairGeometry(geom, validationJob.Index, repairMethod)
 			if repairedGeom != nil {
 				geom.Destroy()
 				geom = repairedGeom
 				wasRepaired = true
+				usedMethod = method
+
+				// Point reviewers at where the geometry was invalid
+				if location, ok := utils.InvalidityLocation(reason); ok {
+					properties = copyProperties(properties)
+					properties[RepairLocationProperty] = location
+				}
+			} else {
+				repairErr = fmt.Errorf("could not repair invalid geometry: %s", reason)
 			}
 		}
 		
 		// Apply coordinate truncation for precision consistency
-		truncatedGeom, err := utils.TruncateFullGeometry(geom)
+		truncatedGeom, err := utils.TruncateFullGeometry(geom, precision)
 		if err != nil {
 			log.Printf("Error truncating geometry at index %d: %v", validationJob.Index, err)
 			return ValidationResult{
 				GeomFeature: GeomFeature{
 					Geom:       geom,
-					Properties: validationJob.GeomFeature.Properties,
+					Properties: properties,
 				},
-				Index:       validationJob.Index,
-				WasRepaired: wasRepaired,
-				Error:       err,
+				Index:        validationJob.Index,
+				WasRepaired:  wasRepaired,
+				RepairMethod: usedMethod,
+				Unfixed:      true,
+				Error:        err,
 			}
 		}
 		
@@ -514,22 +1705,26 @@ func validateAndRepairGeometriesParallel(geomFeatures []GeomFeature) ([]GeomFeat
 		return ValidationResult{
 			GeomFeature: GeomFeature{
 				Geom:       truncatedGeom,
-				Properties: validationJob.GeomFeature.Properties,
+				Properties: properties,
 			},
-			Index:       validationJob.Index,
-			WasRepaired: wasRepaired,
-			Error:       nil,
+			Index:        validationJob.Index,
+			WasRepaired:  wasRepaired,
+			RepairMethod: usedMethod,
+			Unfixed:      repairErr != nil,
+			Error:        repairErr,
 		}
 	}
 	
 	// Process jobs in parallel
 	results, err := processor.ProcessBatch(jobs, validateGeometry, "Validating geometries")
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	
-	// Collect results in order
+	// Collect results in order, noting why each unfixed geometry failed
 	resultGeometries := make([]GeomFeature, len(geomFeatures))
+	unfixed := make(map[int]string)
+	repairMethods := make(map[string]int)
 	repairedCount := 0
 	errorCount := 0
 	
@@ -540,16 +1735,20 @@ func validateAndRepairGeometriesParallel(geomFeatures []GeomFeature) ([]GeomFeat
 			
 			if validationResult.WasRepaired {
 				repairedCount++
+				repairMethods[validationResult.RepairMethod]++
 			}
 			if validationResult.Error != nil {
 				errorCount++
 				log.Printf("Validation error for geometry %d: %v", validationResult.Index, validationResult.Error)
 			}
+			if validationResult.Unfixed {
+				unfixed[validationResult.Index] = validationResult.Error.Error()
+			}
 		}
 	}
 	
 	fmt.Printf("Parallel geometry validation complete. Repaired %d geometries, %d errors\n", repairedCount, errorCount)
-	return resultGeometries, nil
+	return resultGeometries, unfixed, repairMethods, nil
 }
 
 // calculateGeometryDistortion measures how much a geometry has been distorted
@@ -611,6 +1810,28 @@ type BoundaryPreservationReport struct {
 	GeometriesRejected   int
 }
 
+// keepsOriginals reports whether the cleaning pipeline needs copies of the
+// input geometries. Boundary preservation validation and snap rollback compare
+// against them, lenient mode falls back to them and IncludeOriginal returns
+// them; without the first, rollback is skipped unless another needs the copies.
+func keepsOriginals(options CleanTopologyOptions) bool {
+	return options.ValidatePreservation || options.Lenient || options.IncludeOriginal
+}
+
+// cloneGeomFeatures returns a copy of geomFeatures with cloned geometries
+func cloneGeomFeatures(geomFeatures []GeomFeature) []GeomFeature {
+	clones := make([]GeomFeature, len(geomFeatures))
+	for i, geomFeature := range geomFeatures {
+		if geomFeature.Geom != nil {
+			clones[i] = GeomFeature{
+				Geom:       geomFeature.Geom.Clone(),
+				Properties: geomFeature.Properties,
+			}
+		}
+	}
+	return clones
+}
+
 // validateBoundaryPreservation checks how well original boundaries are preserved
 func validateBoundaryPreservation(originalGeoms, cleanedGeoms []GeomFeature, tolerance float64) BoundaryPreservationReport {
 	report := BoundaryPreservationReport{
@@ -654,8 +1875,15 @@ func validateBoundaryPreservation(originalGeoms, cleanedGeoms []GeomFeature, tol
 	return report
 }
 
-// fillGapBetweenGeometries creates connecting geometry to fill gaps between adjacent polygons
-func fillGapBetweenGeometries(geomI, geomJ *geos.Geom, tolerance float64) (*geos.Geom, error) {
+// DefaultGapFillAreaFactor sets the largest gap fillGapBetweenGeometries fills
+// when no maximum area is given: this many times the square of the tolerance
+// in meters, 1.6 m² at the default 40cm tolerance
+const DefaultGapFillAreaFactor = 10.0
+
+// fillGapBetweenGeometries creates connecting geometry to fill gaps between
+// adjacent polygons. Gaps with a geodesic area above maxAreaM2 are rejected
+// rather than filled; zero or negative uses DefaultGapFillAreaFactor.
+func fillGapBetweenGeometries(geomI, geomJ *geos.Geom, tolerance float64, maxAreaM2 float64, quadSegs int) (*geos.Geom, error) {
 	if geomI == nil || geomJ == nil {
 		return nil, fmt.Errorf("cannot fill gap between nil geometries")
 	}
@@ -684,8 +1912,8 @@ func fillGapBetweenGeometries(geomI, geomJ *geos.Geom, tolerance float64) (*geos
 	}
 	
 	// Create buffer around boundaries to find connection area
-	bufferI := boundaryI.Buffer(distance/2, 4) // Small buffer
-	bufferJ := boundaryJ.Buffer(distance/2, 4)
+	bufferI := boundaryI.Buffer(distance/2, quadSegs) // Small buffer
+	bufferJ := boundaryJ.Buffer(distance/2, quadSegs)
 	
 	if bufferI == nil || bufferJ == nil {
 		if bufferI != nil {
@@ -707,16 +1935,27 @@ func fillGapBetweenGeometries(geomI, geomJ *geos.Geom, tolerance float64) (*geos
 	}
 	
 	// Only return small gap areas (prevent excessive modification)
-	if gapArea.Area() > tolerance*tolerance*10 {
+	maxAreaM2 = gapFillMaxAreaM2(tolerance, maxAreaM2)
+	if areaM2 := utils.GeodesicArea(gapArea); areaM2 > maxAreaM2 {
 		gapArea.Destroy()
-		return nil, fmt.Errorf("gap area too large: %f", gapArea.Area())
+		return nil, fmt.Errorf("gap area too large: %.3f m² exceeds the %.3f m² maximum", areaM2, maxAreaM2)
 	}
 	
 	return gapArea, nil
 }
 
+// gapFillMaxAreaM2 returns the largest gap area fillGapBetweenGeometries fills:
+// maxAreaM2, or the DefaultGapFillAreaFactor default when it is not positive
+func gapFillMaxAreaM2(tolerance float64, maxAreaM2 float64) float64 {
+	if maxAreaM2 > 0 {
+		return maxAreaM2
+	}
+	toleranceMeters := utils.CalculateMetersFromWGS84Degrees(tolerance)
+	return DefaultGapFillAreaFactor * toleranceMeters * toleranceMeters
+}
+
 // analyzeBoundaryGaps performs detailed boundary gap analysis between two geometries
-func analyzeBoundaryGaps(geomI, geomJ *geos.Geom, tolerance float64) (bool, float64, float64, int) {
+func analyzeBoundaryGaps(geomI, geomJ *geos.Geom, tolerance float64, searchFactor float64, quadSegs int) (bool, float64, float64, int, float64) {
 	// Get boundaries of both geometries
 	boundaryI := geomI.Boundary()
 	boundaryJ := geomJ.Boundary()
@@ -728,7 +1967,7 @@ func analyzeBoundaryGaps(geomI, geomJ *geos.Geom, tolerance float64) (bool, floa
 		if boundaryJ != nil {
 			boundaryJ.Destroy()
 		}
-		return false, 0.0, 0.0, 0
+		return false, 0.0, 0.0, 0, 0.0
 	}
 	
 	defer boundaryI.Destroy()
@@ -738,14 +1977,14 @@ func analyzeBoundaryGaps(geomI, geomJ *geos.Geom, tolerance float64) (bool, floa
 	distance := boundaryI.Distance(boundaryJ)
 	
 	// Only consider potential gaps if geometries are close but not touching
-	if distance <= tolerance || distance > tolerance*50 {
-		return false, distance, 0.0, 0
+	if distance <= tolerance || distance > tolerance*searchFactor {
+		return false, distance, 0.0, 0, 0.0
 	}
 	
 	// Check if boundaries are nearly parallel (indicating a potential gap)
 	// Use buffering to find areas where boundaries are close
-	bufferI := boundaryI.Buffer(tolerance*2, 8)
-	bufferJ := boundaryJ.Buffer(tolerance*2, 8)
+	bufferI := boundaryI.Buffer(tolerance*2, quadSegs)
+	bufferJ := boundaryJ.Buffer(tolerance*2, quadSegs)
 	
 	if bufferI == nil || bufferJ == nil {
 		if bufferI != nil {
@@ -754,7 +1993,7 @@ func analyzeBoundaryGaps(geomI, geomJ *geos.Geom, tolerance float64) (bool, floa
 		if bufferJ != nil {
 			bufferJ.Destroy()
 		}
-		return false, distance, 0.0, 0
+		return false, distance, 0.0, 0, 0.0
 	}
 	
 	defer bufferI.Destroy()
@@ -763,7 +2002,7 @@ func analyzeBoundaryGaps(geomI, geomJ *geos.Geom, tolerance float64) (bool, floa
 	// Find intersection of buffered boundaries
 	intersection := bufferI.Intersection(bufferJ)
 	if intersection == nil {
-		return false, distance, 0.0, 0
+		return false, distance, 0.0, 0, 0.0
 	}
 	defer intersection.Destroy()
 	
@@ -782,14 +2021,30 @@ func analyzeBoundaryGaps(geomI, geomJ *geos.Geom, tolerance float64) (bool, floa
 			}
 		}
 		
-		return true, distance, maxGapWidth, boundaryGaps
+		return true, distance, maxGapWidth, boundaryGaps, gapAreaM2(intersection, geomI, geomJ)
 	}
 	
-	return false, distance, 0.0, 0
+	return false, distance, 0.0, 0, 0.0
+}
+
+// gapAreaM2 returns the geodesic area of the part of the buffered boundary
+// intersection covered by neither geometry, i.e. the gap region between them
+func gapAreaM2(intersection, geomI, geomJ *geos.Geom) float64 {
+	outsideI := intersection.Difference(geomI)
+	if outsideI == nil {
+		return 0
+	}
+	defer outsideI.Destroy()
+	gap := outsideI.Difference(geomJ)
+	if gap == nil {
+		return 0
+	}
+	defer gap.Destroy()
+	return utils.GeodesicArea(gap)
 }
 
 // validateCoverageParallel performs coverage validation in parallel using worker pool
-func validateCoverageParallel(geomFeatures []GeomFeature, tolerance float64) CoverageReport {
+func validateCoverageParallel(geomFeatures []GeomFeature, tolerance float64, searchFactor float64, quadSegs int, profiler *utils.FeatureProfiler) CoverageReport {
 	log.Printf("=== Starting parallel coverage validation ===")
 	log.Printf("Number of geometries to validate: %d", len(geomFeatures))
 	log.Printf("Tolerance: %e degrees", tolerance)
@@ -827,7 +2082,9 @@ func validateCoverageParallel(geomFeatures []GeomFeature, tolerance float64) Cov
 				GeomJ:     geomFeatures[j].Geom,
 				IndexI:    i,
 				IndexJ:    j,
-				Tolerance: tolerance,
+				Tolerance:    tolerance,
+				SearchFactor: searchFactor,
+				QuadSegs:     quadSegs,
 			})
 		}
 	}
@@ -850,6 +2107,7 @@ func validateCoverageParallel(geomFeatures []GeomFeature, tolerance float64) Cov
 	// Define coverage validation work function
 	validatePair := func(job interface{}) interface{} {
 		coverageJob := job.(CoverageJob)
+		defer profiler.Track("coverage", coverageJob.IndexI, coverageJob.IndexJ)()
 		
 		result := CoverageResult{
 			IndexI:         coverageJob.IndexI,
@@ -872,6 +2130,17 @@ func validateCoverageParallel(geomFeatures []GeomFeature, tolerance float64) Cov
 		distance := coverageJob.GeomI.Distance(coverageJob.GeomJ)
 		result.GapDistance = distance
 		
+		// Check for nested geometries; GEOS does not report these as overlaps
+		if coverageJob.GeomI.Contains(coverageJob.GeomJ) {
+			result.HasContainment = true
+			result.ContainerIndex = coverageJob.IndexI
+			result.ContainedIndex = coverageJob.IndexJ
+		} else if coverageJob.GeomJ.Contains(coverageJob.GeomI) {
+			result.HasContainment = true
+			result.ContainerIndex = coverageJob.IndexJ
+			result.ContainedIndex = coverageJob.IndexI
+		}
+		
 		// Check for overlap first
 		if coverageJob.GeomI.Overlaps(coverageJob.GeomJ) {
 			intersection := coverageJob.GeomI.Intersection(coverageJob.GeomJ)
@@ -880,20 +2149,23 @@ func validateCoverageParallel(geomFeatures []GeomFeature, tolerance float64) Cov
 				if area > coverageJob.Tolerance*coverageJob.Tolerance {
 					result.HasOverlap = true
 					result.OverlapArea = area
+					result.OverlapAreaM2 = utils.GeodesicArea(intersection)
 				}
 				intersection.Destroy()
 			}
 		}
 		
 		// Perform detailed boundary gap analysis for nearby geometries
-		if distance <= coverageJob.Tolerance*50 { // Only analyze reasonably close geometries
-			hasGap, gapDistance, maxGapWidth, boundaryGaps := analyzeBoundaryGaps(
-				coverageJob.GeomI, coverageJob.GeomJ, coverageJob.Tolerance)
+		if distance <= coverageJob.Tolerance*coverageJob.SearchFactor { // Only analyze reasonably close geometries
+			hasGap, gapDistance, maxGapWidth, boundaryGaps, gapArea := analyzeBoundaryGaps(
+				coverageJob.GeomI, coverageJob.GeomJ, coverageJob.Tolerance, coverageJob.SearchFactor, coverageJob.QuadSegs)
 			
 			if hasGap {
 				result.HasGap = true
 				result.GapDistance = gapDistance
 				result.MaxGapWidth = maxGapWidth
+				result.GapDistanceM = utils.GeodesicDistance(coverageJob.GeomI, coverageJob.GeomJ)
+				result.GapAreaM2 = gapArea
 				result.BoundaryGaps = boundaryGaps
 			}
 		}
@@ -927,6 +2199,17 @@ func validateCoverageParallel(geomFeatures []GeomFeature, tolerance float64) Cov
 		BoundaryGaps:    0,
 	}
 	
+	// Workers finish in arbitrary order; aggregate in pair order so the pair
+	// lists, the summed measurements and anything acting on them (snap
+	// rollback, sliver clipping) are the same on every run
+	sort.Slice(results, func(i, j int) bool {
+		a, b := results[i].(CoverageResult), results[j].(CoverageResult)
+		if a.IndexI != b.IndexI {
+			return a.IndexI < b.IndexI
+		}
+		return a.IndexJ < b.IndexJ
+	})
+
 	for _, result := range results {
 		if result != nil {
 			coverageResult := result.(CoverageResult)
@@ -939,31 +2222,62 @@ func validateCoverageParallel(geomFeatures []GeomFeature, tolerance float64) Cov
 			if coverageResult.HasOverlap {
 				report.OverlapCount++
 				report.OverlapArea += coverageResult.OverlapArea
+				report.OverlapAreaM2 += coverageResult.OverlapAreaM2
+				report.OverlapPairs = append(report.OverlapPairs, OverlapPair{
+					A:      coverageResult.IndexI,
+					B:      coverageResult.IndexJ,
+					AreaM2: coverageResult.OverlapAreaM2,
+				})
+				
+				log.Printf("*** OVERLAP DETECTED *** between features %d and %d (area: %.3f m²)",
+					coverageResult.IndexI, coverageResult.IndexJ, coverageResult.OverlapAreaM2)
+			}
+			
+			if coverageResult.HasContainment {
+				report.ContainmentCount++
+				report.ContainmentPairs = append(report.ContainmentPairs, ContainmentPair{
+					Container: coverageResult.ContainerIndex,
+					Contained: coverageResult.ContainedIndex,
+				})
 				
-				log.Printf("*** OVERLAP DETECTED *** between features %d and %d (area: %f)", 
-					coverageResult.IndexI, coverageResult.IndexJ, coverageResult.OverlapArea)
+				log.Printf("*** CONTAINMENT DETECTED *** feature %d lies inside feature %d", 
+					coverageResult.ContainedIndex, coverageResult.ContainerIndex)
 			}
 			
 			if coverageResult.HasGap {
 				report.GapCount++
 				report.BoundaryGaps += coverageResult.BoundaryGaps
 				report.TotalGapLength += coverageResult.GapDistance
+				report.TotalGapLengthM += coverageResult.GapDistanceM
+				report.GapAreaM2 += coverageResult.GapAreaM2
+				report.GapPairs = append(report.GapPairs, GapPair{
+					A:         coverageResult.IndexI,
+					B:         coverageResult.IndexJ,
+					Distance:  coverageResult.GapDistance,
+					MaxWidth:  coverageResult.MaxGapWidth,
+					DistanceM: coverageResult.GapDistanceM,
+					// The widest point of a gap is measured as its distance
+					MaxWidthM: coverageResult.GapDistanceM,
+					AreaM2:    coverageResult.GapAreaM2,
+					Segments:  coverageResult.BoundaryGaps,
+				})
 				
 				if coverageResult.MaxGapWidth > report.MaxGapWidth {
 					report.MaxGapWidth = coverageResult.MaxGapWidth
 				}
+				report.MaxGapWidthM = max(report.MaxGapWidthM, coverageResult.GapDistanceM)
 				
-				log.Printf("*** GAP DETECTED *** between features %d and %d (distance: %f, width: %f, segments: %d)", 
-					coverageResult.IndexI, coverageResult.IndexJ, 
-					coverageResult.GapDistance, coverageResult.MaxGapWidth, coverageResult.BoundaryGaps)
+				log.Printf("*** GAP DETECTED *** between features %d and %d (distance: %.3f m, area: %.3f m², segments: %d)",
+					coverageResult.IndexI, coverageResult.IndexJ,
+					coverageResult.GapDistanceM, coverageResult.GapAreaM2, coverageResult.BoundaryGaps)
 			}
 		}
 	}
 	
 	log.Printf("=== Parallel coverage validation complete ===")
-	log.Printf("Results: %d gaps (%d boundary segments, max width: %f, total length: %f), %d overlaps (total area: %f)", 
-		report.GapCount, report.BoundaryGaps, report.MaxGapWidth, report.TotalGapLength, 
-		report.OverlapCount, report.OverlapArea)
+	log.Printf("Results: %d gaps (%d boundary segments, max width: %.3f m, total length: %.3f m, area: %.3f m²), %d overlaps (total area: %.3f m²), %d containments",
+		report.GapCount, report.BoundaryGaps, report.MaxGapWidthM, report.TotalGapLengthM, report.GapAreaM2,
+		report.OverlapCount, report.OverlapAreaM2, report.ContainmentCount)
 	return report
 }
 
@@ -1027,7 +2341,7 @@ func snapBoundaries(geomFeatures []GeomFeature, spatialIndex *utils.SpatialIndex
 	return result, nil
 }
 
-func validateAndRepairGeometries(geomFeatures []GeomFeature) ([]GeomFeature, error) {
+func validateAndRepairGeometries(geomFeatures []GeomFeature, precision int, repairMethod string) ([]GeomFeature, error) {
 	fmt.Printf("Starting geometry validation and repair\n")
 	
 	result := make([]GeomFeature, len(geomFeatures))
@@ -1044,7 +2358,7 @@ func validateAndRepairGeometries(geomFeatures []GeomFeature) ([]GeomFeature, err
 			fmt.Printf("Repairing invalid geometry at index %d: %s\n", i, geom.IsValidReason())
 			
 			// Make geometry valid
-			repairedGeom := geom.MakeValidWithParams(geos.MakeValidLinework, geos.MakeValidDiscardCollapsed)
+			repairedGeom, _ := RepairGeometry(geom, i, repairMethod)
 			if repairedGeom != nil {
 				geom.Destroy()
 				geom = repairedGeom
@@ -1052,7 +2366,7 @@ func validateAndRepairGeometries(geomFeatures []GeomFeature) ([]GeomFeature, err
 		}
 		
 		// Apply coordinate truncation for precision consistency
-		truncatedGeom, err := utils.TruncateFullGeometry(geom)
+		truncatedGeom, err := utils.TruncateFullGeometry(geom, precision)
 		if err != nil {
 			log.Printf("Error truncating geometry at index %d: %v", i, err)
 			result[i] = GeomFeature{
@@ -1074,14 +2388,53 @@ func validateAndRepairGeometries(geomFeatures []GeomFeature) ([]GeomFeature, err
 	return result, nil
 }
 
+// CoverageReport summarises the gaps, overlaps and containments between pairs
+// of geometries. The degree-based fields are for internal comparisons against
+// tolerances; anything shown to users (logs, report.json, API responses) uses
+// the geodesic meter and square meter fields.
 type CoverageReport struct {
-	GapCount        int
-	OverlapCount    int
-	GapArea         float64
-	OverlapArea     float64
-	TotalGapLength  float64  // Total length of boundary gaps
-	MaxGapWidth     float64  // Maximum gap width detected
-	BoundaryGaps    int      // Total number of boundary gap segments
+	GapCount         int
+	OverlapCount     int
+	GapArea          float64
+	OverlapArea      float64
+	GapAreaM2        float64           // Geodesic area of the gap regions
+	OverlapAreaM2    float64           // Geodesic area of the overlaps
+	TotalGapLength   float64           // Total length of boundary gaps
+	MaxGapWidth      float64           // Maximum gap width detected
+	TotalGapLengthM  float64           // TotalGapLength in geodesic meters
+	MaxGapWidthM     float64           // MaxGapWidth in geodesic meters
+	BoundaryGaps     int               // Total number of boundary gap segments
+	ContainmentCount int               // Number of geometries nested entirely inside another
+	ContainmentPairs []ContainmentPair // Feature indices of each nested pair
+	OverlapPairs     []OverlapPair     // Feature indices and area of each overlapping pair
+	GapPairs         []GapPair         // Feature indices and extent of each pair with a boundary gap
+}
+
+// GapPair identifies two geometries with a boundary gap between them.
+// Distance and MaxWidth are in degrees, as measured by GEOS; DistanceM,
+// MaxWidthM and AreaM2 are their geodesic equivalents.
+type GapPair struct {
+	A         int
+	B         int
+	Distance  float64
+	MaxWidth  float64
+	DistanceM float64
+	MaxWidthM float64
+	AreaM2    float64
+	Segments  int
+}
+
+// OverlapPair identifies two geometries that overlap and by how much
+type OverlapPair struct {
+	A      int     `json:"a"`
+	B      int     `json:"b"`
+	AreaM2 float64 `json:"areaM2"`
+}
+
+// ContainmentPair identifies a geometry that lies entirely inside another
+type ContainmentPair struct {
+	Container int
+	Contained int
 }
 
 func validateCoverage(geomFeatures []GeomFeature, tolerance float64) CoverageReport {
@@ -1118,10 +2471,11 @@ func validateCoverage(geomFeatures []GeomFeature, tolerance float64) CoverageRep
 					if intersection != nil && intersection.Area() > tolerance*tolerance {
 						report.OverlapCount++
 						report.OverlapArea += intersection.Area()
+						report.OverlapAreaM2 += utils.GeodesicArea(intersection)
 						
 						// Log overlap details
-						fmt.Printf("Overlap detected between features %d and %d (area: %f)\n", 
-							i, j, intersection.Area())
+						fmt.Printf("Overlap detected between features %d and %d (area: %.3f m²)\n",
+							i, j, utils.GeodesicArea(intersection))
 					}
 					if intersection != nil {
 						intersection.Destroy()
@@ -0,0 +1,1241 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+// Two unit squares sharing the edge x=1, a clean coverage
+const (
+	westSquare = `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`
+	eastSquare = `{"type":"Polygon","coordinates":[[[1,0],[2,0],[2,1],[1,1],[1,0]]]}`
+)
+
+// featureCollection returns a FeatureCollection payload of the given GeoJSON
+// geometries, each feature carrying its position in an id property
+func featureCollection(geometries ...string) string {
+	features := make([]string, len(geometries))
+	for i, geometry := range geometries {
+		features[i] = fmt.Sprintf(`{"type":"Feature","geometry":%s,"properties":{"id":%d}}`, geometry, i)
+	}
+	return `{"type":"FeatureCollection","features":[` + strings.Join(features, ",") + `]}`
+}
+
+// cleanTopology runs CleanTopology on payload with the default options as
+// changed by configure, failing the test on error
+func cleanTopology(t testing.TB, payload string, configure func(*CleanTopologyOptions)) *TopologyCleaningResult {
+	t.Helper()
+	options := DefaultCleanTopologyOptions()
+	if configure != nil {
+		configure(&options)
+	}
+	result, err := CleanTopology(payload, options)
+	if err != nil {
+		t.Fatalf("CleanTopology: %v", err)
+	}
+	if result == nil {
+		t.Fatal("CleanTopology returned no result")
+	}
+	return result
+}
+
+// featureByID returns the output feature whose id property is id
+func featureByID(t testing.TB, features []Feature, id int) Feature {
+	t.Helper()
+	for _, feature := range features {
+		if value, ok := feature.Properties["id"].(float64); ok && int(value) == id {
+			return feature
+		}
+	}
+	t.Fatalf("no output feature with id %d", id)
+	return Feature{}
+}
+
+// firstPositionSize returns the number of ordinates in the first position of a
+// LineString geometry
+func firstPositionSize(t testing.TB, geometry json.RawMessage) int {
+	t.Helper()
+	var line struct {
+		Coordinates [][]float64 `json:"coordinates"`
+	}
+	if err := json.Unmarshal(geometry, &line); err != nil || len(line.Coordinates) == 0 {
+		t.Fatalf("not a LineString geometry: %s", geometry)
+	}
+	return len(line.Coordinates[0])
+}
+
+func TestCleanTopologyFlatten(t *testing.T) {
+	const lineZ = `{"type":"LineString","coordinates":[[0,2,10],[2,2,12]]}`
+
+	tests := []struct {
+		name      string
+		flatten   bool
+		ordinates int
+	}{
+		{"keeps Z without flatten", false, 3},
+		{"drops Z with flatten", true, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := cleanTopology(t, featureCollection(westSquare, eastSquare, lineZ), func(options *CleanTopologyOptions) {
+				options.PassThroughNonPolygons = true
+				options.Flatten = tt.flatten
+			})
+
+			line := featureByID(t, result.Features, 2)
+			if got := firstPositionSize(t, line.Geometry); got != tt.ordinates {
+				t.Errorf("line position has %d ordinates, want %d: %s", got, tt.ordinates, line.Geometry)
+			}
+		})
+	}
+}
+
+// testTolerance is a snap tolerance well below the unit-square test geometries
+const testTolerance = 1e-6
+
+// geomFeatures parses WKT geometries into pipeline features, numbered by an id
+// property, and destroys them when the test ends
+func geomFeatures(t testing.TB, wkts ...string) []GeomFeature {
+	t.Helper()
+	features := make([]GeomFeature, len(wkts))
+	for i, wkt := range wkts {
+		geom, err := geos.NewGeomFromWKT(wkt)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", wkt, err)
+		}
+		features[i] = GeomFeature{Geom: geom, Properties: map[string]interface{}{"id": i}}
+	}
+	t.Cleanup(func() { destroyGeomFeatures(features) })
+	return features
+}
+
+func validateTestCoverage(geomFeatures []GeomFeature) CoverageReport {
+	return validateCoverageParallel(geomFeatures, testTolerance, DefaultCoverageSearchFactor, utils.DefaultQuadSegs, nil)
+}
+
+func TestValidateCoverageContainment(t *testing.T) {
+	tests := []struct {
+		name string
+		wkts []string
+		want []ContainmentPair
+	}{
+		{
+			name: "nested pair",
+			wkts: []string{"POLYGON ((0 0, 4 0, 4 4, 0 4, 0 0))", "POLYGON ((1 1, 2 1, 2 2, 1 2, 1 1))"},
+			want: []ContainmentPair{{Container: 0, Contained: 1}},
+		},
+		{
+			name: "container listed second",
+			wkts: []string{"POLYGON ((1 1, 2 1, 2 2, 1 2, 1 1))", "POLYGON ((0 0, 4 0, 4 4, 0 4, 0 0))"},
+			want: []ContainmentPair{{Container: 1, Contained: 0}},
+		},
+		{
+			name: "adjacent squares",
+			wkts: []string{"POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))", "POLYGON ((1 0, 2 0, 2 1, 1 1, 1 0))"},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := validateTestCoverage(geomFeatures(t, tt.wkts...))
+			if report.ContainmentCount != len(tt.want) {
+				t.Errorf("ContainmentCount = %d, want %d", report.ContainmentCount, len(tt.want))
+			}
+			if len(report.ContainmentPairs) != len(tt.want) {
+				t.Fatalf("ContainmentPairs = %v, want %v", report.ContainmentPairs, tt.want)
+			}
+			for i, pair := range tt.want {
+				if report.ContainmentPairs[i] != pair {
+					t.Errorf("ContainmentPairs[%d] = %+v, want %+v", i, report.ContainmentPairs[i], pair)
+				}
+			}
+			if report.OverlapCount != 0 {
+				t.Errorf("OverlapCount = %d, want 0: containment is not an overlap", report.OverlapCount)
+			}
+		})
+	}
+}
+
+// rawFeatures returns features of the given GeoJSON geometries, numbered by an
+// id property; an empty string leaves the geometry member out
+func rawFeatures(geometries ...string) []Feature {
+	features := make([]Feature, len(geometries))
+	for i, geometry := range geometries {
+		features[i] = Feature{Type: "Feature", Properties: map[string]interface{}{"id": i}}
+		if geometry != "" {
+			features[i].Geometry = json.RawMessage(geometry)
+		}
+	}
+	return features
+}
+
+func TestParseGeometriesNullGeometry(t *testing.T) {
+	tests := []struct {
+		name        string
+		geometry    string
+		keepNull    bool
+		passThrough int
+	}{
+		{"null geometry skipped", "null", false, 0},
+		{"missing geometry skipped", "", false, 0},
+		{"null geometry kept", "null", true, 1},
+		{"missing geometry kept", "", true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultCleanTopologyOptions()
+			options.KeepNullGeometries = tt.keepNull
+
+			parsed, passThrough, unfixed, err := parseGeometriesParallel(rawFeatures(westSquare, tt.geometry), options, nil)
+			if err != nil {
+				t.Fatalf("parseGeometriesParallel: %v", err)
+			}
+			defer destroyGeomFeatures(parsed)
+
+			if len(parsed) != 1 {
+				t.Errorf("parsed %d geometries, want only the square", len(parsed))
+			}
+			if len(passThrough) != tt.passThrough {
+				t.Fatalf("passed through %d features, want %d", len(passThrough), tt.passThrough)
+			}
+			if unfixed != 0 {
+				t.Errorf("unfixed = %d, want 0", unfixed)
+			}
+			if tt.passThrough > 0 {
+				if id := passThrough[0].Properties["id"]; id != 1 {
+					t.Errorf("passed through feature id %v, want 1", id)
+				}
+				if !utils.IsNullGeometry(passThrough[0].Geometry) {
+					t.Errorf("passed through geometry = %s, want it untouched", passThrough[0].Geometry)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeFeaturesTolerant(t *testing.T) {
+	const (
+		good         = `{"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]},"properties":{"id":%d}}`
+		syntaxError  = `{"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]},"properties":{"id": }}`
+		wrongType    = `{"type":"Feature","geometry":null,"properties":"not an object"}`
+		bracketValue = `{"type":"Feature","geometry":null,"properties":{"note":"a ] and , inside a string","id":%d}}`
+	)
+
+	tests := []struct {
+		name    string
+		members []string
+		wantIDs []float64
+		skipped []int
+	}{
+		{
+			name:    "all good",
+			members: []string{fmt.Sprintf(good, 0), fmt.Sprintf(good, 1)},
+			wantIDs: []float64{0, 1},
+			skipped: []int{},
+		},
+		{
+			name:    "syntax error in the middle",
+			members: []string{fmt.Sprintf(good, 0), syntaxError, fmt.Sprintf(good, 2)},
+			wantIDs: []float64{0, 2},
+			skipped: []int{1},
+		},
+		{
+			name:    "wrong member types",
+			members: []string{wrongType, fmt.Sprintf(good, 1), wrongType},
+			wantIDs: []float64{1},
+			skipped: []int{0, 2},
+		},
+		{
+			name:    "delimiters inside strings",
+			members: []string{fmt.Sprintf(bracketValue, 0), syntaxError},
+			wantIDs: []float64{0},
+			skipped: []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := `{"type":"FeatureCollection","features":[` + strings.Join(tt.members, ",") + `]}`
+			collection, skipped, err := decodeFeaturesTolerant(payload)
+			if err != nil {
+				t.Fatalf("decodeFeaturesTolerant: %v", err)
+			}
+
+			if fmt.Sprint(skipped) != fmt.Sprint(tt.skipped) {
+				t.Errorf("skipped = %v, want %v", skipped, tt.skipped)
+			}
+			if len(collection.Features) != len(tt.wantIDs) {
+				t.Fatalf("decoded %d features, want %d", len(collection.Features), len(tt.wantIDs))
+			}
+			for i, id := range tt.wantIDs {
+				if got := collection.Features[i].Properties["id"]; got != id {
+					t.Errorf("feature %d has id %v, want %v", i, got, id)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeFeaturesTolerantRejectsNonCollection(t *testing.T) {
+	for _, payload := range []string{`[]`, `{"type":"FeatureCollection","features":{}}`, `{"features":[{"type":"Feature"}`} {
+		if _, _, err := decodeFeaturesTolerant(payload); err == nil {
+			t.Errorf("decodeFeaturesTolerant(%s) succeeded, want an error", payload)
+		}
+	}
+}
+
+// gridGeomFeatures returns the features of a GenerateGrid collection of 0.001° cells
+func gridGeomFeatures(tb testing.TB, rows, cols int) []GeomFeature {
+	tb.Helper()
+	return generatedGeomFeatures(tb, utils.GenerateGrid(rows, cols, 0.001))
+}
+
+// generatedGeomFeatures parses a generated FeatureCollection into pipeline
+// features and destroys them when the test or benchmark ends
+func generatedGeomFeatures(tb testing.TB, payload []byte) []GeomFeature {
+	tb.Helper()
+	collection, skipped, err := decodeFeaturesTolerant(string(payload))
+	if err != nil || len(skipped) > 0 {
+		tb.Fatalf("decoding generated grid: %v (skipped %v)", err, skipped)
+	}
+
+	features := make([]GeomFeature, len(collection.Features))
+	for i, feature := range collection.Features {
+		geom, err := geos.NewGeomFromGeoJSON(string(feature.Geometry))
+		if err != nil {
+			tb.Fatalf("parsing feature %d: %v", i, err)
+		}
+		features[i] = GeomFeature{Geom: geom, Properties: feature.Properties}
+	}
+	tb.Cleanup(func() { destroyGeomFeatures(features) })
+	return features
+}
+
+func TestSerializeGeometriesParallelKeepsOrder(t *testing.T) {
+	geomFeatures := gridGeomFeatures(t, 20, 50)
+	// A nil geometry is skipped without shifting the rest
+	geomFeatures[3].Geom.Destroy()
+	geomFeatures[3].Geom = nil
+
+	features, _, err := serializeGeometriesParallel(geomFeatures, -1, false, nil)
+	if err != nil {
+		t.Fatalf("serializeGeometriesParallel: %v", err)
+	}
+	if len(features) != len(geomFeatures)-1 {
+		t.Fatalf("serialized %d features, want %d", len(features), len(geomFeatures)-1)
+	}
+
+	previous := -1.0
+	for i, feature := range features {
+		id, _ := feature.Properties["id"].(float64)
+		if id <= previous || id == 3 {
+			t.Fatalf("feature %d has id %v after id %v, want input order without id 3", i, id, previous)
+		}
+		previous = id
+		if !utils.IsPolygonalGeometry(feature.Geometry) {
+			t.Errorf("feature %d geometry = %s, want a polygon", i, feature.Geometry)
+		}
+	}
+}
+
+// BenchmarkSerializeGeometries compares writing cleaned geometries back to
+// GeoJSON one by one with the parallel serialization
+func BenchmarkSerializeGeometries(b *testing.B) {
+	geomFeatures := gridGeomFeatures(b, 200, 250) // 50k features
+
+	b.Run("serial", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			features := make([]Feature, 0, len(geomFeatures))
+			for _, geomFeature := range geomFeatures {
+				features = append(features, Feature{
+					Type:       "Feature",
+					Properties: geomFeature.Properties,
+					Geometry:   json.RawMessage(geomFeature.Geom.ToGeoJSON(-1)),
+				})
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			if _, _, err := serializeGeometriesParallel(geomFeatures, -1, false, nil); err != nil {
+				b.Fatalf("serializeGeometriesParallel: %v", err)
+			}
+		}
+	})
+}
+
+// BenchmarkCleanTopology runs the whole pipeline on clean GenerateGrid coverages
+func BenchmarkCleanTopology(b *testing.B) {
+	sizes := []struct {
+		rows, cols int
+	}{
+		{10, 100},  // 1k features
+		{100, 100}, // 10k features
+		{200, 250}, // 50k features
+	}
+
+	for _, size := range sizes {
+		payload := string(utils.GenerateGrid(size.rows, size.cols, 0.001))
+		b.Run(fmt.Sprintf("%d features", size.rows*size.cols), func(b *testing.B) {
+			options := DefaultCleanTopologyOptions()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := CleanTopology(payload, options); err != nil {
+					b.Fatalf("CleanTopology: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRollBackOverlappingSnaps(t *testing.T) {
+	// Three ~111 m cells: south with east to its right and north above it
+	const (
+		south        = "POLYGON ((0 0, 0.001 0, 0.001 0.001, 0 0.001, 0 0))"
+		southSnapped = "POLYGON ((0 0, 0.001 0, 0.001 0.0012, 0 0.0012, 0 0))"
+		east         = "POLYGON ((0.001 0, 0.002 0, 0.002 0.001, 0.001 0.001, 0.001 0))"
+		north        = "POLYGON ((0 0.001, 0.001 0.001, 0.001 0.002, 0 0.002, 0 0.001))"
+	)
+
+	tests := []struct {
+		name       string
+		originals  []string
+		validated  []string
+		snapped    []bool
+		rolledBack []int
+	}{
+		{
+			name:       "snap pushed into a third geometry",
+			originals:  []string{south, east, north},
+			validated:  []string{southSnapped, east, north},
+			snapped:    []bool{true, false, false},
+			rolledBack: []int{0},
+		},
+		{
+			name:      "snap without new overlap",
+			originals: []string{south, east, north},
+			validated: []string{south, east, north},
+			snapped:   []bool{true, true, true},
+		},
+		{
+			name:      "overlap already there before snapping",
+			originals: []string{southSnapped, east, north},
+			validated: []string{southSnapped, east, north},
+			snapped:   []bool{true, false, false},
+		},
+		{
+			name:      "overlap between unsnapped features",
+			originals: []string{south, east, north},
+			validated: []string{southSnapped, east, north},
+			snapped:   []bool{false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultCleanTopologyOptions()
+			originals := geomFeatures(t, tt.originals...)
+			validated := geomFeatures(t, tt.validated...)
+			report := validateTestCoverage(validated)
+
+			count := rollBackOverlappingSnaps(validated, originals, tt.snapped, report, 1, options.Precision, options.RepairMethod)
+			if count != len(tt.rolledBack) {
+				t.Errorf("rolled back %d snaps, want %d", count, len(tt.rolledBack))
+			}
+
+			rolledBack := make(map[int]bool)
+			for _, index := range tt.rolledBack {
+				rolledBack[index] = true
+			}
+			for i := range validated {
+				want := tt.validated[i]
+				if rolledBack[i] {
+					want = tt.originals[i]
+				}
+				wantGeom := mustGeomFromWKT(t, want)
+				if !validated[i].Geom.Equals(wantGeom) {
+					t.Errorf("feature %d = %s, want %s", i, validated[i].Geom.ToWKT(), want)
+				}
+			}
+
+			if after := validateTestCoverage(validated); len(tt.rolledBack) > 0 && after.OverlapCount != 0 {
+				t.Errorf("%d overlaps left after rolling back, want 0", after.OverlapCount)
+			}
+		})
+	}
+}
+
+// mustGeomFromWKT parses WKT and destroys the geometry when the test ends
+func mustGeomFromWKT(t testing.TB, wkt string) *geos.Geom {
+	t.Helper()
+	geom, err := geos.NewGeomFromWKT(wkt)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", wkt, err)
+	}
+	t.Cleanup(geom.Destroy)
+	return geom
+}
+
+// polygonCoordinates returns every ordinate of a Polygon or MultiPolygon geometry
+func polygonCoordinates(t testing.TB, geometry json.RawMessage) []float64 {
+	t.Helper()
+	var geom struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+	}
+	if err := json.Unmarshal(geometry, &geom); err != nil {
+		t.Fatalf("decoding geometry %s: %v", geometry, err)
+	}
+
+	var polygons [][][][]float64
+	if geom.Type == "Polygon" {
+		var rings [][][]float64
+		if err := json.Unmarshal(geom.Coordinates, &rings); err != nil {
+			t.Fatalf("decoding polygon %s: %v", geometry, err)
+		}
+		polygons = [][][][]float64{rings}
+	} else if err := json.Unmarshal(geom.Coordinates, &polygons); err != nil {
+		t.Fatalf("decoding multipolygon %s: %v", geometry, err)
+	}
+
+	ordinates := make([]float64, 0)
+	for _, rings := range polygons {
+		for _, ring := range rings {
+			for _, position := range ring {
+				ordinates = append(ordinates, position...)
+			}
+		}
+	}
+	return ordinates
+}
+
+func TestCleanTopologyOutputPrecision(t *testing.T) {
+	const precise = `{"type":"Polygon","coordinates":[[[0.123456789,0.123456789],[1.987654321,0.123456789],[1.987654321,1.555555555],[0.123456789,0.123456789]]]}`
+
+	tests := []struct {
+		name            string
+		outputPrecision int
+		wantDecimals    int
+	}{
+		{"three decimals", 3, 3},
+		{"six decimals", 6, 6},
+		{"full precision", -1, utils.DefaultPrecision},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := cleanTopology(t, featureCollection(precise), func(options *CleanTopologyOptions) {
+				options.OutputPrecision = tt.outputPrecision
+			})
+			if len(result.Features) != 1 {
+				t.Fatalf("got %d features, want 1", len(result.Features))
+			}
+
+			scale := math.Pow(10, float64(tt.wantDecimals))
+			for _, ordinate := range polygonCoordinates(t, result.Features[0].Geometry) {
+				if scaled := ordinate * scale; math.Abs(scaled-math.Round(scaled)) > 1e-6 {
+					t.Errorf("ordinate %v has more than %d decimals", ordinate, tt.wantDecimals)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanTopologyLenient(t *testing.T) {
+	const (
+		line          = `{"type":"LineString","coordinates":[[5,5],[6,6]]}`
+		wrongNesting  = `{"type":"MultiPolygon","coordinates":[[[3,0],[4,0],[4,1],[3,0]]]}`
+		noCoordinates = `{"type":"Polygon","coordinates":[]}`
+	)
+	payload := featureCollection(westSquare, line, "null", wrongNesting, noCoordinates)
+
+	tests := []struct {
+		name    string
+		lenient bool
+		wantIDs []int
+		unfixed []int
+	}{
+		{"drops what it cannot clean", false, []int{0}, nil},
+		{"keeps every feature", true, []int{0, 1, 2, 3, 4}, []int{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := cleanTopology(t, payload, func(options *CleanTopologyOptions) {
+				options.Lenient = tt.lenient
+			})
+
+			if len(result.Features) != len(tt.wantIDs) {
+				t.Fatalf("got %d features, want %d", len(result.Features), len(tt.wantIDs))
+			}
+			if result.UnfixedFeatures != len(tt.unfixed) {
+				t.Errorf("UnfixedFeatures = %d, want %d", result.UnfixedFeatures, len(tt.unfixed))
+			}
+
+			unfixed := make(map[int]bool)
+			for _, id := range tt.unfixed {
+				unfixed[id] = true
+			}
+			for _, id := range tt.wantIDs {
+				feature := featureByID(t, result.Features, id)
+				flagged, _ := feature.Properties["_unfixed"].(bool)
+				if flagged != unfixed[id] {
+					t.Errorf("feature %d _unfixed = %v, want %v", id, flagged, unfixed[id])
+				}
+				if reason, _ := feature.Properties["_unfixed_reason"].(string); unfixed[id] && reason == "" {
+					t.Errorf("feature %d has no _unfixed_reason", id)
+				}
+			}
+		})
+	}
+}
+
+func TestInputPosition(t *testing.T) {
+	tests := []struct {
+		name    string
+		skipped []int
+		want    []int // request position of each decoded feature
+	}{
+		{"nothing skipped", nil, []int{0, 1, 2}},
+		{"first skipped", []int{0}, []int{1, 2, 3}},
+		{"middle skipped", []int{1}, []int{0, 2, 3}},
+		{"runs of skipped features", []int{0, 1, 3, 4}, []int{2, 5, 6}},
+		{"last skipped", []int{3}, []int{0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for decodedIndex, want := range tt.want {
+				if got := inputPosition(decodedIndex, tt.skipped); got != want {
+					t.Errorf("inputPosition(%d, %v) = %d, want %d", decodedIndex, tt.skipped, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCleanTopologyKeepsInputOrder(t *testing.T) {
+	const (
+		feature   = `{"type":"Feature","geometry":%s,"properties":{"id":%d}}`
+		malformed = `{"type":"Feature","geometry":null,"properties":{"id": }}`
+		line      = `{"type":"LineString","coordinates":[[5,5],[6,6]]}`
+	)
+	// Request positions match the id properties; position 1 is skipped by the
+	// decoder and the east square comes before the west one
+	members := []string{
+		fmt.Sprintf(feature, eastSquare, 0),
+		malformed,
+		fmt.Sprintf(feature, line, 2),
+		fmt.Sprintf(feature, "null", 3),
+		fmt.Sprintf(feature, westSquare, 4),
+	}
+	payload := `{"type":"FeatureCollection","features":[` + strings.Join(members, ",") + `]}`
+
+	tests := []struct {
+		name      string
+		configure func(*CleanTopologyOptions)
+		wantIDs   []int
+	}{
+		{
+			name:    "dropped features leave gaps",
+			wantIDs: []int{0, 4},
+		},
+		{
+			name: "pass-through features stay in place",
+			configure: func(options *CleanTopologyOptions) {
+				options.PassThroughNonPolygons = true
+				options.KeepNullGeometries = true
+			},
+			wantIDs: []int{0, 2, 3, 4},
+		},
+		{
+			name: "lenient features stay in place",
+			configure: func(options *CleanTopologyOptions) {
+				options.Lenient = true
+			},
+			wantIDs: []int{0, 2, 3, 4},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, includeIndex := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/includeInputIndex=%v", tt.name, includeIndex), func(t *testing.T) {
+				result := cleanTopology(t, payload, func(options *CleanTopologyOptions) {
+					if tt.configure != nil {
+						tt.configure(options)
+					}
+					options.IncludeInputIndex = includeIndex
+				})
+
+				if result.SkippedFeatureCount != 1 {
+					t.Errorf("SkippedFeatureCount = %d, want 1", result.SkippedFeatureCount)
+				}
+				if len(result.Features) != len(tt.wantIDs) {
+					t.Fatalf("got %d features, want %d", len(result.Features), len(tt.wantIDs))
+				}
+				for i, want := range tt.wantIDs {
+					properties := result.Features[i].Properties
+					if id, _ := properties["id"].(float64); int(id) != want {
+						t.Errorf("feature %d has id %v, want %d", i, properties["id"], want)
+					}
+					index, ok := properties[InputIndexProperty]
+					switch {
+					case includeIndex && index != want:
+						t.Errorf("feature %d %s = %v, want %d", i, InputIndexProperty, index, want)
+					case !includeIndex && ok:
+						t.Errorf("feature %d kept %s without includeInputIndex", i, InputIndexProperty)
+					}
+				}
+			})
+		}
+	}
+}
+
+const bowtieWKT = "POLYGON ((0 0, 2 2, 2 0, 0 2, 0 0))"
+
+func TestRepairGeometry(t *testing.T) {
+	tests := []struct {
+		name         string
+		repairMethod string
+		want         string
+	}{
+		{"makeValid tries linework first", RepairMethodMakeValid, "makeValid(linework)"},
+		{"buffer0 tries buffer(0) first", RepairMethodBuffer0, "buffer(0)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bowtie := mustGeomFromWKT(t, bowtieWKT)
+			repaired, method := RepairGeometry(bowtie, 0, tt.repairMethod)
+			if repaired == nil {
+				t.Fatal("RepairGeometry failed on a bowtie")
+			}
+			defer repaired.Destroy()
+
+			if method != tt.want {
+				t.Errorf("method = %q, want %q", method, tt.want)
+			}
+			if !repaired.IsValid() {
+				t.Errorf("repaired geometry %s is invalid: %s", repaired.ToWKT(), repaired.IsValidReason())
+			}
+			if bowtie.IsValid() {
+				t.Error("RepairGeometry changed its input")
+			}
+		})
+	}
+}
+
+func TestRepairWithStrategiesFallsBack(t *testing.T) {
+	// stillInvalid stands in for a MakeValid that returns an invalid geometry
+	stillInvalid := repairStrategy{"stillInvalid", func(geom *geos.Geom) *geos.Geom { return geom.Clone() }}
+	returnsNil := repairStrategy{"returnsNil", func(*geos.Geom) *geos.Geom { return nil }}
+	panics := repairStrategy{"panics", func(*geos.Geom) *geos.Geom { panic("GEOS failure") }}
+
+	tests := []struct {
+		name       string
+		strategies []repairStrategy
+		want       string // empty when every strategy fails
+	}{
+		{"invalid result falls through", []repairStrategy{stillInvalid, makeValidStructure, bufferZero}, "makeValid(structure)"},
+		{"nil and panics fall through", []repairStrategy{returnsNil, panics, bufferZero}, "buffer(0)"},
+		{"first valid result wins", []repairStrategy{makeValidLinework, bufferZero}, "makeValid(linework)"},
+		{"every strategy fails", []repairStrategy{stillInvalid, returnsNil, panics}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bowtie := mustGeomFromWKT(t, bowtieWKT)
+			repaired, method := repairWithStrategies(bowtie, 0, tt.strategies)
+			if method != tt.want {
+				t.Errorf("method = %q, want %q", method, tt.want)
+			}
+			if tt.want == "" {
+				if repaired != nil {
+					t.Errorf("repaired = %s, want nil when every strategy fails", repaired.ToWKT())
+					repaired.Destroy()
+				}
+				return
+			}
+			if repaired == nil {
+				t.Fatal("repaired = nil")
+			}
+			defer repaired.Destroy()
+			if !repaired.IsValid() {
+				t.Errorf("repaired geometry %s is invalid", repaired.ToWKT())
+			}
+		})
+	}
+}
+
+func TestFeatureFilesZip(t *testing.T) {
+	features := rawFeatures(westSquare, eastSquare, westSquare, eastSquare)
+	for i, parcel := range []interface{}{"P-1", nil, "P-1", 42.0} {
+		if parcel != nil {
+			features[i].Properties["parcel"] = parcel
+		}
+	}
+	wantNames := []string{"P-1.geojson", "feature_1.geojson", "P-1_2.geojson", "42.geojson"}
+
+	zipData, err := featureFilesZip(features, "parcel")
+	if err != nil {
+		t.Fatalf("featureFilesZip: %v", err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+
+	if len(reader.File) != len(wantNames) {
+		t.Fatalf("zip holds %d files, want %d", len(reader.File), len(wantNames))
+	}
+	for i, file := range reader.File {
+		if file.Name != wantNames[i] {
+			t.Errorf("file %d is %s, want %s", i, file.Name, wantNames[i])
+		}
+
+		contents, err := file.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", file.Name, err)
+		}
+		var feature Feature
+		err = json.NewDecoder(contents).Decode(&feature)
+		contents.Close()
+		if err != nil {
+			t.Fatalf("decoding %s: %v", file.Name, err)
+		}
+		if id, _ := feature.Properties["id"].(float64); int(id) != i {
+			t.Errorf("%s holds feature %v, want %d", file.Name, feature.Properties["id"], i)
+		}
+		if string(feature.Geometry) != string(features[i].Geometry) {
+			t.Errorf("%s has geometry %s, want %s", file.Name, feature.Geometry, features[i].Geometry)
+		}
+	}
+}
+
+// zipEntries returns the contents of each member of a zip, by name
+func zipEntries(t testing.TB, zipData []byte) map[string][]byte {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+
+	entries := make(map[string][]byte, len(reader.File))
+	for _, file := range reader.File {
+		contents, err := file.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(contents)
+		contents.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", file.Name, err)
+		}
+		entries[file.Name] = data
+	}
+	return entries
+}
+
+func TestCleanTopologyWithShapefileReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*CleanTopologyOptions)
+		entries   []string
+	}{
+		{
+			name:    "default output",
+			entries: []string{"cleaned_topology.json", "cleaned_topology.shp", "cleaned_topology.shx", "cleaned_topology.dbf", "report.json"},
+		},
+		{
+			name: "with original geometries",
+			configure: func(options *CleanTopologyOptions) {
+				options.IncludeOriginal = true
+			},
+			entries: []string{"cleaned_topology.json", "cleaned_topology.shp", "original.geojson", "cleaned.geojson", "report.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultCleanTopologyOptions()
+			if tt.configure != nil {
+				tt.configure(&options)
+			}
+			zipData, err := CleanTopologyWithShapefile(featureCollection(westSquare, eastSquare), options)
+			if err != nil {
+				t.Fatalf("CleanTopologyWithShapefile: %v", err)
+			}
+
+			entries := zipEntries(t, zipData)
+			for _, name := range tt.entries {
+				if _, ok := entries[name]; !ok {
+					t.Errorf("zip has no %s", name)
+				}
+			}
+
+			var report CleaningReport
+			if err := json.Unmarshal(entries["report.json"], &report); err != nil {
+				t.Fatalf("decoding report.json: %v", err)
+			}
+			if report.Coverage.GapCount != 0 || report.Coverage.OverlapCount != 0 {
+				t.Errorf("coverage = %+v, want no gaps or overlaps between two edge-sharing squares", report.Coverage)
+			}
+			if report.Coverage.Gaps == nil || report.Coverage.Overlaps == nil || report.Coverage.Containments == nil {
+				t.Errorf("coverage lists should be empty arrays, not null: %s", entries["report.json"])
+			}
+			if report.BoundaryPreservation == nil || report.BoundaryPreservation.TotalGeometries != 2 {
+				t.Errorf("boundaryPreservation = %+v, want 2 geometries checked", report.BoundaryPreservation)
+			}
+		})
+	}
+}
+
+func TestKeepsOriginals(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(*CleanTopologyOptions)
+		want      bool
+	}{
+		{"defaults validate preservation", func(*CleanTopologyOptions) {}, true},
+		{"preservation disabled", func(options *CleanTopologyOptions) {
+			options.ValidatePreservation = false
+		}, false},
+		{"lenient mode still needs originals", func(options *CleanTopologyOptions) {
+			options.ValidatePreservation = false
+			options.Lenient = true
+		}, true},
+		{"includeOriginal still needs originals", func(options *CleanTopologyOptions) {
+			options.ValidatePreservation = false
+			options.IncludeOriginal = true
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultCleanTopologyOptions()
+			tt.configure(&options)
+			if got := keepsOriginals(options); got != tt.want {
+				t.Errorf("keepsOriginals = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanTopologyWithoutPreservation(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate bool
+	}{
+		{"preservation validated", true},
+		{"preservation skipped", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := cleanTopology(t, featureCollection(westSquare, eastSquare), func(options *CleanTopologyOptions) {
+				options.ValidatePreservation = tt.validate
+			})
+
+			if len(result.Features) != 2 {
+				t.Fatalf("got %d features, want 2", len(result.Features))
+			}
+			if result.Original != nil {
+				t.Errorf("Original holds %d features without includeOriginal", len(result.Original))
+			}
+			if result.RolledBackSnaps != 0 {
+				t.Errorf("RolledBackSnaps = %d, want 0", result.RolledBackSnaps)
+			}
+			if preserved := result.Report.BoundaryPreservation != nil; preserved != tt.validate {
+				t.Errorf("boundary preservation reported = %v, want %v", preserved, tt.validate)
+			}
+		})
+	}
+}
+
+func TestValidateCoverageOverlapAreaM2(t *testing.T) {
+	// Each pair overlaps in a 0.0001° x 0.001° strip, whose geodesic area is
+	// R² Δλ (sin φ2 - sin φ1)
+	tests := []struct {
+		name   string
+		wkts   []string
+		wantM2 float64
+	}{
+		{
+			name:   "at the equator",
+			wkts:   []string{"POLYGON ((0 0, 0.001 0, 0.001 0.001, 0 0.001, 0 0))", "POLYGON ((0.0009 0, 0.002 0, 0.002 0.001, 0.0009 0.001, 0.0009 0))"},
+			wantM2: 1236.434586751409,
+		},
+		{
+			name:   "at 60°N",
+			wkts:   []string{"POLYGON ((0 60, 0.001 60, 0.001 60.001, 0 60.001, 0 60))", "POLYGON ((0.0009 60, 0.002 60, 0.002 60.001, 0.0009 60.001, 0.0009 60))"},
+			wantM2: 618.207949019072,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := validateTestCoverage(geomFeatures(t, tt.wkts...))
+			if report.OverlapCount != 1 || len(report.OverlapPairs) != 1 {
+				t.Fatalf("OverlapCount = %d with pairs %v, want one overlap", report.OverlapCount, report.OverlapPairs)
+			}
+			// Within a square centimetre
+			if math.Abs(report.OverlapAreaM2-tt.wantM2) > 1e-4 {
+				t.Errorf("OverlapAreaM2 = %v, want %v", report.OverlapAreaM2, tt.wantM2)
+			}
+			if pair := report.OverlapPairs[0]; math.Abs(pair.AreaM2-tt.wantM2) > 1e-4 {
+				t.Errorf("overlap pair AreaM2 = %v, want %v", pair.AreaM2, tt.wantM2)
+			}
+		})
+	}
+}
+
+func TestCleanTopologyReproducible(t *testing.T) {
+	// Snapping applies neighbours in turn and workers finish in any order, so
+	// this catches results that depend on completion order
+	payload := string(utils.GenerateGridWithDefects(12, 15, 0.001, utils.GridDefects{
+		GapRatio:       0.15,
+		OverlapRatio:   0.15,
+		InvalidRatio:   0.05,
+		DefectWidthDeg: 0.000001,
+		Seed:           42,
+	}))
+
+	var first []byte
+	for run := range 3 {
+		result := cleanTopology(t, payload, nil)
+		output, err := json.Marshal(result.Features)
+		if err != nil {
+			t.Fatalf("encoding run %d: %v", run, err)
+		}
+		if run == 0 {
+			first = output
+		} else if !bytes.Equal(output, first) {
+			t.Errorf("run %d gave different output from run 0", run)
+		}
+	}
+}
+
+func TestParseGeometriesNonPolygons(t *testing.T) {
+	const (
+		point        = `{"type":"Point","coordinates":[5,5]}`
+		line         = `{"type":"LineString","coordinates":[[5,5],[6,6]]}`
+		multiPolygon = `{"type":"MultiPolygon","coordinates":[[[[3,0],[4,0],[4,1],[3,0]]]]}`
+		collection   = `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[7,7]}]}`
+	)
+	features := rawFeatures(westSquare, point, line, multiPolygon, collection)
+
+	tests := []struct {
+		name        string
+		passThrough bool
+		lenient     bool
+		wantIDs     []int    // of the passed-through features
+		wantReasons []string // geometry type named in each _unfixed_reason
+	}{
+		{name: "dropped by default"},
+		{name: "passed through", passThrough: true, wantIDs: []int{1, 2, 4}},
+		{name: "flagged when lenient", lenient: true, wantIDs: []int{1, 2, 4}, wantReasons: []string{"Point", "LineString", "GeometryCollection"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := DefaultCleanTopologyOptions()
+			options.PassThroughNonPolygons = tt.passThrough
+			options.Lenient = tt.lenient
+
+			parsed, passThrough, unfixed, err := parseGeometriesParallel(features, options, nil)
+			if err != nil {
+				t.Fatalf("parseGeometriesParallel: %v", err)
+			}
+			defer destroyGeomFeatures(parsed)
+
+			if len(parsed) != 2 || parsed[0].Properties["id"] != 0 || parsed[1].Properties["id"] != 3 {
+				t.Errorf("parsed %d geometries, want the polygon and the multipolygon", len(parsed))
+			}
+			if len(passThrough) != len(tt.wantIDs) {
+				t.Fatalf("passed through %d features, want %d", len(passThrough), len(tt.wantIDs))
+			}
+			if unfixed != len(tt.wantReasons) {
+				t.Errorf("unfixed = %d, want %d", unfixed, len(tt.wantReasons))
+			}
+
+			for i, id := range tt.wantIDs {
+				feature := passThrough[i]
+				if feature.Properties["id"] != id {
+					t.Errorf("passed through feature %d has id %v, want %d", i, feature.Properties["id"], id)
+				}
+				if string(feature.Geometry) != string(features[id].Geometry) {
+					t.Errorf("passed through geometry = %s, want %s unchanged", feature.Geometry, features[id].Geometry)
+				}
+
+				reason, _ := feature.Properties["_unfixed_reason"].(string)
+				if tt.wantReasons == nil {
+					if reason != "" {
+						t.Errorf("passed through feature %d flagged unfixed: %s", i, reason)
+					}
+				} else if !strings.Contains(reason, "type: "+tt.wantReasons[i]) {
+					t.Errorf("_unfixed_reason = %q, want it to name the %s type", reason, tt.wantReasons[i])
+				}
+			}
+		})
+	}
+}
+
+func TestGapFillMaxAreaM2(t *testing.T) {
+	const defaultTolerance = 0.4 / 111000 // 40cm in degrees
+
+	tests := []struct {
+		name      string
+		tolerance float64
+		maxAreaM2 float64
+		want      float64
+	}{
+		{"default at 40cm", defaultTolerance, 0, 1.6},
+		{"negative uses the default", defaultTolerance, -1, 1.6},
+		{"default scales with tolerance", 2 * defaultTolerance, 0, 6.4},
+		{"explicit maximum", defaultTolerance, 2.5, 2.5},
+		{"explicit maximum below the default", defaultTolerance, 0.01, 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := gapFillMaxAreaM2(tt.tolerance, tt.maxAreaM2); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("gapFillMaxAreaM2(%g, %g) = %g, want %g", tt.tolerance, tt.maxAreaM2, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFillGapBetweenGeometries(t *testing.T) {
+	const tolerance = 1e-5
+
+	// gapSquares returns two 0.001° squares at the equator separated by gap
+	gapSquares := func(t *testing.T, gap float64) (*geos.Geom, *geos.Geom) {
+		t.Helper()
+		west, err := geos.NewGeomFromWKT("POLYGON ((0 0, 0.001 0, 0.001 0.001, 0 0.001, 0 0))")
+		if err != nil {
+			t.Fatalf("parsing west square: %v", err)
+		}
+		x := 0.001 + gap
+		east, err := geos.NewGeomFromWKT(fmt.Sprintf("POLYGON ((%[1]v 0, %[2]v 0, %[2]v 0.001, %[1]v 0.001, %[1]v 0))", x, x+0.001))
+		if err != nil {
+			t.Fatalf("parsing east square: %v", err)
+		}
+		return west, east
+	}
+
+	tests := []struct {
+		name    string
+		gap     float64
+		wantErr string
+	}{
+		{"gap just inside twice the tolerance", 2 * tolerance * (1 - 1e-6), ""},
+		{"gap well inside the tolerance", tolerance / 2, ""},
+		{"gap just beyond twice the tolerance", 2 * tolerance * (1 + 1e-6), "gap too large to fill safely"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			west, east := gapSquares(t, tt.gap)
+			defer west.Destroy()
+			defer east.Destroy()
+
+			filled, err := fillGapBetweenGeometries(west, east, tolerance, 0, 8)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("fillGapBetweenGeometries error = %v, want %q", err, tt.wantErr)
+				}
+				if filled != nil {
+					filled.Destroy()
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("fillGapBetweenGeometries: %v", err)
+			}
+			defer filled.Destroy()
+			if areaM2, maxAreaM2 := utils.GeodesicArea(filled), gapFillMaxAreaM2(tolerance, 0); areaM2 > maxAreaM2 {
+				t.Errorf("filled %g m², above the %g m² maximum", areaM2, maxAreaM2)
+			}
+		})
+	}
+
+	t.Run("nil geometry", func(t *testing.T) {
+		west, east := gapSquares(t, tolerance)
+		defer west.Destroy()
+		defer east.Destroy()
+		if _, err := fillGapBetweenGeometries(west, nil, tolerance, 0, 8); err == nil {
+			t.Error("fillGapBetweenGeometries accepted a nil geometry")
+		}
+	})
+}
+
+func TestPassUnserialized(t *testing.T) {
+	// Request positions 5, 7 and 9, as after undecodable features were skipped
+	features := make([]Feature, 0, 3)
+	geomFeatures := make([]GeomFeature, 0, 3)
+	for i, position := range []int{5, 7, 9} {
+		properties := map[string]interface{}{"name": fmt.Sprintf("parcel %d", i), InputIndexProperty: position}
+		features = append(features, Feature{Type: "Feature", Geometry: json.RawMessage(westSquare), Properties: properties})
+		geomFeatures = append(geomFeatures, GeomFeature{Properties: copyProperties(properties)})
+	}
+	failed := []SerializationResult{{Index: 1, Error: fmt.Errorf("IllegalArgumentException: bad geometry")}}
+
+	tests := []struct {
+		name        string
+		lenient     bool
+		wantPassed  int
+		wantSkipped []int
+	}{
+		{"lenient passes the input geometry through", true, 1, nil},
+		{"strict lists the request position as skipped", false, 0, []int{7}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &TopologyCleaningResult{}
+			passed := passUnserialized(result, features, geomFeatures, failed, tt.lenient)
+
+			if len(passed) != tt.wantPassed {
+				t.Fatalf("passed %d features through, want %d", len(passed), tt.wantPassed)
+			}
+			if result.UnfixedFeatures != tt.wantPassed {
+				t.Errorf("UnfixedFeatures = %d, want %d", result.UnfixedFeatures, tt.wantPassed)
+			}
+			if fmt.Sprint(result.SkippedFeatures) != fmt.Sprint(tt.wantSkipped) {
+				t.Errorf("SkippedFeatures = %v, want %v", result.SkippedFeatures, tt.wantSkipped)
+			}
+
+			for _, feature := range passed {
+				if string(feature.Geometry) != westSquare {
+					t.Errorf("passed through geometry %s, want the input %s", feature.Geometry, westSquare)
+				}
+				if feature.Properties["_unfixed"] != true || feature.Properties[InputIndexProperty] != 7 || feature.Properties["name"] != "parcel 1" {
+					t.Errorf("passed through properties %v, want parcel 1 at position 7 flagged _unfixed", feature.Properties)
+				}
+				if reason, _ := feature.Properties["_unfixed_reason"].(string); !strings.Contains(reason, "bad geometry") {
+					t.Errorf("_unfixed_reason = %q, want the serialization error", reason)
+				}
+			}
+		})
+	}
+
+	if passed := passUnserialized(&TopologyCleaningResult{}, features, geomFeatures, nil, true); passed != nil {
+		t.Errorf("passUnserialized without failures = %v, want nil", passed)
+	}
+}
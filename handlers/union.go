@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/twpayne/go-geos"
+)
+
+// Union methods
+const (
+	// UnionMethodUnary unions every geometry in one GEOS UnaryUnion call, which
+	// nodes overlapping inputs together and is the most robust choice
+	UnionMethodUnary = "unary"
+	// UnionMethodCascaded unions geometries pairwise, halving the set each round
+	UnionMethodCascaded = "cascaded"
+)
+
+// UnionFeatures returns a single feature holding the union of all feature
+// geometries, carrying the caller-supplied properties, or none. Unlike dissolve
+// the inputs are not made valid, buffered or truncated first.
+func UnionFeatures(features []Feature, method string, properties map[string]interface{}) (Feature, error) {
+	geoms := make([]*geos.Geom, 0, len(features))
+	for i, feature := range features {
+		geom, err := parseFeatureGeometry(feature)
+		if err != nil {
+			log.Printf("Skipping feature %d: %v", i, err)
+			continue
+		}
+		geoms = append(geoms, geom)
+	}
+
+	union, err := UnionGeometries(geoms, method)
+	if err != nil {
+		return Feature{}, err
+	}
+	defer union.Destroy()
+
+	return NewMergedFeature(union, properties), nil
+}
+
+// UnionGeometries unions geometries with the given method. It takes ownership
+// of geometries, which are destroyed whether or not the union succeeds.
+func UnionGeometries(geometries []*geos.Geom, method string) (*geos.Geom, error) {
+	if method != UnionMethodUnary && method != UnionMethodCascaded {
+		for _, geom := range geometries {
+			geom.Destroy()
+		}
+		return nil, fmt.Errorf("unknown union method %q", method)
+	}
+	if len(geometries) == 0 {
+		return nil, fmt.Errorf("no valid geometries found")
+	}
+
+	var union *geos.Geom
+	if method == UnionMethodCascaded {
+		// CascadedUnion destroys its inputs as it merges them
+		var err error
+		union, err = CascadedUnion(geometries)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// The collection takes ownership of the geometries
+		collection := geos.NewCollection(geos.TypeIDGeometryCollection, geometries)
+		defer collection.Destroy()
+		union = collection.UnaryUnion()
+	}
+
+	if union == nil {
+		return nil, fmt.Errorf("failed to union geometries")
+	}
+	return union, nil
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+)
+
+// cloneGeoms clones the geometries of features, for union calls that consume their input
+func cloneGeoms(features []GeomFeature) []*geos.Geom {
+	geoms := make([]*geos.Geom, len(features))
+	for i, feature := range features {
+		geoms[i] = feature.Geom.Clone()
+	}
+	return geoms
+}
+
+func TestUnionGeometriesMethodsAgree(t *testing.T) {
+	tests := []struct {
+		name  string
+		wkts  []string
+		area  float64
+		parts int
+	}{
+		{
+			name:  "adjacent squares",
+			wkts:  []string{"POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))", "POLYGON ((1 0, 2 0, 2 1, 1 1, 1 0))"},
+			area:  2,
+			parts: 1,
+		},
+		{
+			name:  "overlapping squares",
+			wkts:  []string{"POLYGON ((0 0, 2 0, 2 2, 0 2, 0 0))", "POLYGON ((1 1, 3 1, 3 3, 1 3, 1 1))", "POLYGON ((0.5 0.5, 1.5 0.5, 1.5 1.5, 0.5 1.5, 0.5 0.5))"},
+			area:  7,
+			parts: 1,
+		},
+		{
+			name:  "disjoint squares",
+			wkts:  []string{"POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))", "POLYGON ((5 5, 6 5, 6 6, 5 6, 5 5))"},
+			area:  2,
+			parts: 2,
+		},
+		{
+			name:  "ring of squares around a hole",
+			wkts:  []string{"POLYGON ((0 0, 3 0, 3 1, 0 1, 0 0))", "POLYGON ((0 2, 3 2, 3 3, 0 3, 0 2))", "POLYGON ((0 1, 1 1, 1 2, 0 2, 0 1))", "POLYGON ((2 1, 3 1, 3 2, 2 2, 2 1))"},
+			area:  8,
+			parts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := geomFeatures(t, tt.wkts...)
+
+			unions := make(map[string]*geos.Geom)
+			for _, method := range []string{UnionMethodUnary, UnionMethodCascaded} {
+				union, err := UnionGeometries(cloneGeoms(features), method)
+				if err != nil {
+					t.Fatalf("%s union: %v", method, err)
+				}
+				defer union.Destroy()
+				unions[method] = union
+
+				if area := union.Area(); math.Abs(area-tt.area) > 1e-9 {
+					t.Errorf("%s union area = %v, want %v", method, area, tt.area)
+				}
+				if parts := union.NumGeometries(); parts != tt.parts {
+					t.Errorf("%s union has %d parts, want %d: %s", method, parts, tt.parts, union.ToWKT())
+				}
+			}
+
+			if !unions[UnionMethodUnary].Equals(unions[UnionMethodCascaded]) {
+				t.Errorf("unary union %s differs from cascaded union %s", unions[UnionMethodUnary].ToWKT(), unions[UnionMethodCascaded].ToWKT())
+			}
+		})
+	}
+}
+
+func TestUnionGeometriesRejectsUnknownMethod(t *testing.T) {
+	features := geomFeatures(t, "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))")
+	if _, err := UnionGeometries(cloneGeoms(features), "pairwise"); err == nil {
+		t.Error("UnionGeometries accepted an unknown method")
+	}
+}
+
+// BenchmarkUnionGeometries compares the unary and cascaded union of a grid coverage
+func BenchmarkUnionGeometries(b *testing.B) {
+	for _, size := range []int{10, 50} {
+		features := gridGeomFeatures(b, size, size)
+		for _, method := range []string{UnionMethodUnary, UnionMethodCascaded} {
+			b.Run(fmt.Sprintf("%s %d features", method, len(features)), func(b *testing.B) {
+				for range b.N {
+					b.StopTimer()
+					geoms := cloneGeoms(features)
+					b.StartTimer()
+
+					union, err := UnionGeometries(geoms, method)
+					if err != nil {
+						b.Fatalf("%s union: %v", method, err)
+					}
+					union.Destroy()
+				}
+			})
+		}
+	}
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/bsaid97/go-polygon-fixer/handlers"
 	"github.com/bsaid97/go-polygon-fixer/utils"
@@ -45,6 +55,7 @@ type PolygonGeometry struct {
 // Feature struct: Holds geometry + properties
 type Feature struct {
 	Type       string                 `json:"type"`
+	BBox       []float64              `json:"bbox,omitempty"`
 	Geometry   json.RawMessage        `json:"geometry"`
 	Properties map[string]interface{} `json:"properties"`
 }
@@ -52,6 +63,7 @@ type Feature struct {
 // FeatureCollection struct: Holds multiple features
 type FeatureCollection struct {
 	Type     string    `json:"type"`
+	BBox     []float64 `json:"bbox,omitempty"`
 	Features []Feature `json:"features"`
 }
 
@@ -60,15 +72,131 @@ type GeomFeature struct {
 	Properties map[string]interface{}
 }
 
+// Build information, set at build time with
+// -ldflags "-X main.version=... -X main.commit=... -X main.buildDate=..."
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+// VersionInfo identifies the running build
+type VersionInfo struct {
+	Version     string `json:"version"`
+	Commit      string `json:"commit"`
+	BuildDate   string `json:"buildDate"`
+	GoVersion   string `json:"goVersion"`
+	GEOSVersion string `json:"geosVersion"`
+}
+
+// referenceCache holds reference layers reused across requests by referenceId
+var referenceCache *handlers.ReferenceCache
+
+// Save-mode directories: client filepaths are only read from inside inputDir
+// and are mirrored into outputDir, the only directory saveFile requests may
+// write into
+var (
+	inputDir  = "files"
+	outputDir = "output"
+)
+
+// partLimits caps polygon parts on every endpoint reading a GeoJSON payload,
+// guarding against payloads built to exhaust memory and CPU
+var partLimits utils.PartLimits
+
+// maxDecompressedBytes caps how far a gzip body or .gz upload may inflate
+var maxDecompressedBytes int64
+
+// sourceFetcher downloads payloads named by a sourceUrl option
+var sourceFetcher *utils.SourceFetcher
+
+// resultUploaders store results at a destinationUrl instead of returning them
+var resultUploaders *utils.ResultUploaders
+
 func main() {
 	log.Printf("=== Starting Go Polygon Fixer Server ===")
 	
+	// Cap concurrently processing heavy requests so they don't oversubscribe the CPU
+	maxConcurrent := envInt("MAX_CONCURRENT_REQUESTS", 2)
+	queueTimeout := time.Duration(envInt("REQUEST_QUEUE_TIMEOUT_SECONDS", 30)) * time.Second
+	limiter := utils.NewRequestLimiter(maxConcurrent, queueTimeout)
+	log.Printf("Limiting heavy requests to %d concurrent (queue timeout %s)", maxConcurrent, queueTimeout)
+
+	// Parsed reference layers shared across /compare requests that name a referenceId
+	referenceTTL := time.Duration(envInt("REFERENCE_CACHE_TTL_SECONDS", 3600)) * time.Second
+	referenceCache = handlers.NewReferenceCache(referenceTTL,
+		envInt("REFERENCE_CACHE_MAX_ENTRIES", 32), int64(envInt("REFERENCE_CACHE_MAX_BYTES", 512<<20)))
+
+	if dir := os.Getenv("INPUT_DIR"); dir != "" {
+		inputDir = dir
+	}
+	if dir := os.Getenv("OUTPUT_DIR"); dir != "" {
+		outputDir = dir
+	}
+	log.Printf("Saving files from %s under %s", inputDir, outputDir)
+
+	partLimits = utils.PartLimits{
+		PerFeature: envInt("MAX_POLYGON_PARTS_PER_FEATURE", 100000),
+		PerRequest: envInt("MAX_POLYGON_PARTS_PER_REQUEST", 1000000),
+	}
+	log.Printf("Limiting polygon parts to %d per feature and %d per request", partLimits.PerFeature, partLimits.PerRequest)
+	maxDecompressedBytes = int64(envInt("MAX_DECOMPRESSED_BYTES", 1<<30))
+	log.Printf("Limiting decompressed gzip payloads to %d bytes", maxDecompressedBytes)
+	
+	// sourceUrl is refused unless hosts are allowed explicitly, so clients cannot
+	// make the server reach internal services
+	sourceSchemes := "https"
+	if schemes := os.Getenv("SOURCE_URL_ALLOWED_SCHEMES"); schemes != "" {
+		sourceSchemes = schemes
+	}
+	sourceFetcher = utils.NewSourceFetcher(utils.NewURLAllowList(sourceSchemes, os.Getenv("SOURCE_URL_ALLOWED_HOSTS")),
+		int64(envInt("SOURCE_URL_MAX_BYTES", 100<<20)), time.Duration(envInt("SOURCE_URL_TIMEOUT_SECONDS", 60))*time.Second)
+	if sourceFetcher.Enabled() {
+		log.Printf("Fetching sourceUrl payloads over %s from %s", sourceSchemes, os.Getenv("SOURCE_URL_ALLOWED_HOSTS"))
+	}
+
+	destinationSchemes := "https"
+	if schemes := os.Getenv("DESTINATION_URL_ALLOWED_SCHEMES"); schemes != "" {
+		destinationSchemes = schemes
+	}
+	resultUploaders = utils.NewResultUploaders(utils.NewURLAllowList(destinationSchemes, os.Getenv("DESTINATION_URL_ALLOWED_HOSTS")),
+		time.Duration(envInt("DESTINATION_URL_TIMEOUT_SECONDS", 300))*time.Second)
+	if resultUploaders.Enabled() {
+		log.Printf("Uploading destinationUrl results over %s to %s", destinationSchemes, os.Getenv("DESTINATION_URL_ALLOWED_HOSTS"))
+	}
+
+	// An empty AUTH_TOKEN leaves every endpoint open
+	auth := utils.NewTokenAuth(os.Getenv("AUTH_TOKEN"))
+	if auth.Enabled() {
+		log.Printf("Bearer token authentication enabled")
+	}
+
 	// Register handlers
-	http.HandleFunc("/dissolve", dissolveHandler)
-	http.HandleFunc("/check-geometry", checkGeometryHandler)
+	http.HandleFunc("/dissolve", auth.Require(limiter.Limit(dissolveHandler)))
+	http.HandleFunc("/union", auth.Require(limiter.Limit(unionHandler)))
+	http.HandleFunc("/check-geometry", auth.Require(checkGeometryHandler))
+	http.HandleFunc("/truncate", auth.Require(truncateHandler))
 	// http.HandleFunc("/fix-geometry", fixGeometryHandler)
-	http.HandleFunc("/v2/fix-geometry", fixGeometryHandler2)
-	http.HandleFunc("/clean-topology", cleanTopologyHandler)
+	http.HandleFunc("/v2/fix-geometry", auth.Require(limiter.Limit(fixGeometryHandler2)))
+	http.HandleFunc("/fix", auth.Require(limiter.Limit(fixNDGeoJSONHandler)))
+	http.HandleFunc("/clean-topology", auth.Require(limiter.Limit(cleanTopologyHandler)))
+	http.HandleFunc("/centroid", auth.Require(centroidHandler))
+	http.HandleFunc("/min-bounding-circle", auth.Require(minBoundingCircleHandler))
+	http.HandleFunc("/oriented-bbox", auth.Require(orientedBBoxHandler))
+	http.HandleFunc("/concave-hull", auth.Require(limiter.Limit(concaveHullHandler)))
+	http.HandleFunc("/explode", auth.Require(explodeHandler))
+	http.HandleFunc("/collect", auth.Require(collectHandler))
+	http.HandleFunc("/split-multiparts", auth.Require(splitMultipartsHandler))
+	http.HandleFunc("/split", auth.Require(splitHandler))
+	http.HandleFunc("/compare", auth.Require(limiter.Limit(compareHandler)))
+	http.HandleFunc("/symmetric-difference", auth.Require(limiter.Limit(symmetricDifferenceHandler)))
+	http.HandleFunc("/noding/validate", auth.Require(limiter.Limit(nodingValidateHandler)))
+	http.HandleFunc("/close-gaps", auth.Require(limiter.Limit(closeGapsHandler)))
+	http.HandleFunc("/validate-coverage", auth.Require(limiter.Limit(validateCoverageHandler)))
+	http.HandleFunc("DELETE /references/{id}", auth.Require(deleteReferenceHandler))
+	// Unauthenticated so probes and deploy checks need no token
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/version", versionHandler)
 	
 	log.Printf("Registered all HTTP handlers")
 	
@@ -76,12 +204,80 @@ func main() {
 	log.Printf("Server is listening on port 8080...")
 	fmt.Println("Server is listening on port 8080...")
 	
-	err := http.ListenAndServe(":8080", nil)
+	// The default server has no timeouts, so a slow client could hold a connection
+	// forever. Writes get long enough for the heavy endpoints to finish.
+	server := &http.Server{
+		Addr:              ":8080",
+		ReadHeaderTimeout: time.Duration(envInt("READ_HEADER_TIMEOUT_SECONDS", 10)) * time.Second,
+		ReadTimeout:       time.Duration(envInt("READ_TIMEOUT_SECONDS", 300)) * time.Second,
+		WriteTimeout:      time.Duration(envInt("WRITE_TIMEOUT_SECONDS", 900)) * time.Second,
+		IdleTimeout:       time.Duration(envInt("IDLE_TIMEOUT_SECONDS", 120)) * time.Second,
+		MaxHeaderBytes:    envInt("MAX_HEADER_BYTES", 1<<20),
+	}
+	log.Printf("Server timeouts: read header %s, read %s, write %s, idle %s (max header bytes %d)",
+		server.ReadHeaderTimeout, server.ReadTimeout, server.WriteTimeout, server.IdleTimeout, server.MaxHeaderBytes)
+
+	err := server.ListenAndServe()
 	if err != nil {
 		log.Fatalf("Server failed to start: %v", err)
 	}
 }
 
+// checkCRS rejects, with a 400, payloads whose legacy crs member is not WGS84.
+// It reports whether the request may proceed.
+func checkCRS(w http.ResponseWriter, geometryPayload string) bool {
+	if err := utils.CheckCRS(geometryPayload); err != nil {
+		log.Printf("Rejecting request: %v", err)
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// checkPartLimits rejects payloads with more polygon parts than partLimits
+// allow: 413 when the request as a whole is too large, 422 when a single
+// feature is. It reports whether the request may proceed.
+func checkPartLimits(w http.ResponseWriter, geometryPayload string) bool {
+	err := utils.CheckPartLimits(geometryPayload, partLimits)
+	if err == nil {
+		return true
+	}
+
+	log.Printf("Rejecting request: %v", err)
+	status := http.StatusUnprocessableEntity
+	var partLimitErr *utils.PartLimitError
+	if errors.As(err, &partLimitErr) && partLimitErr.PerRequest() {
+		status = http.StatusRequestEntityTooLarge
+	}
+	http.Error(w, fmt.Sprintf("ERROR: %v", err), status)
+	return false
+}
+
+// readErrorStatus is the status for a payload that could not be read: 413 when
+// it decompressed past maxDecompressedBytes, 400 otherwise
+func readErrorStatus(err error) int {
+	var sizeErr *utils.DecompressedSizeError
+	if errors.As(err, &sizeErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+// envInt reads an integer setting from the environment, falling back to defaultValue
+func envInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid value %q for %s, using default %d", value, key, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
 func readBody(w http.ResponseWriter, r *http.Request) string {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Invalid request method, only POST allowed", http.StatusMethodNotAllowed)
@@ -99,62 +295,338 @@ func readBody(w http.ResponseWriter, r *http.Request) string {
 	return string(body)
 }
 
+// readGeometryPayload reads GeoJSON from a direct JSON body or a multipart form
+// upload, featureCollection value or a filepath inside inputDir, rejecting payloads
+// whose legacy crs member is not WGS84
+func readGeometryPayload(r *http.Request) (string, error) {
+	geometryPayload, err := readRawGeometryPayload(r)
+	if err != nil {
+		return "", err
+	}
+	if err := utils.CheckCRS(geometryPayload); err != nil {
+		return "", err
+	}
+	return geometryPayload, nil
+}
+
+func readRawGeometryPayload(r *http.Request) (string, error) {
+	if r.Method != http.MethodPost {
+		return "", fmt.Errorf("invalid request method, only POST allowed")
+	}
+	if err := utils.DecompressRequestBody(r, maxDecompressedBytes); err != nil {
+		return "", err
+	}
+
+	if strings.Contains(r.Header.Get("Content-Type"), "application/json") {
+		body, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("error reading request body: %w", err)
+		}
+		if len(body) == 0 {
+			return "", fmt.Errorf("empty request body")
+		}
+		return string(body), nil
+	}
+
+	multiPartRequest := utils.ReadMultiPartForm(r, "file", maxDecompressedBytes)
+	if multiPartRequest.Err != nil {
+		return "", multiPartRequest.Err
+	}
+	if multiPartRequest.File != "" {
+		return multiPartRequest.File, nil
+	}
+	if multiPartRequest.Properties.FeatureCollection != "" {
+		return multiPartRequest.Properties.FeatureCollection, nil
+	}
+	if multiPartRequest.Properties.FilePath != "" {
+		return readInputFile(multiPartRequest.Properties.FilePath)
+	}
+
+	return "", fmt.Errorf("no suitable files found")
+}
+
+// readSourceURL fetches the payload named by the sourceUrl option, or returns
+// an empty payload when the request names none. Multipart requests must have
+// been parsed already for a form value to be seen.
+func readSourceURL(r *http.Request) (string, error) {
+	sourceURL := utils.ReadRequestOptions(r).String("sourceUrl", "")
+	if sourceURL == "" {
+		return "", nil
+	}
+
+	// Signed links carry credentials, so the URL itself is not logged
+	log.Printf("Fetching payload from sourceUrl")
+	return sourceFetcher.Fetch(r.Context(), sourceURL)
+}
+
+// UploadReceipt is the response to a request whose result was uploaded to its destinationUrl
+type UploadReceipt struct {
+	Location    string `json:"location"`
+	ContentType string `json:"contentType"`
+	Bytes       int    `json:"bytes"`
+	Features    int    `json:"features,omitempty"`
+}
+
+// checkDestinationURL rejects a request whose destinationUrl cannot be
+// uploaded to, before any processing, writing a 400 and returning false
+func checkDestinationURL(w http.ResponseWriter, r *http.Request) bool {
+	destination := utils.ReadRequestOptions(r).String("destinationUrl", "")
+	if destination == "" {
+		return true
+	}
+	if _, err := resultUploaders.CheckDestination(destination); err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+// uploadResult uploads data to the request's destinationUrl, if it names one,
+// and responds with an UploadReceipt instead of the data. It reports whether
+// the response has been written. featureCount is left out of the receipt when zero.
+func uploadResult(w http.ResponseWriter, r *http.Request, contentType string, data []byte, featureCount int) bool {
+	destination := utils.ReadRequestOptions(r).String("destinationUrl", "")
+	if destination == "" {
+		return false
+	}
+
+	location, err := resultUploaders.Upload(r.Context(), destination, contentType, data)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadGateway)
+		return true
+	}
+	log.Printf("Uploaded %d bytes to %s", len(data), location)
+
+	jsonReceipt, err := json.Marshal(UploadReceipt{Location: location, ContentType: contentType, Bytes: len(data), Features: featureCount})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+		return true
+	}
+	sendResponse(w, jsonReceipt)
+	return true
+}
+
+// sendFeatureCollection writes features as a GeoJSON FeatureCollection response
+func sendFeatureCollection(w http.ResponseWriter, features []handlers.Feature) {
+	jsonFC, err := json.Marshal(handlers.NewFeatureCollection(features))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sendResponse(w, jsonFC)
+}
+
+// fixSettings holds the repair options shared by the fix endpoints
+type fixSettings struct {
+	precision    int
+	removeSpikes bool
+	spikeAngle   float64
+}
+
+// fixFeature repairs a single feature for the fix endpoints: rings are
+// closed, spikes optionally removed, invalid geometries made valid and
+// coordinates truncated to the requested precision. Repairs cascade through
+// MakeValid's linework and structure methods and buffer(0), recording the one
+// that worked in RepairMethodProperty; a geometry none of them make valid is
+// passed through flagged as unfixed. It reports false for a
+// feature without a polygonal result, such as a null geometry, and returns
+// an error for mis-nested coordinates, which cannot be repaired.
+func fixFeature(feature Feature, index int, settings fixSettings) (GeomFeature, bool, error) {
+	if utils.IsNullGeometry(feature.Geometry) {
+		log.Printf("Skipping null geometry at feature %d", index)
+		return GeomFeature{}, false, nil
+	}
+
+	// Mis-nested coordinates cannot be repaired, only explained
+	if err := utils.CheckCoordinateNesting(feature.Geometry); err != nil {
+		return GeomFeature{}, false, err
+	}
+
+	geometry, ringsClosed, closeErr := utils.CloseRings(feature.Geometry)
+	if closeErr != nil {
+		geometry = feature.Geometry
+	} else if ringsClosed {
+		log.Printf("Closed open ring(s) at feature %d", index)
+	}
+	jsonString, _ := json.Marshal(geometry)
+	geo, _ := geos.NewGeomFromGeoJSON(string(jsonString))
+	if geo == nil {
+		return GeomFeature{}, false, nil
+	}
+
+	// Spikes survive MakeValid, so they are removed before repair
+	if settings.removeSpikes {
+		if despiked, removed := utils.RemoveSpikes(geo, settings.spikeAngle); despiked != nil && removed > 0 {
+			log.Printf("Removed %d spike vertices at feature %d", removed, index)
+			geo.Destroy()
+			geo = despiked
+		} else if despiked != nil {
+			despiked.Destroy()
+		}
+	}
+
+	properties := feature.Properties
+	if !geo.IsValid() {
+		reason := geo.IsValidReason()
+		fmt.Println(feature.Properties["PC6"], reason)
+		repaired, method := handlers.RepairGeometry(geo, index, handlers.RepairMethodMakeValid)
+		if repaired == nil {
+			// Flagged and passed through unchanged rather than dropped
+			properties = handlers.UnfixedProperties(properties, fmt.Sprintf("no repair method made the geometry valid: %s", reason))
+			return fixedPolygon(geo, properties)
+		}
+		geo.Destroy()
+		geo = repaired
+
+		properties = withProperty(properties, handlers.RepairMethodProperty, method)
+		if location, ok := utils.InvalidityLocation(reason); ok {
+			properties[handlers.RepairLocationProperty] = location
+		}
+	}
+
+	truncated, err := utils.TruncateFullGeometry(geo, settings.precision)
+	if err != nil {
+		log.Printf("Failed to truncate feature %d: %v", index, err)
+	}
+	geo.Destroy()
+
+	// Truncation fails when no valid polygon is left
+	if truncated == nil {
+		return GeomFeature{}, false, nil
+	}
+	geo = truncated
+
+	if !geo.IsValid() {
+		// fmt.Println(feature.Properties["PC6"], "after trunc", geo.IsValidReason())
+		repaired, method := handlers.RepairGeometry(geo, index, handlers.RepairMethodMakeValid)
+		if repaired == nil {
+			properties = handlers.UnfixedProperties(properties, fmt.Sprintf("no repair method made the truncated geometry valid: %s", geo.IsValidReason()))
+			return fixedPolygon(geo, properties)
+		}
+		geo.Destroy()
+		geo = repaired
+		if _, repairedBefore := properties[handlers.RepairMethodProperty]; !repairedBefore {
+			properties = withProperty(properties, handlers.RepairMethodProperty, method)
+		}
+	}
+
+	return fixedPolygon(geo, properties)
+}
+
+// withProperty returns a copy of properties with key set to value
+func withProperty(properties map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(properties)+1)
+	maps.Copy(copied, properties)
+	copied[key] = value
+	return copied
+}
+
+// fixedPolygon keeps geo if it is a Polygon or MultiPolygon, destroying it otherwise
+func fixedPolygon(geo *geos.Geom, properties map[string]interface{}) (GeomFeature, bool, error) {
+	if geo.TypeID() == 6 || geo.TypeID() == 3 {
+		return GeomFeature{Geom: geo, Properties: properties}, true, nil
+	}
+	geo.Destroy()
+	return GeomFeature{}, false, nil
+}
+
+// fixedFeature encodes a repaired geometry as an output feature, rounded to
+// outputPrecision decimals unless it is negative
+func fixedFeature(geomFeature GeomFeature, outputPrecision int, includeBBox bool) Feature {
+	geometry := json.RawMessage(geomFeature.Geom.ToGeoJSON(-1))
+	if outputPrecision >= 0 {
+		if rounded, err := utils.RoundGeoJSONCoordinates(geometry, outputPrecision); err == nil {
+			geometry = rounded
+		}
+	}
+
+	feature := Feature{
+		Type:       "Feature",
+		Properties: geomFeature.Properties,
+		Geometry:   geometry,
+	}
+	if includeBBox {
+		feature.BBox = utils.BBox(geomFeature.Geom, outputPrecision)
+	}
+	return feature
+}
+
 func fixGeometryHandler2(w http.ResponseWriter, r *http.Request) {
-	multiPartRequest := utils.ReadMultiPartForm(r, "file")
+	if err := utils.DecompressRequestBody(r, maxDecompressedBytes); err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+	multiPartRequest := utils.ReadMultiPartForm(r, "file", maxDecompressedBytes)
+	if multiPartRequest.Err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", multiPartRequest.Err), http.StatusRequestEntityTooLarge)
+		return
+	}
 	var geometryPayload string
 	fmt.Print("Request Received.")
 
-	if multiPartRequest.File == "" {
+	sourcePayload, err := readSourceURL(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+	if sourcePayload != "" {
+		geometryPayload = sourcePayload
+	} else if multiPartRequest.File == "" {
 		if multiPartRequest.Properties.FeatureCollection != "" {
 			geometryPayload = multiPartRequest.Properties.FeatureCollection
 		} else if multiPartRequest.Properties.FilePath != "" {
-			geometryPayload = readFile(multiPartRequest.Properties)
+			filePayload, err := readInputFile(multiPartRequest.Properties.FilePath)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+				return
+			}
+			geometryPayload = filePayload
 		} else {
-			sendResponse(w, []byte("ERROR: No suitable files found"))
+			http.Error(w, "ERROR: No suitable files found", http.StatusBadRequest)
+			return
 		}
 	} else {
 		fmt.Println("Reading from payload")
 		geometryPayload = multiPartRequest.File
 	}
+	if !checkCRS(w, geometryPayload) || !checkPartLimits(w, geometryPayload) {
+		return
+	}
+
+	if !checkDestinationURL(w, r) {
+		return
+	}
+
+	options := utils.ReadRequestOptions(r)
+	precision := requestPrecision(options)
+	removeSpikes := options.Bool("removeSpikes", false)
+	spikeAngle := requestSpikeAngle(options)
+
+	// Dry run: report what is invalid and why without fixing anything
+	if options.Bool("dryRun", false) {
+		features, err := handlers.ParseFeatureCollection(geometryPayload)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+			return
+		}
+		jsonSummary, _ := json.Marshal(handlers.SummarizeValidity(features))
+		sendResponse(w, jsonSummary)
+		return
+	}
 
 	var featureCollection FeatureCollection
 	var geomFeatures []GeomFeature
 	json.Unmarshal([]byte(geometryPayload), &featureCollection)
-	var err error
 
+	settings := fixSettings{precision: precision, removeSpikes: removeSpikes, spikeAngle: spikeAngle}
 	for i := range len(featureCollection.Features) {
-		jsonString, _ := json.Marshal(featureCollection.Features[i].Geometry)
-		geo, _ := geos.NewGeomFromGeoJSON(string(jsonString))
-
-		if !geo.IsValid() {
-			fmt.Println(featureCollection.Features[i].Properties["PC6"], geo.IsValidReason())
-			geo = geo.MakeValidWithParams(geos.MakeValidLinework, geos.MakeValidDiscardCollapsed)
-			geo, err = utils.TruncateFullGeometry(geo)
-
-			if err != nil {
-				fmt.Println("ERROR Trunc", featureCollection.Features[i].Properties["PC6"])
-			}
-		} else {
-			geo, err = utils.TruncateFullGeometry(geo)
-			if err != nil {
-				fmt.Println("ERROR Trunc", featureCollection.Features[i].Properties["PC6"])
-			}
-		}
-
-		if !geo.IsValid() {
-			// fmt.Println(featureCollection.Features[i].Properties["PC6"], "after trunc", geo.IsValidReason())
-			geo = geo.MakeValidWithParams(geos.MakeValidLinework, geos.MakeValidDiscardCollapsed)
-			if err != nil {
-				fmt.Println("ERROR Trunc", featureCollection.Features[i].Properties["PC6"])
-			}
+		geomFeature, ok, err := fixFeature(featureCollection.Features[i], i, settings)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ERROR: feature %d: %v", i, err), http.StatusBadRequest)
+			return
 		}
-
-		if geo.TypeID() == 6 || geo.TypeID() == 3 {
-			geomFeature := GeomFeature{
-				Geom:       geo,
-				Properties: featureCollection.Features[i].Properties,
-			}
-
+		if ok {
 			geomFeatures = append(geomFeatures, geomFeature)
 		}
 	}
@@ -162,23 +634,31 @@ func fixGeometryHandler2(w http.ResponseWriter, r *http.Request) {
 		Features: make([]Feature, 0),
 		Type:     "FeatureCollection",
 	}
+	outputPrecision := requestOutputPrecision(options)
+	includeBBox := options.Bool("includeBBox", false)
+	includeFeatureBBox := options.Bool("includeFeatureBBox", false)
+	keepProperties := options.List("keepProperties")
 	for i := range len(geomFeatures) {
 		geomFeature := geomFeatures[i]
+		geomFeature.Properties = handlers.SelectProperties(geomFeature.Properties, keepProperties)
 
-		jsonString := geomFeature.Geom.ToGeoJSON(-1)
-
-		feature := Feature{
-			Type:       "Feature",
-			Properties: geomFeature.Properties,
-			Geometry:   json.RawMessage(jsonString),
+		feature := fixedFeature(geomFeature, outputPrecision, includeFeatureBBox)
+		if includeBBox {
+			finalFeatureCollection.BBox = utils.MergeBBox(finalFeatureCollection.BBox, utils.BBox(geomFeature.Geom, outputPrecision))
 		}
 
 		finalFeatureCollection.Features = append(finalFeatureCollection.Features, feature)
 	}
 	jsonFC, _ := json.Marshal(finalFeatureCollection)
 
+	if uploadResult(w, r, "application/geo+json", jsonFC, len(finalFeatureCollection.Features)) {
+		return
+	}
 	if multiPartRequest.Properties.SaveFile {
-		saveFile(multiPartRequest.Properties.FilePath, string(jsonFC))
+		if err := saveFile(multiPartRequest.Properties.FilePath, string(jsonFC)); err != nil {
+			http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+			return
+		}
 		sendResponse(w, []byte("File Saved"))
 	} else {
 		fmt.Println("Done. Sending Response")
@@ -218,167 +698,1160 @@ func fixGeometryHandler2(w http.ResponseWriter, r *http.Request) {
 // 	// truncatedFeature.Destroy()
 // }
 
-func saveFile(filePath string, jsonString string) {
-	name := strings.Replace(filePath, ".json", "", 1)
-	name = strings.Replace(name, "files", "output", 1)
-	filename := name + "_PROCESSED.json"
+// saveFile writes processed GeoJSON under outputDir, mirroring the client-supplied input path
+func saveFile(filePath string, jsonString string) error {
+	return writeOutputFile(filePath, "_PROCESSED.json", []byte(jsonString))
+}
 
-	err := os.WriteFile(filename, []byte(jsonString), 0644)
+// readInputFile reads a client-supplied filepath, which must lie inside inputDir
+func readInputFile(filePath string) (string, error) {
+	filename, err := utils.ResolveInputPath(inputDir, filePath)
 	if err != nil {
-		fmt.Println("Error saving JSON file:", err)
-		return
+		log.Printf("Rejected input path %q: %v", filePath, err)
+		return "", err
 	}
 
-	fmt.Println("JSON saved to", filename)
-}
-
-func readFile(filePath utils.Properties) string {
-	file, _ := os.ReadFile(filePath.FilePath)
-
-	return string(file)
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	return string(file), nil
 }
 
 func dissolveHandler(w http.ResponseWriter, r *http.Request) {
 	// Assume geo1 is your GeometryCollection
-	geo1, err := geos.NewGeomFromGeoJSON(readBody(w, r))
+	geometryPayload := readBody(w, r)
+	if !checkCRS(w, geometryPayload) || !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	parsed, err := geos.NewGeomFromGeoJSON(geometryPayload)
 	if err != nil {
-		log.Fatalf("Error creating geom")
+		http.Error(w, fmt.Sprintf("ERROR: failed to parse geometry: %v", err), http.StatusBadRequest)
+		return
 	}
+	defer parsed.Destroy()
+	fmt.Println("isSimple", parsed.IsValidReason())
+	geo1 := parsed.MakeValid()
+	defer geo1.Destroy()
+
+	// Sub-geometries belong to geo1, but each buffer is a new geometry that
+	// UnionGeometries takes ownership of
 	numGeometries := geo1.NumGeometries()
 	geometries := make([]*geos.Geom, numGeometries)
-	fmt.Println("isSimple", geo1.IsValidReason())
-	geo1 = geo1.MakeValid()
 	for i := 0; i < numGeometries; i++ {
 		geometries[i] = geo1.Geometry(i).Buffer(0, 0)
 	}
 
-	// Use the cascaded union approach
-	finalUnion, err := handlers.CascadedUnion(geometries)
-
-	if finalUnion == nil {
-		log.Fatalf("Error can't find final Union")
-	}
-
+	// method=cascaded keeps the original pairwise union; UnaryUnion is the default
+	options := utils.ReadRequestOptions(r)
+	finalUnion, err := handlers.UnionGeometries(geometries, options.String("method", handlers.UnionMethodUnary))
 	if err != nil {
-		log.Fatalf("Error performing cascaded union: %v", err)
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
 	}
+	defer finalUnion.Destroy()
 
-	truncatedFeature, _ := utils.TruncateFullGeometry(finalUnion)
-
+	precision := requestPrecision(options)
+	truncatedFeature, err := utils.TruncateFullGeometry(finalUnion, precision)
 	if err != nil {
-		fmt.Println("error", err)
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
 	}
+	defer truncatedFeature.Destroy()
+
 	// Use finalUnion as needed
 	fmt.Println("Union complete", truncatedFeature.IsValidReason())
-	jsonFeature := truncatedFeature.MakeValidWithParams(geos.MakeValidStructure, geos.MakeValidDiscardCollapsed).ToGeoJSON(-1)
-	sendResponse(w, []byte(jsonFeature))
-	finalUnion.Destroy()
-}
-
-func checkGeometryHandler(w http.ResponseWriter, r *http.Request) {
-	geo1, err := geos.NewGeomFromGeoJSON(readBody(w, r))
-	if err != nil {
-		log.Fatalf("Error creating geom")
+	validUnion := truncatedFeature.MakeValidWithParams(geos.MakeValidStructure, geos.MakeValidDiscardCollapsed)
+	if validUnion == nil {
+		http.Error(w, "ERROR: failed to make dissolved geometry valid", http.StatusInternalServerError)
+		return
 	}
-	errors := handlers.CheckGeometry(geo1)
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(errors)
-}
+	defer validUnion.Destroy()
 
-func cleanTopologyHandler(w http.ResponseWriter, r *http.Request) {
-	// Add panic recovery to prevent server crashes
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("PANIC recovered in cleanTopologyHandler: %v", r)
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-		}
-	}()
-	
-	log.Printf("=== Topology cleaning request received ===")
-	log.Printf("Content-Type: %s", r.Header.Get("Content-Type"))
-	
-	var geometryPayload string
-	
-	// Check if this is a direct JSON request or multipart form
-	contentType := r.Header.Get("Content-Type")
-	if strings.Contains(contentType, "application/json") {
-		// Handle direct JSON request
-		log.Printf("Handling direct JSON request")
-		geometryPayload = readBody(w, r)
-		if geometryPayload == "" {
-			sendResponse(w, []byte("ERROR: Empty request body"))
+	// Collapse into one MultiPolygon feature, e.g. for choropleth rendering
+	if options.Bool("flatten", false) {
+		properties, err := requestProperties(options)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
 			return
 		}
-	} else {
-		// Handle multipart form request
-		log.Printf("Handling multipart form request")
-		multiPartRequest := utils.ReadMultiPartForm(r, "file")
-		
-		if multiPartRequest.File == "" {
-			if multiPartRequest.Properties.FeatureCollection != "" {
-				geometryPayload = multiPartRequest.Properties.FeatureCollection
-			} else if multiPartRequest.Properties.FilePath != "" {
-				geometryPayload = readFile(multiPartRequest.Properties)
-			} else {
-				sendResponse(w, []byte("ERROR: No suitable files found"))
-				return
-			}
-		} else {
-			log.Printf("Reading from uploaded file")
-			geometryPayload = multiPartRequest.File
+
+		merged := handlers.MergeToMultiPolygon(validUnion)
+		if options.Bool("includeCentroid", false) {
+			properties = withCentroid(properties, merged)
 		}
+		jsonFeature, _ := json.Marshal(handlers.NewMergedFeature(merged, properties))
+		merged.Destroy()
+		sendResponse(w, jsonFeature)
+		return
 	}
 
-	// Check if shapefile format is requested (you can add a parameter for this)
-	// For now, always generate zip with both formats
-	zipData, err := handlers.CleanTopologyWithShapefile(geometryPayload)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("ERROR: Topology cleaning failed: %v", err), http.StatusInternalServerError)
+	// A bare geometry has nowhere to carry the centroid, so wrap it in a feature
+	if options.Bool("includeCentroid", false) {
+		jsonFeature, _ := json.Marshal(handlers.NewMergedFeature(validUnion, withCentroid(nil, validUnion)))
+		sendResponse(w, jsonFeature)
 		return
 	}
 
-	// For direct JSON requests, always return the zip response
-	// For multipart requests, check if file saving is requested
-	if strings.Contains(contentType, "application/json") {
-		log.Printf("Topology cleaning complete. Sending zip response")
-		sendZipResponse(w, zipData)
-	} else {
-		// This is a multipart form request, check if saving is requested
-		multiPartRequest := utils.ReadMultiPartForm(r, "file")
-		if multiPartRequest.Properties.SaveFile {
-			saveZipFile(multiPartRequest.Properties.FilePath, zipData)
-			sendResponse(w, []byte("Topology cleaned and zip file saved"))
-		} else {
-			log.Printf("Topology cleaning complete. Sending zip response")
-			sendZipResponse(w, zipData)
-		}
+	jsonFeature := validUnion.ToGeoJSON(-1)
+	sendResponse(w, []byte(jsonFeature))
+}
+
+// withCentroid sets the centroid of a dissolved geometry on properties,
+// allocating them when nil
+func withCentroid(properties map[string]interface{}, geom *geos.Geom) map[string]interface{} {
+	if properties == nil {
+		properties = make(map[string]interface{})
 	}
+	if centroid, ok := handlers.CentroidPoint(geom); ok {
+		properties[handlers.CentroidProperty] = centroid
+	}
+	return properties
 }
 
-func sendResponse(w http.ResponseWriter, response []byte) {
+// unionHandler returns the union of every feature geometry as a single feature,
+// without the repair and truncation steps dissolve applies
+func unionHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	properties, err := requestProperties(options)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	union, err := handlers.UnionFeatures(features, options.String("method", handlers.UnionMethodUnary), properties)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	jsonFeature, err := json.Marshal(union)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sendResponse(w, jsonFeature)
+}
+
+// checkGeometryHandler reports invalid parts of a geometry sent as GeoJSON, WKT
+// or WKB (raw or hex), chosen by the format option or the Content-Type
+func checkGeometryHandler(w http.ResponseWriter, r *http.Request) {
+	options := utils.ReadRequestOptions(r)
+	format, err := utils.GeometryFormat(options.String("format", ""), r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	geo1, err := utils.ParseGeometry([]byte(readBody(w, r)), format)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to parse %s geometry: %v", format, err), http.StatusBadRequest)
+		return
+	}
+	defer geo1.Destroy()
+	errors := handlers.CheckGeometry(geo1)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	// The bare errors array stays the default response for existing clients
+	if options.Bool("summary", false) {
+		if errors == nil {
+			errors = make([]handlers.Error, 0)
+		}
+		json.NewEncoder(w).Encode(handlers.CheckResult{Errors: errors, Summary: handlers.SummarizeGeometry(geo1)})
+		return
+	}
+	json.NewEncoder(w).Encode(errors)
+}
+
+// truncateHandler rounds the X and Y of a geometry to the precision option,
+// keeping any Z and M ordinates. The response uses the request's format, so
+// measured linework sent as WKT or WKB comes back with its M values; GeoJSON
+// has no M, and GEOS reads and writes M only from 3.12.
+func truncateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Invalid request method, only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := utils.DecompressRequestBody(r, maxDecompressedBytes); err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+
+	options := utils.ReadRequestOptions(r)
+	format, err := utils.GeometryFormat(options.String("format", ""), r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+	geom, err := utils.ParseGeometry(body, format)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to parse %s geometry: %v", format, err), http.StatusBadRequest)
+		return
+	}
+	defer geom.Destroy()
+
+	truncated, err := utils.TruncateXY(geom, requestPrecision(options))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusUnprocessableEntity)
+		return
+	}
+	defer truncated.Destroy()
+
+	w.Header().Set("Content-Type", utils.GeometryContentType(format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(utils.FormatGeometry(truncated, format))
+}
+
+func centroidHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	points, err := handlers.LabelPoints(features, options.String("method", handlers.LabelMethodPointOnSurface))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sendFeatureCollection(w, points)
+}
+
+func minBoundingCircleHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	// output=parameters returns {centerX, centerY, radiusM} instead of circle polygons
+	if options.String("output", "polygon") == "parameters" {
+		jsonCircles, err := json.Marshal(handlers.MinimumBoundingCircles(features))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+			return
+		}
+		sendResponse(w, jsonCircles)
+		return
+	}
+
+	sendFeatureCollection(w, handlers.BoundingCirclePolygons(features, requestQuadSegs(options, 16)))
+}
+
+func orientedBBoxHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sendFeatureCollection(w, handlers.OrientedBoundingBoxes(features))
+}
+
+func explodeHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sendFeatureCollection(w, handlers.ExplodeFeatures(features))
+}
+
+func collectHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	collected, err := handlers.CollectFeatures(features, options.String("by", ""), options.Bool("includeCentroid", false))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sendFeatureCollection(w, collected)
+}
+
+func splitMultipartsHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := handlers.SplitMultipartFeatures(features, options.Float("separationM", handlers.DefaultMultipartSeparationMeters))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sendResponse(w, jsonResult)
+}
+
+func concaveHullHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	hull, err := handlers.ConcaveHull(features, options.Float("ratio", 0.3), options.Bool("allowHoles", false))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer hull.Destroy()
+
+	sendResponse(w, []byte(hull.ToGeoJSON(-1)))
+}
+
+func splitHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	pieces, err := handlers.SplitPolygon(features)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sendFeatureCollection(w, pieces)
+}
+
+func symmetricDifferenceHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+
+	layers, err := handlers.ParseLayers(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(layers) != 2 {
+		http.Error(w, "ERROR: expected exactly two layers", http.StatusBadRequest)
+		return
+	}
+
+	features, err := handlers.SymmetricDifference(layers[0], layers[1])
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sendFeatureCollection(w, features)
+}
+
+func nodingValidateHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	sendFeatureCollection(w, handlers.ValidateNoding(features))
+}
+
+func compareHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+
+	// layers[0] is the reference, layers[1] the layer being compared against it
+	layers, err := handlers.ParseLayers(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var report *handlers.ComparisonReport
+	if referenceID := options.String("referenceId", ""); referenceID != "" {
+		// A cached reference lets the client send only the candidate layer, while
+		// a reference sent alongside it replaces the cached one so it is never stale
+		var reference *handlers.ReferenceLayer
+		switch len(layers) {
+		case 2:
+			reference = referenceCache.Store(referenceID, layers[0])
+		case 1:
+			var cached bool
+			if reference, cached = referenceCache.Acquire(referenceID); !cached {
+				http.Error(w, fmt.Sprintf("ERROR: reference %q is not cached; expected exactly two layers (reference, candidate)", referenceID), http.StatusBadRequest)
+				return
+			}
+		default:
+			http.Error(w, "ERROR: expected a candidate layer, optionally preceded by the reference", http.StatusBadRequest)
+			return
+		}
+		defer referenceCache.Release(reference)
+		report, err = handlers.CompareWithReference(reference, layers[len(layers)-1], options.String("key", ""))
+	} else {
+		if len(layers) != 2 {
+			http.Error(w, "ERROR: expected exactly two layers (reference, candidate)", http.StatusBadRequest)
+			return
+		}
+		report, err = handlers.CompareLayers(layers[0], layers[1], options.String("key", ""))
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	jsonReport, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sendResponse(w, jsonReport)
+}
+
+// buildVersionInfo reports the ldflags build information, falling back to what
+// the Go toolchain embedded (module version and VCS stamp) for unset values
+func buildVersionInfo() VersionInfo {
+	info := VersionInfo{
+		Version:     version,
+		Commit:      commit,
+		BuildDate:   buildDate,
+		GoVersion:   runtime.Version(),
+		GEOSVersion: fmt.Sprintf("%d.%d.%d", geos.VersionMajor, geos.VersionMinor, geos.VersionPatch),
+	}
+
+	if buildInfo, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" {
+			info.Version = buildInfo.Main.Version
+		}
+		for _, setting := range buildInfo.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+
+	return info
+}
+
+// fixNDGeoJSONHandler fixes newline-delimited GeoJSON, one Feature per line,
+// and streams the fixed features back one per line in input order. Lines are
+// fixed in parallel by a bounded pool and each is written once every earlier
+// line is done, so neither the request nor the response is held in memory.
+// Blank lines are ignored and, as in /v2/fix-geometry, features without a
+// polygonal result are dropped. The status is sent before the first line is
+// read, so a line that cannot be decoded or repaired is answered in place by a
+// feature with a null geometry and _line and _error properties.
+func fixNDGeoJSONHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "ERROR: invalid request method, only POST allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := utils.DecompressRequestBody(r, maxDecompressedBytes); err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	options := utils.ReadRequestOptions(r)
+	settings := fixSettings{
+		precision:    requestPrecision(options),
+		removeSpikes: options.Bool("removeSpikes", false),
+		spikeAngle:   requestSpikeAngle(options),
+	}
+	outputPrecision := requestOutputPrecision(options)
+	includeFeatureBBox := options.Bool("includeFeatureBBox", false)
+
+	type ndLine struct {
+		number int
+		data   []byte
+		result chan []byte
+	}
+	workers := runtime.NumCPU()
+	jobs := make(chan ndLine)
+	// Lines in input order awaiting output; its buffer bounds the lines in flight
+	pending := make(chan ndLine, workers*4)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range jobs {
+				line.result <- fixNDGeoJSONLine(line.number, line.data, settings, outputPrecision, includeFeatureBBox)
+			}
+		}()
+	}
+
+	var readErr error
+	var readErrLine int
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		reader := bufio.NewReader(r.Body)
+		for number := 1; ; number++ {
+			data, err := reader.ReadBytes('\n')
+			if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 {
+				line := ndLine{number: number, data: trimmed, result: make(chan []byte, 1)}
+				pending <- line
+				jobs <- line
+			}
+			if err != nil {
+				if err != io.EOF {
+					readErr, readErrLine = err, number
+				}
+				return
+			}
+		}
+	}()
+
+	// HTTP/1 would otherwise drain the whole request body before the first write
+	controller := http.NewResponseController(w)
+	if err := controller.EnableFullDuplex(); err != nil {
+		log.Printf("Could not enable full duplex for NDGeoJSON streaming: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	out := bufio.NewWriter(w)
+	written := 0
+	for line := range pending {
+		if result := <-line.result; result != nil {
+			out.Write(result)
+			out.WriteByte('\n')
+			written++
+		}
+		// Push out what is ready instead of waiting for the buffer to fill
+		if len(pending) == 0 {
+			out.Flush()
+			controller.Flush()
+		}
+	}
+	out.Flush()
+	wg.Wait()
+
+	// The status is long sent, so a body cut short, for instance by the
+	// decompression cap, is reported in a last error line
+	if readErr != nil {
+		log.Printf("NDGeoJSON request ended early: %v", readErr)
+		out.Write(ndGeoJSONErrorLine(readErrLine, nil, readErr))
+		out.WriteByte('\n')
+		out.Flush()
+	}
+	log.Printf("NDGeoJSON fix complete. Wrote %d features", written)
+}
+
+// fixNDGeoJSONLine fixes the feature on one NDGeoJSON line and returns its
+// output line, an error feature if it cannot be fixed, or nil if it is dropped
+func fixNDGeoJSONLine(number int, data []byte, settings fixSettings, outputPrecision int, includeFeatureBBox bool) (output []byte) {
+	var feature Feature
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("PANIC recovered fixing NDGeoJSON line %d: %v", number, r)
+			output = ndGeoJSONErrorLine(number, feature.Properties, fmt.Errorf("internal error"))
+		}
+	}()
+
+	if err := json.Unmarshal(data, &feature); err != nil {
+		return ndGeoJSONErrorLine(number, nil, fmt.Errorf("invalid JSON: %v", err))
+	}
+	if feature.Type != "Feature" {
+		return ndGeoJSONErrorLine(number, nil, fmt.Errorf("expected a Feature, got %q", feature.Type))
+	}
+
+	geomFeature, ok, err := fixFeature(feature, number, settings)
+	if err != nil {
+		return ndGeoJSONErrorLine(number, feature.Properties, err)
+	}
+	if !ok {
+		return nil
+	}
+	defer geomFeature.Geom.Destroy()
+
+	output, err = json.Marshal(fixedFeature(geomFeature, outputPrecision, includeFeatureBBox))
+	if err != nil {
+		return ndGeoJSONErrorLine(number, feature.Properties, err)
+	}
+	return output
+}
+
+// ndGeoJSONErrorLine reports a line that could not be fixed as a feature with a
+// null geometry, keeping its properties when they could be read
+func ndGeoJSONErrorLine(number int, properties map[string]interface{}, err error) []byte {
+	flagged := make(map[string]interface{}, len(properties)+2)
+	maps.Copy(flagged, properties)
+	flagged["_line"] = number
+	flagged["_error"] = err.Error()
+
+	output, _ := json.Marshal(Feature{Type: "Feature", Geometry: json.RawMessage("null"), Properties: flagged})
+	return output
+}
+
+// healthzHandler reports that the server is up
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	sendResponse(w, []byte(`{"status":"ok"}`))
+}
+
+func versionHandler(w http.ResponseWriter, r *http.Request) {
+	jsonInfo, err := json.Marshal(buildVersionInfo())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sendResponse(w, jsonInfo)
+}
+
+func deleteReferenceHandler(w http.ResponseWriter, r *http.Request) {
+	referenceID := r.PathValue("id")
+	if !referenceCache.Delete(referenceID) {
+		http.Error(w, fmt.Sprintf("ERROR: reference %q is not cached", referenceID), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func closeGapsHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	features, err := handlers.ParseFeatureCollection(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	result, err := handlers.CloseGaps(features, options.Float("toleranceMeters", handlers.DefaultSnapToleranceMeters), requestQuadSegs(options, utils.DefaultQuadSegs))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	jsonResult, err := json.Marshal(result)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sendResponse(w, jsonResult)
+}
+
+func validateCoverageHandler(w http.ResponseWriter, r *http.Request) {
+	geometryPayload, err := readGeometryPayload(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), readErrorStatus(err))
+		return
+	}
+	if !checkPartLimits(w, geometryPayload) {
+		return
+	}
+	options := utils.ReadRequestOptions(r)
+
+	layers, err := handlers.ParseLayers(geometryPayload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	report, err := handlers.ValidateLayerCoverage(layers,
+		options.Float("toleranceMeters", handlers.DefaultSnapToleranceMeters),
+		options.Float("coverageSearchFactor", handlers.DefaultCoverageSearchFactor),
+		requestQuadSegs(options, utils.DefaultQuadSegs))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	jsonResult, err := json.Marshal(report)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: failed to encode response: %v", err), http.StatusInternalServerError)
+		return
+	}
+	sendResponse(w, jsonResult)
+}
+
+func cleanTopologyHandler(w http.ResponseWriter, r *http.Request) {
+	// Add panic recovery to prevent server crashes
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("PANIC recovered in cleanTopologyHandler: %v", r)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}()
+	
+	log.Printf("=== Topology cleaning request received ===")
+	log.Printf("Content-Type: %s", r.Header.Get("Content-Type"))
+
+	if err := utils.DecompressRequestBody(r, maxDecompressedBytes); err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+		return
+	}
+	
+	var geometryPayload string
+	var uploadName string
+	
+	// Check if this is a direct JSON request or multipart form
+	contentType := r.Header.Get("Content-Type")
+	if strings.Contains(contentType, "application/json") {
+		// Handle direct JSON request
+		log.Printf("Handling direct JSON request")
+		sourcePayload, err := readSourceURL(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+			return
+		}
+		geometryPayload = sourcePayload
+		if geometryPayload == "" {
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("ERROR: reading request body: %v", err), readErrorStatus(err))
+				return
+			}
+			geometryPayload = string(body)
+		}
+		if geometryPayload == "" {
+			sendResponse(w, []byte("ERROR: Empty request body"))
+			return
+		}
+	} else {
+		// Handle multipart form request
+		log.Printf("Handling multipart form request")
+		multiPartRequest := utils.ReadMultiPartForm(r, "file", maxDecompressedBytes)
+		if multiPartRequest.Err != nil {
+			http.Error(w, fmt.Sprintf("ERROR: %v", multiPartRequest.Err), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		sourcePayload, err := readSourceURL(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+			return
+		}
+		if sourcePayload != "" {
+			geometryPayload = sourcePayload
+		} else if multiPartRequest.File == "" {
+			if multiPartRequest.Properties.FeatureCollection != "" {
+				geometryPayload = multiPartRequest.Properties.FeatureCollection
+			} else if multiPartRequest.Properties.FilePath != "" {
+				filePayload, err := readInputFile(multiPartRequest.Properties.FilePath)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+					return
+				}
+				geometryPayload = filePayload
+			} else {
+				sendResponse(w, []byte("ERROR: No suitable files found"))
+				return
+			}
+		} else {
+			log.Printf("Reading from uploaded file")
+			geometryPayload = multiPartRequest.File
+			uploadName = multiPartRequest.Properties.FileName
+		}
+	}
+
+	if !checkCRS(w, geometryPayload) || !checkPartLimits(w, geometryPayload) || !checkDestinationURL(w, r) {
+		return
+	}
+
+	requestOptions := utils.ReadRequestOptions(r)
+	options := cleanTopologyOptionsFromRequest(requestOptions)
+	// An explicit outputName wins over the uploaded filename
+	options.OutputName = utils.OutputBaseName(requestOptions.String("outputName", uploadName))
+
+	// Check if shapefile format is requested (you can add a parameter for this)
+	// For now, always generate zip with both formats
+	zipData, err := handlers.CleanTopologyWithShapefile(geometryPayload, options)
+	var violation *handlers.CoverageViolationError
+	if errors.As(err, &violation) {
+		log.Printf("Rejecting input: %v", violation)
+		jsonViolation, _ := json.Marshal(violation)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write(jsonViolation)
+		return
+	}
+	var mixedTypes *utils.MixedGeometryTypesError
+	if errors.As(err, &mixedTypes) {
+		http.Error(w, fmt.Sprintf("ERROR: %v", mixedTypes), http.StatusUnprocessableEntity)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("ERROR: Topology cleaning failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if uploadResult(w, r, "application/zip", zipData, 0) {
+		return
+	}
+
+	// For direct JSON requests, always return the zip response
+	// For multipart requests, check if file saving is requested
+	if strings.Contains(contentType, "application/json") {
+		log.Printf("Topology cleaning complete. Sending zip response")
+		sendZipResponse(w, zipData, options.OutputName)
+	} else {
+		// This is a multipart form request, check if saving is requested
+		multiPartRequest := utils.ReadMultiPartForm(r, "file", maxDecompressedBytes)
+		if multiPartRequest.Properties.SaveFile {
+			if err := saveZipFile(multiPartRequest.Properties.FilePath, zipData); err != nil {
+				http.Error(w, fmt.Sprintf("ERROR: %v", err), http.StatusBadRequest)
+				return
+			}
+			sendResponse(w, []byte("Topology cleaned and zip file saved"))
+		} else {
+			log.Printf("Topology cleaning complete. Sending zip response")
+			sendZipResponse(w, zipData, options.OutputName)
+		}
+	}
+}
+
+// cleanTopologyOptionsFromRequest maps request options onto the cleaning pipeline options
+func cleanTopologyOptionsFromRequest(options utils.RequestOptions) handlers.CleanTopologyOptions {
+	cleanOptions := handlers.DefaultCleanTopologyOptions()
+	cleanOptions.IncludeOriginal = options.Bool("includeOriginal", cleanOptions.IncludeOriginal)
+	cleanOptions.Flatten = options.Bool("flatten", cleanOptions.Flatten)
+	cleanOptions.Precision = requestPrecision(options)
+	cleanOptions.OutputPrecision = requestOutputPrecision(options)
+	cleanOptions.SortBy = options.String("sortBy", cleanOptions.SortBy)
+	cleanOptions.KeepNullGeometries = options.Bool("keepNullGeometries", cleanOptions.KeepNullGeometries)
+	cleanOptions.PassThroughNonPolygons = options.Bool("passThroughNonPolygons", cleanOptions.PassThroughNonPolygons)
+	cleanOptions.IncludeInputIndex = options.Bool("includeInputIndex", cleanOptions.IncludeInputIndex)
+	cleanOptions.Lenient = options.Bool("lenient", cleanOptions.Lenient)
+	cleanOptions.ValidatePreservation = options.Bool("validatePreservation", cleanOptions.ValidatePreservation)
+	cleanOptions.DebugSpatialIndex = options.Bool("debugSpatialIndex", cleanOptions.DebugSpatialIndex)
+	if precisionScale := options.Float("precisionScale", 0); precisionScale >= 0 {
+		cleanOptions.PrecisionScale = precisionScale
+	} else {
+		log.Printf("Ignoring negative precisionScale %g", precisionScale)
+	}
+	if simplifyToleranceM := options.Float("simplifyToleranceM", 0); simplifyToleranceM >= 0 {
+		cleanOptions.SimplifyToleranceMeters = simplifyToleranceM
+	} else {
+		log.Printf("Ignoring negative simplifyToleranceM %g", simplifyToleranceM)
+	}
+	cleanOptions.DBFDecimals = requestDBFDecimals(options)
+	cleanOptions.QuadSegs = requestQuadSegs(options, cleanOptions.QuadSegs)
+	// snapToleranceM is the explicit name; toleranceMeters is kept for existing clients
+	cleanOptions.ToleranceMeters = options.Float("snapToleranceM", options.Float("toleranceMeters", cleanOptions.ToleranceMeters))
+	if adjacencyToleranceM := options.Float("adjacencyToleranceM", 0); adjacencyToleranceM >= 0 {
+		cleanOptions.AdjacencyToleranceMeters = adjacencyToleranceM
+	} else {
+		log.Printf("Ignoring negative adjacencyToleranceM %g", adjacencyToleranceM)
+	}
+	cleanOptions.AutoTolerance = options.Bool("autoTolerance", cleanOptions.AutoTolerance)
+	if snapPasses := options.Int("snapPasses", cleanOptions.SnapPasses); snapPasses >= 1 && snapPasses <= 10 {
+		cleanOptions.SnapPasses = snapPasses
+	} else {
+		log.Printf("Ignoring out of range snapPasses %d", snapPasses)
+	}
+	if batchSize := options.Int("batchSize", cleanOptions.BatchSize); batchSize >= 0 {
+		cleanOptions.BatchSize = batchSize
+	} else {
+		log.Printf("Ignoring negative batchSize %d", batchSize)
+	}
+	cleanOptions.StrictCoverage = options.Bool("strictCoverage", cleanOptions.StrictCoverage)
+	cleanOptions.StrictOverlapAreaM2 = options.Float("strictOverlapAreaM2", cleanOptions.StrictOverlapAreaM2)
+	cleanOptions.ResolveSliverOverlaps = options.Bool("resolveSliverOverlaps", cleanOptions.ResolveSliverOverlaps)
+	if sliverMaxAreaRatio := options.Float("sliverMaxAreaRatio", cleanOptions.SliverMaxAreaRatio); sliverMaxAreaRatio > 0 && sliverMaxAreaRatio <= 1 {
+		cleanOptions.SliverMaxAreaRatio = sliverMaxAreaRatio
+	} else {
+		log.Printf("Ignoring out of range sliverMaxAreaRatio %g, using %g", sliverMaxAreaRatio, cleanOptions.SliverMaxAreaRatio)
+	}
+	switch sliverClipRule := options.String("sliverClipRule", cleanOptions.SliverClipRule); sliverClipRule {
+	case handlers.SliverClipSmaller, handlers.SliverClipLarger:
+		cleanOptions.SliverClipRule = sliverClipRule
+	default:
+		log.Printf("Ignoring unknown sliverClipRule %q, using %s", sliverClipRule, cleanOptions.SliverClipRule)
+	}
+	cleanOptions.Profile = options.Bool("profile", cleanOptions.Profile)
+	cleanOptions.Dedupe = options.Bool("dedupe", cleanOptions.Dedupe)
+	switch dedupeStrategy := options.String("dedupeStrategy", cleanOptions.DedupeStrategy); dedupeStrategy {
+	case handlers.DedupeKeepFirst, handlers.DedupeKeepLast, handlers.DedupeMerge:
+		cleanOptions.DedupeStrategy = dedupeStrategy
+	default:
+		log.Printf("Ignoring unknown dedupeStrategy %q, using %s", dedupeStrategy, cleanOptions.DedupeStrategy)
+	}
+	cleanOptions.ProfileTop = options.Int("profileTop", cleanOptions.ProfileTop)
+	cleanOptions.IncludeBBox = options.Bool("includeBBox", cleanOptions.IncludeBBox)
+	if snapSearchFactor := options.Float("snapSearchFactor", cleanOptions.SnapSearchFactor); snapSearchFactor >= 1 {
+		cleanOptions.SnapSearchFactor = snapSearchFactor
+	} else {
+		log.Printf("Ignoring snapSearchFactor %g below 1, using %g", snapSearchFactor, cleanOptions.SnapSearchFactor)
+	}
+	if coverageSearchFactor := options.Float("coverageSearchFactor", cleanOptions.CoverageSearchFactor); coverageSearchFactor >= 1 {
+		cleanOptions.CoverageSearchFactor = coverageSearchFactor
+	} else {
+		log.Printf("Ignoring coverageSearchFactor %g below 1, using %g", coverageSearchFactor, cleanOptions.CoverageSearchFactor)
+	}
+	cleanOptions.IncludeFeatureBBox = options.Bool("includeFeatureBBox", cleanOptions.IncludeFeatureBBox)
+	cleanOptions.RemoveSpikes = options.Bool("removeSpikes", cleanOptions.RemoveSpikes)
+	cleanOptions.SpikeAngleDegrees = requestSpikeAngle(options)
+	switch output := options.String("output", cleanOptions.Output); output {
+	case handlers.OutputShapefile, handlers.OutputFeatureFiles:
+		cleanOptions.Output = output
+	default:
+		log.Printf("Ignoring unknown output %q, using %s", output, cleanOptions.Output)
+	}
+	cleanOptions.FileKey = options.String("fileKey", cleanOptions.FileKey)
+	cleanOptions.KeepProperties = options.List("keepProperties")
+	switch outputCRS := strings.TrimPrefix(strings.ToUpper(options.String("outputCRS", cleanOptions.OutputCRS)), "EPSG:"); outputCRS {
+	case handlers.OutputCRSWGS84, handlers.OutputCRSWebMercator:
+		cleanOptions.OutputCRS = outputCRS
+	default:
+		log.Printf("Ignoring unsupported outputCRS %q, using EPSG:%s", outputCRS, cleanOptions.OutputCRS)
+	}
+	switch repairMethod := options.String("repairMethod", cleanOptions.RepairMethod); repairMethod {
+	case handlers.RepairMethodMakeValid, handlers.RepairMethodBuffer0:
+		cleanOptions.RepairMethod = repairMethod
+	default:
+		log.Printf("Ignoring unknown repairMethod %q, using %s", repairMethod, cleanOptions.RepairMethod)
+	}
+	return cleanOptions
+}
+
+// requestQuadSegs returns the requested buffer quadrant segments, or defaultValue if unset or invalid
+func requestQuadSegs(options utils.RequestOptions, defaultValue int) int {
+	quadSegs := options.Int("quadSegs", defaultValue)
+	if quadSegs < 1 || quadSegs > 64 {
+		log.Printf("Ignoring out of range quadSegs %d, using %d", quadSegs, defaultValue)
+		return defaultValue
+	}
+	return quadSegs
+}
+
+// requestSpikeAngle returns the requested spike angle in degrees, or the default if unset or invalid
+func requestSpikeAngle(options utils.RequestOptions) float64 {
+	spikeAngle := options.Float("spikeAngleDeg", utils.DefaultSpikeAngleDegrees)
+	if spikeAngle <= 0 || spikeAngle >= 180 {
+		log.Printf("Ignoring out of range spikeAngleDeg %g, using %g", spikeAngle, utils.DefaultSpikeAngleDegrees)
+		return utils.DefaultSpikeAngleDegrees
+	}
+	return spikeAngle
+}
+
+// requestDBFDecimals parses dbfDecimals, a comma-separated list of
+// property:decimals pairs such as "area:3,id:0", skipping malformed entries
+func requestDBFDecimals(options utils.RequestOptions) map[string]int {
+	list := options.String("dbfDecimals", "")
+	if list == "" {
+		return nil
+	}
+
+	decimals := make(map[string]int)
+	for _, entry := range strings.Split(list, ",") {
+		property, value, found := strings.Cut(entry, ":")
+		places, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || err != nil || places < 0 || places > 15 || strings.TrimSpace(property) == "" {
+			log.Printf("Ignoring malformed dbfDecimals entry %q", entry)
+			continue
+		}
+		decimals[strings.TrimSpace(property)] = places
+	}
+	return decimals
+}
+
+// requestOutputPrecision returns the requested output decimal places, or -1 to emit at processing precision
+func requestOutputPrecision(options utils.RequestOptions) int {
+	outputPrecision := options.Int("outputPrecision", -1)
+	if outputPrecision > 15 {
+		log.Printf("Ignoring out of range outputPrecision %d", outputPrecision)
+		return -1
+	}
+	return outputPrecision
+}
+
+// requestProperties decodes the optional "properties" option, a JSON object attached to merged output features
+func requestProperties(options utils.RequestOptions) (map[string]interface{}, error) {
+	var properties map[string]interface{}
+	if rawProperties := options.String("properties", ""); rawProperties != "" {
+		if err := json.Unmarshal([]byte(rawProperties), &properties); err != nil {
+			return nil, fmt.Errorf("invalid properties: %v", err)
+		}
+	}
+	return properties, nil
+}
+
+// requestPrecision returns the requested truncation precision, clamped to a sane range
+func requestPrecision(options utils.RequestOptions) int {
+	precision := options.Int("precision", utils.DefaultPrecision)
+	if precision < 0 || precision > 15 {
+		log.Printf("Ignoring out of range precision %d, using %d", precision, utils.DefaultPrecision)
+		return utils.DefaultPrecision
+	}
+	return precision
+}
+
+func sendResponse(w http.ResponseWriter, response []byte) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write(response)
 }
 
-func sendZipResponse(w http.ResponseWriter, zipData []byte) {
+// sendZipResponse sends zipData as an attachment named <baseName>.zip; baseName
+// must already be sanitized with utils.OutputBaseName
+func sendZipResponse(w http.ResponseWriter, zipData []byte, baseName string) {
 	w.Header().Set("Content-Type", "application/zip")
-	w.Header().Set("Content-Disposition", "attachment; filename=\"cleaned_topology.zip\"")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.zip\"", baseName))
 	w.WriteHeader(http.StatusOK)
 	w.Write(zipData)
 }
 
-func saveZipFile(filePath string, zipData []byte) {
-	name := strings.Replace(filePath, ".json", "", 1)
-	name = strings.Replace(name, "files", "output", 1)
-	filename := name + "_PROCESSED.zip"
+// saveZipFile writes a processed shapefile zip under outputDir, mirroring the client-supplied input path
+func saveZipFile(filePath string, zipData []byte) error {
+	return writeOutputFile(filePath, "_PROCESSED.zip", zipData)
+}
 
-	err := os.WriteFile(filename, zipData, 0644)
+// writeOutputFile resolves filePath inside outputDir, rejecting traversal, and writes data there
+func writeOutputFile(filePath string, suffix string, data []byte) error {
+	filename, err := utils.ResolveOutputPath(inputDir, outputDir, filePath, suffix)
 	if err != nil {
-		fmt.Println("Error saving zip file:", err)
-		return
+		log.Printf("Rejected output path %q: %v", filePath, err)
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("failed to save %s: %w", filename, err)
 	}
 
-	fmt.Println("Zip file saved to", filename)
+	fmt.Println("Saved to", filename)
+	return nil
 }
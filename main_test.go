@@ -0,0 +1,573 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bsaid97/go-polygon-fixer/handlers"
+	"github.com/bsaid97/go-polygon-fixer/utils"
+	"github.com/twpayne/go-geos"
+)
+
+func TestSendZipResponseContentDisposition(t *testing.T) {
+	tests := []struct {
+		name       string
+		uploadName string
+		want       string
+	}{
+		{"uploaded filename", "parcels_2024.geojson", `attachment; filename="parcels_2024.zip"`},
+		{"gzipped upload with spaces", "county parcels.geojson.gz", `attachment; filename="county_parcels.zip"`},
+		{"quotes cannot break out", `x"; filename="evil.exe`, `attachment; filename="x___filename__evil.zip"`},
+		{"no name falls back to the default", "", `attachment; filename="cleaned_topology.zip"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			sendZipResponse(recorder, []byte("zip"), utils.OutputBaseName(tt.uploadName))
+
+			if got := recorder.Header().Get("Content-Disposition"); got != tt.want {
+				t.Errorf("Content-Disposition = %s, want %s", got, tt.want)
+			}
+			if got := recorder.Header().Get("Content-Type"); got != "application/zip" {
+				t.Errorf("Content-Type = %q, want application/zip", got)
+			}
+		})
+	}
+}
+
+func TestCheckCRSStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		allowed bool
+	}{
+		{"wgs84", `{"type":"FeatureCollection","crs":{"type":"name","properties":{"name":"EPSG:4326"}},"features":[]}`, true},
+		{"other crs", `{"type":"FeatureCollection","crs":{"type":"name","properties":{"name":"EPSG:2154"}},"features":[]}`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			if allowed := checkCRS(recorder, tt.payload); allowed != tt.allowed {
+				t.Fatalf("checkCRS = %v, want %v", allowed, tt.allowed)
+			}
+			if !tt.allowed && recorder.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestFixNDGeoJSONHandler(t *testing.T) {
+	const (
+		square = `{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]},"properties":{"id":%d}}`
+		bowtie = `{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[0,0],[2,2],[2,0],[0,2],[0,0]]]},"properties":{"id":%d}}`
+	)
+
+	tests := []struct {
+		name   string
+		lines  []string
+		want   []float64   // id of each output line, in order
+		errors map[int]int // output line index to the _line it reports
+	}{
+		{
+			name:  "trailing newline",
+			lines: []string{fmt.Sprintf(square, 1), fmt.Sprintf(bowtie, 2), fmt.Sprintf(square, 3), ""},
+			want:  []float64{1, 2, 3},
+		},
+		{
+			name:  "blank lines and no trailing newline",
+			lines: []string{"", fmt.Sprintf(square, 1), "   ", "", fmt.Sprintf(bowtie, 2), "\t", fmt.Sprintf(square, 3)},
+			want:  []float64{1, 2, 3},
+		},
+		{
+			name:   "bad lines are answered in place",
+			lines:  []string{fmt.Sprintf(square, 1), `{"type":"Feature",`, `{"type":"FeatureCollection","features":[]}`, fmt.Sprintf(square, 4), ""},
+			want:   []float64{1, 0, 0, 4},
+			errors: map[int]int{1: 2, 2: 3},
+		},
+		{
+			name:  "windows line endings",
+			lines: []string{fmt.Sprintf(square, 1) + "\r", fmt.Sprintf(square, 2) + "\r", ""},
+			want:  []float64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := strings.Join(tt.lines, "\n")
+			recorder := httptest.NewRecorder()
+			fixNDGeoJSONHandler(recorder, httptest.NewRequest(http.MethodPost, "/fix", strings.NewReader(body)))
+
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusOK, recorder.Body)
+			}
+			if got := recorder.Header().Get("Content-Type"); got != "application/x-ndjson" {
+				t.Errorf("Content-Type = %q, want application/x-ndjson", got)
+			}
+
+			output := bytes.TrimSuffix(recorder.Body.Bytes(), []byte("\n"))
+			lines := bytes.Split(output, []byte("\n"))
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d output lines, want %d:\n%s", len(lines), len(tt.want), recorder.Body)
+			}
+			for i, line := range lines {
+				var feature Feature
+				if err := json.Unmarshal(line, &feature); err != nil {
+					t.Fatalf("output line %d is not a feature: %v: %s", i, err, line)
+				}
+
+				if number, isError := tt.errors[i]; isError {
+					if got, _ := feature.Properties["_line"].(float64); int(got) != number {
+						t.Errorf("output line %d has _line %v, want %d", i, feature.Properties["_line"], number)
+					}
+					if feature.Properties["_error"] == nil || string(feature.Geometry) != "null" {
+						t.Errorf("output line %d = %s, want a null geometry with an _error", i, line)
+					}
+					continue
+				}
+				if id := feature.Properties["id"]; id != tt.want[i] {
+					t.Errorf("output line %d has id %v, want %v", i, id, tt.want[i])
+				}
+				if string(feature.Geometry) == "null" || feature.Properties["_error"] != nil {
+					t.Errorf("output line %d was not fixed: %s", i, line)
+				}
+			}
+		})
+	}
+}
+
+func TestFixNDGeoJSONHandlerRejectsGet(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	fixNDGeoJSONHandler(recorder, httptest.NewRequest(http.MethodGet, "/fix", nil))
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+// filepathRequest builds a multipart POST to target naming clientPath in its
+// filepath field
+func filepathRequest(t *testing.T, target string, clientPath string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("filepath", clientPath); err != nil {
+		t.Fatalf("writing filepath field: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("closing multipart body: %v", err)
+	}
+	request := httptest.NewRequest(http.MethodPost, target, &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return request
+}
+
+func TestFilepathReadsConfinedToInputDir(t *testing.T) {
+	defer func(previous string) { inputDir = previous }(inputDir)
+	inputDir = t.TempDir()
+
+	// A readable file just outside the input directory
+	outside := filepath.Join(filepath.Dir(inputDir), "outside.json")
+	if err := os.WriteFile(outside, []byte(`{"type":"FeatureCollection","features":[]}`), 0644); err != nil {
+		t.Fatalf("writing %s: %v", outside, err)
+	}
+	defer os.Remove(outside)
+
+	clientPaths := []struct {
+		name string
+		path string
+	}{
+		{"parent directories", filepath.Join(inputDir, "..", "outside.json")},
+		{"relative traversal", "../../etc/passwd"},
+		{"absolute path", "/etc/passwd"},
+		{"missing file inside the input directory", filepath.Join(inputDir, "missing.json")},
+	}
+	handlersByRoute := []struct {
+		route   string
+		handler http.HandlerFunc
+	}{
+		{"/v2/fix-geometry", fixGeometryHandler2},
+		{"/clean-topology", cleanTopologyHandler},
+		{"/centroid", centroidHandler},
+	}
+
+	for _, route := range handlersByRoute {
+		for _, clientPath := range clientPaths {
+			t.Run(route.route+"/"+clientPath.name, func(t *testing.T) {
+				recorder := httptest.NewRecorder()
+				route.handler(recorder, filepathRequest(t, route.route, clientPath.path))
+				if recorder.Code != http.StatusBadRequest {
+					t.Errorf("status = %d, want %d for filepath %q: %s", recorder.Code, http.StatusBadRequest, clientPath.path, recorder.Body)
+				}
+			})
+		}
+	}
+}
+
+func TestReadInputFile(t *testing.T) {
+	defer func(previous string) { inputDir = previous }(inputDir)
+	inputDir = t.TempDir()
+
+	const payload = `{"type":"FeatureCollection","features":[]}`
+	filename := filepath.Join(inputDir, "parcels.json")
+	if err := os.WriteFile(filename, []byte(payload), 0644); err != nil {
+		t.Fatalf("writing %s: %v", filename, err)
+	}
+
+	got, err := readInputFile(filename)
+	if err != nil {
+		t.Fatalf("readInputFile: %v", err)
+	}
+	if got != payload {
+		t.Errorf("readInputFile = %s, want %s", got, payload)
+	}
+
+	if _, err := readInputFile(filepath.Join(inputDir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("readInputFile of a missing file = %v, want the os.ReadFile error", err)
+	}
+}
+
+func TestPartLimitsOnPayloadEndpoints(t *testing.T) {
+	defer func(previous utils.PartLimits) { partLimits = previous }(partLimits)
+	partLimits = utils.PartLimits{PerFeature: 10, PerRequest: 2}
+
+	const square = `{"type":"Feature","geometry":{"type":"Polygon","coordinates":[[[%[1]d,0],[%[1]d,1],[%[2]d,1],[%[2]d,0],[%[1]d,0]]]},"properties":{}}`
+	payload := fmt.Sprintf(`{"type":"FeatureCollection","features":[%s,%s,%s]}`,
+		fmt.Sprintf(square, 0, 1), fmt.Sprintf(square, 2, 3), fmt.Sprintf(square, 4, 5))
+
+	tests := []struct {
+		route   string
+		handler http.HandlerFunc
+	}{
+		{"/centroid", centroidHandler},
+		{"/min-bounding-circle", minBoundingCircleHandler},
+		{"/oriented-bbox", orientedBBoxHandler},
+		{"/concave-hull", concaveHullHandler},
+		{"/explode", explodeHandler},
+		{"/collect", collectHandler},
+		{"/split", splitHandler},
+		{"/split-multiparts", splitMultipartsHandler},
+		{"/noding/validate", nodingValidateHandler},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.route, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, tt.route, strings.NewReader(payload))
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			tt.handler(recorder, request)
+			if recorder.Code != http.StatusRequestEntityTooLarge {
+				t.Errorf("status = %d, want %d for three polygons over a limit of two", recorder.Code, http.StatusRequestEntityTooLarge)
+			}
+		})
+	}
+}
+
+func TestCompareReferenceCache(t *testing.T) {
+	defer func(previous *handlers.ReferenceCache) { referenceCache = previous }(referenceCache)
+	referenceCache = handlers.NewReferenceCache(time.Hour, 0, 0)
+
+	// layer is a one-feature FeatureCollection keyed "a", its unit square shifted east by x
+	layer := func(x int) string {
+		return fmt.Sprintf(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"a"},"geometry":{"type":"Polygon","coordinates":[[[%[1]d,0],[%[2]d,0],[%[2]d,1],[%[1]d,1],[%[1]d,0]]]}}]}`, x, x+1)
+	}
+	candidate := layer(0)
+
+	// Requests run in order against one cache
+	steps := []struct {
+		name          string
+		referenceID   string
+		layers        []string
+		wantStatus    int
+		wantHausdorff float64
+	}{
+		{"uncached id needs the reference", "parcels", []string{candidate}, http.StatusBadRequest, 0},
+		{"reference is cached", "parcels", []string{layer(0), candidate}, http.StatusOK, 0},
+		{"candidate only uses the cached reference", "parcels", []string{candidate}, http.StatusOK, 0},
+		{"new reference replaces the cached one", "parcels", []string{layer(2), candidate}, http.StatusOK, 2},
+		{"candidate only uses the replaced reference", "parcels", []string{candidate}, http.StatusOK, 2},
+		{"other ids are unaffected", "other", []string{candidate}, http.StatusBadRequest, 0},
+		{"too many layers", "parcels", []string{candidate, candidate, candidate}, http.StatusBadRequest, 0},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			payload := fmt.Sprintf(`{"layers":[%s]}`, strings.Join(step.layers, ","))
+			request := httptest.NewRequest(http.MethodPost, "/compare?key=name&referenceId="+step.referenceID, strings.NewReader(payload))
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			compareHandler(recorder, request)
+
+			if recorder.Code != step.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, step.wantStatus, recorder.Body)
+			}
+			if step.wantStatus != http.StatusOK {
+				return
+			}
+
+			var report handlers.ComparisonReport
+			if err := json.Unmarshal(recorder.Body.Bytes(), &report); err != nil {
+				t.Fatalf("decoding report: %v", err)
+			}
+			if len(report.Matched) != 1 {
+				t.Fatalf("matched %d features, want 1", len(report.Matched))
+			}
+			if got := report.Matched[0].HausdorffDistance; math.Abs(got-step.wantHausdorff) > 1e-9 {
+				t.Errorf("Hausdorff distance = %v, want %v against the latest reference", got, step.wantHausdorff)
+			}
+		})
+	}
+}
+
+func TestFixGeometryHandler2WithoutPayload(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("saveFile", "false"); err != nil {
+		t.Fatalf("writing saveFile field: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("closing multipart body: %v", err)
+	}
+	request := httptest.NewRequest(http.MethodPost, "/v2/fix-geometry", &body)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+
+	recorder := httptest.NewRecorder()
+	fixGeometryHandler2(recorder, request)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", recorder.Code, http.StatusBadRequest)
+	}
+	if got := strings.TrimSpace(recorder.Body.String()); got != "ERROR: No suitable files found" {
+		t.Errorf("body = %q, want only the error", got)
+	}
+}
+
+func TestTruncateHandler(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		contentType     string
+		payload         string
+		needsM          bool
+		wantStatus      int
+		wantContentType string
+		want            string
+	}{
+		{
+			name:            "geojson",
+			target:          "/truncate?precision=2",
+			payload:         `{"type":"LineString","coordinates":[[0.123,1.987],[2.555,3.444]]}`,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/geo+json",
+			want:            "LINESTRING (0.12 1.99, 2.56 3.44)",
+		},
+		{
+			name:            "wkt by content type",
+			target:          "/truncate?precision=2",
+			contentType:     "text/wkt",
+			payload:         "POINT Z (0.123 1.987 10.555)",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/wkt",
+			want:            "POINT Z (0.12 1.99 10.555)",
+		},
+		{
+			name:            "multilinestring m as wkt",
+			target:          "/truncate?format=wkt&precision=2",
+			payload:         "MULTILINESTRING M ((0.123 1.987 10.555, 2.555 3.444 20.125), (4 5 30, 6 7 40))",
+			needsM:          true,
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/wkt",
+			want:            "MULTILINESTRING M ((0.12 1.99 10.555, 2.56 3.44 20.125), (4 5 30, 6 7 40))",
+		},
+		{
+			name:            "multilinestring m as wkb",
+			target:          "/truncate?format=wkb&precision=2",
+			payload:         "MULTILINESTRING M ((0.123 1.987 10.555, 2.555 3.444 20.125), (4 5 30, 6 7 40))",
+			needsM:          true,
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/wkb",
+			want:            "MULTILINESTRING M ((0.12 1.99 10.555, 2.56 3.44 20.125), (4 5 30, 6 7 40))",
+		},
+		{
+			name:       "unparseable wkt",
+			target:     "/truncate?format=wkt",
+			payload:    "LINESTRING (0 0,",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown format",
+			target:     "/truncate?format=kml",
+			payload:    "POINT (0 0)",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "get",
+			method:     http.MethodGet,
+			target:     "/truncate",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.needsM && geos.VersionCompare(3, 12, 0) < 0 {
+				t.Skip("GEOS before 3.12 reads and writes no M ordinates")
+			}
+
+			payload := tt.payload
+			if strings.Contains(tt.target, "format=wkb") {
+				// Clients send measured WKB, not WKT, to the wkb format
+				geom, err := geos.NewGeomFromWKT(payload)
+				if err != nil {
+					t.Fatalf("parsing payload: %v", err)
+				}
+				payload = string(utils.FormatGeometry(geom, utils.GeometryFormatWKB))
+				geom.Destroy()
+			}
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			request := httptest.NewRequest(method, tt.target, strings.NewReader(payload))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			recorder := httptest.NewRecorder()
+			truncateHandler(recorder, request)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := recorder.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+
+			format, err := utils.GeometryFormat("", tt.wantContentType)
+			if err != nil {
+				t.Fatalf("GeometryFormat: %v", err)
+			}
+			got, err := utils.ParseGeometry(recorder.Body.Bytes(), format)
+			if err != nil {
+				t.Fatalf("parsing response %q: %v", recorder.Body.String(), err)
+			}
+			defer got.Destroy()
+			want, err := geos.NewGeomFromWKT(tt.want)
+			if err != nil {
+				t.Fatalf("parsing want: %v", err)
+			}
+			defer want.Destroy()
+			// EqualsExact ignores M, so compare through the same WKT writer
+			if got.ToWKT() != want.ToWKT() {
+				t.Errorf("response = %s, want %s", got.ToWKT(), want.ToWKT())
+			}
+		})
+	}
+}
+
+// countLeakedGeoms swaps geos.DefaultContext for one that counts geometries the
+// garbage collector finalizes without Destroy having been called, restoring it
+// when the test ends. Parts and rings share their parent's memory and are never
+// destroyed on their own, so only top-level geometries count. The returned
+// function collects garbage and reports the count so far.
+func countLeakedGeoms(tb testing.TB) func() int64 {
+	var leaked atomic.Int64
+	previous := geos.DefaultContext
+	geos.DefaultContext = geos.NewContext(geos.WithGeomFinalizeFunc(func(geom *geos.Geom) {
+		if reflect.ValueOf(geom).Elem().FieldByName("parent").IsNil() {
+			leaked.Add(1)
+		}
+	}))
+	tb.Cleanup(func() { geos.DefaultContext = previous })
+
+	return func() int64 {
+		// Finalizers run on their own goroutine after the collection that finds them
+		for range 5 {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+		}
+		return leaked.Load()
+	}
+}
+
+// dissolvePayload is a 3x3 grid of unit squares, with a bowtie to repair
+const dissolvePayload = `{"type":"GeometryCollection","geometries":[
+	{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]},
+	{"type":"Polygon","coordinates":[[[1,0],[2,0],[2,1],[1,1],[1,0]]]},
+	{"type":"Polygon","coordinates":[[[2,0],[3,0],[3,1],[2,1],[2,0]]]},
+	{"type":"MultiPolygon","coordinates":[[[[0,1],[1,1],[1,2],[0,2],[0,1]]],[[[1,1],[2,1],[2,2],[1,2],[1,1]]]]},
+	{"type":"Polygon","coordinates":[[[2,1],[3,1],[3,2],[2,2],[2,1]]]},
+	{"type":"Polygon","coordinates":[[[0,2],[1,3],[1,2],[0,3],[0,2]]]},
+	{"type":"Polygon","coordinates":[[[1,2],[3,2],[3,3],[1,3],[1,2]]]}
+]}`
+
+func TestHandlersFreeGeometries(t *testing.T) {
+	const repetitions = 20
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		payload string
+	}{
+		{"dissolve", dissolveHandler, "/dissolve", dissolvePayload},
+		{"dissolve cascaded", dissolveHandler, "/dissolve?method=cascaded", dissolvePayload},
+		{"dissolve flattened with centroid", dissolveHandler, "/dissolve?flatten=true&includeCentroid=true", dissolvePayload},
+		{"dissolve unknown method", dissolveHandler, "/dissolve?method=nope", dissolvePayload},
+		{"check geometry", checkGeometryHandler, "/check-geometry", dissolvePayload},
+		{"check geometry summary", checkGeometryHandler, "/check-geometry?summary=true", dissolvePayload},
+		{"check geometry wkt", checkGeometryHandler, "/check-geometry?format=wkt", "GEOMETRYCOLLECTION (POLYGON ((0 0, 2 2, 2 0, 0 2, 0 0)), POINT (5 5))"},
+		{"truncate", truncateHandler, "/truncate?precision=0", dissolvePayload},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaked := countLeakedGeoms(t)
+			for range repetitions {
+				recorder := httptest.NewRecorder()
+				tt.handler(recorder, httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.payload)))
+				if recorder.Body.Len() == 0 {
+					t.Fatalf("empty response, status %d", recorder.Code)
+				}
+			}
+			if count := leaked(); count != 0 {
+				t.Errorf("%d geometries over %d requests were garbage collected without being destroyed", count, repetitions)
+			}
+		})
+	}
+}
+
+func BenchmarkDissolveHandler(b *testing.B) {
+	for _, method := range []string{handlers.UnionMethodUnary, handlers.UnionMethodCascaded} {
+		b.Run(method, func(b *testing.B) {
+			leaked := countLeakedGeoms(b)
+			b.ReportAllocs()
+			for range b.N {
+				dissolveHandler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/dissolve?method="+method, strings.NewReader(dissolvePayload)))
+			}
+			b.StopTimer()
+			if count := leaked(); count != 0 {
+				b.Errorf("%d geometries over %d requests were garbage collected without being destroyed", count, b.N)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Circle is a planar circle in coordinate units
+type Circle struct {
+	X      float64
+	Y      float64
+	Radius float64
+}
+
+func (c Circle) contains(p []float64) bool {
+	const epsilon = 1e-12
+	return math.Hypot(p[0]-c.X, p[1]-c.Y) <= c.Radius+epsilon
+}
+
+// MinimumBoundingCircle returns the smallest circle enclosing all coordinates
+// using Welzl's randomized incremental algorithm. The shuffle is seeded so the
+// result is reproducible for identical input.
+func MinimumBoundingCircle(coords [][]float64) Circle {
+	if len(coords) == 0 {
+		return Circle{}
+	}
+
+	points := make([][]float64, len(coords))
+	copy(points, coords)
+	shuffler := rand.New(rand.NewSource(1))
+	shuffler.Shuffle(len(points), func(i, j int) {
+		points[i], points[j] = points[j], points[i]
+	})
+
+	circle := Circle{X: points[0][0], Y: points[0][1]}
+	for i := 1; i < len(points); i++ {
+		if circle.contains(points[i]) {
+			continue
+		}
+		circle = Circle{X: points[i][0], Y: points[i][1]}
+		for j := 0; j < i; j++ {
+			if circle.contains(points[j]) {
+				continue
+			}
+			circle = circleFromTwo(points[i], points[j])
+			for k := 0; k < j; k++ {
+				if !circle.contains(points[k]) {
+					circle = circleFromThree(points[i], points[j], points[k])
+				}
+			}
+		}
+	}
+
+	return circle
+}
+
+func circleFromTwo(a, b []float64) Circle {
+	x := (a[0] + b[0]) / 2
+	y := (a[1] + b[1]) / 2
+	return Circle{X: x, Y: y, Radius: math.Hypot(a[0]-x, a[1]-y)}
+}
+
+func circleFromThree(a, b, c []float64) Circle {
+	bx, by := b[0]-a[0], b[1]-a[1]
+	cx, cy := c[0]-a[0], c[1]-a[1]
+	d := 2 * (bx*cy - by*cx)
+
+	// Collinear points: the circle spans the two furthest apart
+	if d == 0 {
+		best := circleFromTwo(a, b)
+		for _, candidate := range []Circle{circleFromTwo(a, c), circleFromTwo(b, c)} {
+			if candidate.Radius > best.Radius {
+				best = candidate
+			}
+		}
+		return best
+	}
+
+	ux := (cy*(bx*bx+by*by) - by*(cx*cx+cy*cy)) / d
+	uy := (bx*(cx*cx+cy*cy) - cx*(bx*bx+by*by)) / d
+	return Circle{X: ux + a[0], Y: uy + a[1], Radius: math.Hypot(ux, uy)}
+}
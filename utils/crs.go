@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// geoJSONCRS is the legacy (pre-RFC 7946) top-level crs member
+type geoJSONCRS struct {
+	Type       string `json:"type"`
+	Properties struct {
+		Name string          `json:"name"`
+		Code json.RawMessage `json:"code"`
+		Href string          `json:"href"`
+	} `json:"properties"`
+}
+
+// wgs84CRSNames are the crs names accepted as WGS84 longitude/latitude,
+// compared case-insensitively
+var wgs84CRSNames = map[string]bool{
+	"epsg:4326":                                    true,
+	"urn:ogc:def:crs:epsg::4326":                   true,
+	"urn:ogc:def:crs:epsg:6.6:4326":                true,
+	"urn:ogc:def:crs:ogc:1.3:crs84":                true,
+	"urn:ogc:def:crs:ogc::crs84":                   true,
+	"crs84":                                        true,
+	"http://www.opengis.net/def/crs/epsg/0/4326":   true,
+	"http://www.opengis.net/def/crs/ogc/1.3/crs84": true,
+}
+
+// CheckCRS inspects the legacy top-level crs member of a GeoJSON payload.
+// RFC 7946 dropped crs and fixed coordinates to WGS84, which is what all
+// tolerances here assume, so a payload without one passes. A crs naming
+// EPSG:4326 or CRS84 also passes; any other crs, including linked ones that
+// cannot be verified, is rejected because nothing here can reproject it.
+// Payloads that are not valid JSON pass unchecked, leaving the caller's own
+// parsing to report the error.
+func CheckCRS(payload string) error {
+	var object struct {
+		CRS *geoJSONCRS `json:"crs"`
+	}
+	if err := json.Unmarshal([]byte(payload), &object); err != nil || object.CRS == nil {
+		return nil
+	}
+
+	crs := object.CRS
+	switch strings.ToLower(crs.Type) {
+	case "name":
+		if wgs84CRSNames[strings.ToLower(strings.TrimSpace(crs.Properties.Name))] {
+			return nil
+		}
+		return fmt.Errorf("unsupported crs %q: only EPSG:4326 (WGS84) is supported, reproject the data before uploading", crs.Properties.Name)
+	case "epsg":
+		if strings.Trim(string(crs.Properties.Code), `"`) == "4326" {
+			return nil
+		}
+		return fmt.Errorf("unsupported crs EPSG:%s: only EPSG:4326 (WGS84) is supported, reproject the data before uploading", strings.Trim(string(crs.Properties.Code), `"`))
+	case "link":
+		return fmt.Errorf("linked crs %q cannot be verified: remove the crs member if the data is WGS84, or reproject it to EPSG:4326", crs.Properties.Href)
+	default:
+		return fmt.Errorf("unrecognised crs type %q: only EPSG:4326 (WGS84) is supported", crs.Type)
+	}
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckCRS(t *testing.T) {
+	const features = `"type":"FeatureCollection","features":[]`
+
+	tests := []struct {
+		name    string
+		payload string
+		wantErr string
+	}{
+		{"no crs", `{` + features + `}`, ""},
+		{"epsg 4326 name", `{` + features + `,"crs":{"type":"name","properties":{"name":"EPSG:4326"}}}`, ""},
+		{"ogc urn for 4326", `{` + features + `,"crs":{"type":"name","properties":{"name":"urn:ogc:def:crs:EPSG::4326"}}}`, ""},
+		{"crs84", `{` + features + `,"crs":{"type":"name","properties":{"name":"urn:ogc:def:crs:OGC:1.3:CRS84"}}}`, ""},
+		{"legacy epsg code", `{` + features + `,"crs":{"type":"EPSG","properties":{"code":4326}}}`, ""},
+		{"not json", `not json`, ""},
+		{"british national grid", `{` + features + `,"crs":{"type":"name","properties":{"name":"urn:ogc:def:crs:EPSG::27700"}}}`, `unsupported crs "urn:ogc:def:crs:EPSG::27700"`},
+		{"web mercator code", `{` + features + `,"crs":{"type":"epsg","properties":{"code":"3857"}}}`, "unsupported crs EPSG:3857"},
+		{"linked crs", `{` + features + `,"crs":{"type":"link","properties":{"href":"http://example.com/crs"}}}`, "cannot be verified"},
+		{"unknown crs type", `{` + features + `,"crs":{"type":"proj4","properties":{}}}`, `unrecognised crs type "proj4"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckCRS(tt.payload)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("CheckCRS: unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("CheckCRS error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
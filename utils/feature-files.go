@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// FeatureFileNames assigns zip member names to per-feature files, derived
+// from a key such as a parcel ID and unique within one zip
+type FeatureFileNames struct {
+	used map[string]bool
+}
+
+// NewFeatureFileNames creates a namer with no names taken
+func NewFeatureFileNames() *FeatureFileNames {
+	return &FeatureFileNames{used: make(map[string]bool)}
+}
+
+// Name returns "<key>.geojson" with the key sanitized as in OutputBaseName,
+// or "feature_<index>.geojson" when nothing usable remains. A name already
+// taken, compared case-insensitively as on Windows and macOS filesystems,
+// gets the first free "_2", "_3", ... suffix.
+func (n *FeatureFileNames) Name(key string, index int) string {
+	base := sanitizeName(key)
+	if base == "" {
+		base = fmt.Sprintf("feature_%d", index)
+	}
+
+	name := base
+	for suffix := 2; n.used[strings.ToLower(name)]; suffix++ {
+		name = fmt.Sprintf("%s_%d", base, suffix)
+	}
+	n.used[strings.ToLower(name)] = true
+	return name + ".geojson"
+}
+
+// GenerateZip creates a zip file containing the given entries, in order
+func GenerateZip(entries []ZipEntry) ([]byte, error) {
+	var zipBuffer bytes.Buffer
+	zipWriter := zip.NewWriter(&zipBuffer)
+
+	for _, entry := range entries {
+		entryFile, err := zipWriter.Create(entry.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s in zip: %v", entry.Name, err)
+		}
+		if _, err := entryFile.Write(entry.Data); err != nil {
+			return nil, fmt.Errorf("failed to write %s to zip: %v", entry.Name, err)
+		}
+	}
+
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close zip writer: %v", err)
+	}
+	return zipBuffer.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFeatureFileNames(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{
+			name: "plain keys",
+			keys: []string{"P-100", "P-101"},
+			want: []string{"P-100.geojson", "P-101.geojson"},
+		},
+		{
+			name: "unsafe characters are replaced",
+			keys: []string{"../../etc/passwd", `lot 7\b`, "parcel:9?"},
+			want: []string{"etc_passwd.geojson", "lot_7_b.geojson", "parcel_9.geojson"},
+		},
+		{
+			name: "missing or unusable keys use the position",
+			keys: []string{"", "P-1", "///", "é"},
+			want: []string{"feature_0.geojson", "P-1.geojson", "feature_2.geojson", "feature_3.geojson"},
+		},
+		{
+			name: "collisions get suffixes",
+			keys: []string{"A", "A", "a", "A_2", "A"},
+			want: []string{"A.geojson", "A_2.geojson", "a_3.geojson", "A_2_2.geojson", "A_4.geojson"},
+		},
+		{
+			name: "keys that sanitize alike collide",
+			keys: []string{"lot 1", "lot/1", "lot_1"},
+			want: []string{"lot_1.geojson", "lot_1_2.geojson", "lot_1_3.geojson"},
+		},
+		{
+			name: "long keys are capped",
+			keys: []string{strings.Repeat("x", 150)},
+			want: []string{strings.Repeat("x", maxOutputNameLength) + ".geojson"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names := NewFeatureFileNames()
+			got := make([]string, len(tt.keys))
+			for i, key := range tt.keys {
+				got[i] = names.Name(key, i)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("names = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateZip(t *testing.T) {
+	entries := []ZipEntry{
+		{Name: "b.geojson", Data: []byte(`{"id":"b"}`)},
+		{Name: "a.geojson", Data: []byte(`{"id":"a"}`)},
+		{Name: "empty.geojson"},
+	}
+
+	zipData, err := GenerateZip(entries)
+	if err != nil {
+		t.Fatalf("GenerateZip: %v", err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(zipData), int64(len(zipData)))
+	if err != nil {
+		t.Fatalf("reading zip: %v", err)
+	}
+
+	if len(reader.File) != len(entries) {
+		t.Fatalf("zip holds %d files, want %d", len(reader.File), len(entries))
+	}
+	for i, file := range reader.File {
+		if file.Name != entries[i].Name {
+			t.Errorf("file %d is %s, want %s", i, file.Name, entries[i].Name)
+		}
+		contents, err := file.Open()
+		if err != nil {
+			t.Fatalf("opening %s: %v", file.Name, err)
+		}
+		data, err := io.ReadAll(contents)
+		contents.Close()
+		if err != nil {
+			t.Fatalf("reading %s: %v", file.Name, err)
+		}
+		if !bytes.Equal(data, entries[i].Data) {
+			t.Errorf("%s holds %q, want %q", file.Name, data, entries[i].Data)
+		}
+	}
+}
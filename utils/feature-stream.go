@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ScanFeatureArray streams through a FeatureCollection payload and returns the raw
+// bytes of each element of its top-level "features" array without decoding them.
+// Elements are split on structural commas, so a syntax error inside one feature
+// only affects that element and the caller can decode the rest individually.
+func ScanFeatureArray(payload []byte) ([]json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feature collection: %v", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("feature collection must be a JSON object")
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read feature collection key: %v", err)
+		}
+		key, _ := token.(string)
+
+		if key == "features" {
+			return splitJSONArray(payload[decoder.InputOffset():])
+		}
+
+		// Skip the value of any other member
+		var skipped json.RawMessage
+		if err := decoder.Decode(&skipped); err != nil {
+			return nil, fmt.Errorf("failed to read feature collection member %q: %v", key, err)
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read feature collection: %v", err)
+	}
+	return []json.RawMessage{}, nil
+}
+
+// splitJSONArray splits the array that follows a member key (after the ':')
+// into its elements, tracking string and nesting state byte by byte
+func splitJSONArray(data []byte) ([]json.RawMessage, error) {
+	start := bytes.IndexByte(data, '[')
+	if start < 0 || len(bytes.TrimSpace(bytes.Trim(bytes.TrimSpace(data[:start]), ":"))) != 0 {
+		return nil, fmt.Errorf("features must be an array")
+	}
+
+	elements := make([]json.RawMessage, 0)
+	depth := 0
+	inString := false
+	escaped := false
+	elementStart := start + 1
+
+	for i := start + 1; i < len(data); i++ {
+		c := data[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch c {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			if depth == 0 && c == ']' {
+				if element := bytes.TrimSpace(data[elementStart:i]); len(element) > 0 {
+					elements = append(elements, json.RawMessage(element))
+				}
+				return elements, nil
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				elements = append(elements, json.RawMessage(bytes.TrimSpace(data[elementStart:i])))
+				elementStart = i + 1
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("unterminated features array")
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/twpayne/go-geos"
+)
+
+// EarthRadiusMeters is the mean Earth radius used for geodesic approximations
+const EarthRadiusMeters = 6371008.8
+
+// HaversineDistance returns the great-circle distance in meters between two WGS84 coordinates
+func HaversineDistance(lon1, lat1, lon2, lat2 float64) float64 {
+	phi1 := lat1 * math.Pi / 180
+	phi2 := lat2 * math.Pi / 180
+	deltaPhi := (lat2 - lat1) * math.Pi / 180
+	deltaLambda := (lon2 - lon1) * math.Pi / 180
+
+	a := math.Sin(deltaPhi/2)*math.Sin(deltaPhi/2) +
+		math.Cos(phi1)*math.Cos(phi2)*math.Sin(deltaLambda/2)*math.Sin(deltaLambda/2)
+	return 2 * EarthRadiusMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// GeodesicArea returns the approximate area in square meters of a WGS84 polygonal
+// geometry, using the spherical excess of each ring. Holes are subtracted and
+// non-polygonal geometries have zero area.
+func GeodesicArea(geom *geos.Geom) float64 {
+	if geom == nil || geom.IsEmpty() {
+		return 0
+	}
+
+	switch geom.TypeID() {
+	case geos.TypeIDPolygon:
+		area := math.Abs(ringArea(geom.ExteriorRing().CoordSeq()))
+		for i := range geom.NumInteriorRings() {
+			area -= math.Abs(ringArea(geom.InteriorRing(i).CoordSeq()))
+		}
+		return math.Max(area, 0)
+	case geos.TypeIDMultiPolygon, geos.TypeIDGeometryCollection:
+		area := 0.0
+		for i := range geom.NumGeometries() {
+			area += GeodesicArea(geom.Geometry(i))
+		}
+		return area
+	default:
+		return 0
+	}
+}
+
+// ringArea returns the signed spherical area of a closed ring in square meters
+func ringArea(coordSeq *geos.CoordSeq) float64 {
+	size := coordSeq.Size()
+	if size < 3 {
+		return 0
+	}
+
+	total := 0.0
+	for i := 0; i < size-1; i++ {
+		lambda1 := coordSeq.X(i) * math.Pi / 180
+		lambda2 := coordSeq.X(i+1) * math.Pi / 180
+		phi1 := coordSeq.Y(i) * math.Pi / 180
+		phi2 := coordSeq.Y(i+1) * math.Pi / 180
+		total += (lambda2 - lambda1) * (2 + math.Sin(phi1) + math.Sin(phi2))
+	}
+	return total * EarthRadiusMeters * EarthRadiusMeters / 2
+}
+
+// GeodesicDistance returns the great-circle distance in meters between the
+// nearest points of two WGS84 geometries, or zero if they intersect
+func GeodesicDistance(a, b *geos.Geom) float64 {
+	if a == nil || b == nil || a.IsEmpty() || b.IsEmpty() {
+		return 0
+	}
+	points := a.NearestPoints(b)
+	if len(points) != 2 {
+		return 0
+	}
+	return HaversineDistance(points[0][0], points[0][1], points[1][0], points[1][1])
+}
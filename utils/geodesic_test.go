@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+// closeTo reports whether got is within a relative tolerance of want
+func closeTo(got, want, relative float64) bool {
+	return math.Abs(got-want) <= relative*math.Max(math.Abs(want), 1)
+}
+
+func TestGeodesicArea(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+		want float64 // m², from R² Δλ (sin φ2 - sin φ1)
+	}{
+		{"one degree at the equator", "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))", 12363718145.180046},
+		{"clockwise ring", "POLYGON ((0 0, 0 1, 1 1, 1 0, 0 0))", 12363718145.180046},
+		{"one degree at 60°N", "POLYGON ((0 60, 1 60, 1 61, 0 61, 0 60))", 6088417933.464338},
+		{"parcel-sized cell", "POLYGON ((0 0, 0.001 0, 0.001 0.001, 0 0.001, 0 0))", 12364.34586751409},
+		{"hole subtracted", "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0), (0 0, 0.001 0, 0.001 0.001, 0 0.001, 0 0))", 12363718145.180046 - 12364.34586751409},
+		{"multipolygon parts added", "MULTIPOLYGON (((0 0, 1 0, 1 1, 0 1, 0 0)), ((0 60, 1 60, 1 61, 0 61, 0 60)))", 12363718145.180046 + 6088417933.464338},
+		{"line string has no area", "LINESTRING (0 0, 1 1)", 0},
+		{"empty polygon", "POLYGON EMPTY", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom := mustGeom(t, tt.wkt)
+			defer geom.Destroy()
+			if got := GeodesicArea(geom); !closeTo(got, tt.want, 1e-9) {
+				t.Errorf("GeodesicArea(%s) = %v m², want %v m²", tt.wkt, got, tt.want)
+			}
+		})
+	}
+
+	if area := GeodesicArea(nil); area != 0 {
+		t.Errorf("GeodesicArea(nil) = %v, want 0", area)
+	}
+}
+
+func TestHaversineDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lon1, lat1, lon2, lat2 float64
+		want                   float64
+	}{
+		{"same point", 5, 5, 5, 5, 0},
+		{"one degree along the equator", 0, 0, 1, 0, 111195.08023353292},
+		{"one degree along a meridian", 10, 0, 10, 1, 111195.08023353292},
+		{"equator to pole", 0, 0, 0, 90, 10007557.221017962},
+		{"one degree along 60°N is half as long", 0, 60, 1, 60, 55597.01086489692},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HaversineDistance(tt.lon1, tt.lat1, tt.lon2, tt.lat2)
+			if !closeTo(got, tt.want, 1e-6) {
+				t.Errorf("HaversineDistance = %v m, want %v m", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeodesicDistance(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want float64
+	}{
+		{"cells 0.0001° apart", "POLYGON ((0 0, 0.001 0, 0.001 0.001, 0 0.001, 0 0))", "POLYGON ((0.0011 0, 0.002 0, 0.002 0.001, 0.0011 0.001, 0.0011 0))", 11.119508023353292},
+		{"touching cells", "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))", "POLYGON ((1 0, 2 0, 2 1, 1 1, 1 0))", 0},
+		{"overlapping cells", "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))", "POLYGON ((0.5 0, 2 0, 2 1, 0.5 1, 0.5 0))", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := mustGeom(t, tt.a), mustGeom(t, tt.b)
+			defer a.Destroy()
+			defer b.Destroy()
+			if got := GeodesicDistance(a, b); !closeTo(got, tt.want, 1e-6) {
+				t.Errorf("GeodesicDistance = %v m, want %v m", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CloseRings ensures every polygon ring in a GeoJSON geometry ends with its first
+// coordinate, appending the closing point where it is missing. This works on the
+// GeoJSON rather than a GEOS geometry because GEOS refuses to construct a
+// polygon from an open ring at all. It returns the (possibly rewritten) geometry
+// and whether any ring was closed.
+func CloseRings(geometry json.RawMessage) (json.RawMessage, bool, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(geometry, &members); err != nil || members == nil {
+		return geometry, false, nil
+	}
+
+	var geomType string
+	if err := json.Unmarshal(members["type"], &geomType); err != nil {
+		return geometry, false, nil
+	}
+
+	changed := false
+	switch geomType {
+	case "Polygon":
+		var rings [][][]float64
+		if err := json.Unmarshal(members["coordinates"], &rings); err != nil {
+			return nil, false, fmt.Errorf("malformed polygon coordinates: %v", err)
+		}
+		if !closePolygonRings(rings) {
+			return geometry, false, nil
+		}
+		coordinates, err := json.Marshal(rings)
+		if err != nil {
+			return nil, false, err
+		}
+		members["coordinates"] = coordinates
+		changed = true
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err := json.Unmarshal(members["coordinates"], &polygons); err != nil {
+			return nil, false, fmt.Errorf("malformed multipolygon coordinates: %v", err)
+		}
+		for _, rings := range polygons {
+			if closePolygonRings(rings) {
+				changed = true
+			}
+		}
+		if !changed {
+			return geometry, false, nil
+		}
+		coordinates, err := json.Marshal(polygons)
+		if err != nil {
+			return nil, false, err
+		}
+		members["coordinates"] = coordinates
+	case "GeometryCollection":
+		var geometries []json.RawMessage
+		if err := json.Unmarshal(members["geometries"], &geometries); err != nil {
+			return geometry, false, nil
+		}
+		for i, child := range geometries {
+			closed, childChanged, err := CloseRings(child)
+			if err != nil {
+				return nil, false, err
+			}
+			if childChanged {
+				geometries[i] = closed
+				changed = true
+			}
+		}
+		if !changed {
+			return geometry, false, nil
+		}
+		encoded, err := json.Marshal(geometries)
+		if err != nil {
+			return nil, false, err
+		}
+		members["geometries"] = encoded
+	default:
+		return geometry, false, nil
+	}
+
+	closed, err := json.Marshal(members)
+	if err != nil {
+		return nil, false, err
+	}
+	return closed, changed, nil
+}
+
+// closePolygonRings appends the first coordinate to any open ring, reporting whether one changed
+func closePolygonRings(rings [][][]float64) bool {
+	changed := false
+	for i, ring := range rings {
+		if len(ring) < 2 {
+			continue
+		}
+
+		first, last := ring[0], ring[len(ring)-1]
+		if !coordinatesEqual(first, last) {
+			closing := make([]float64, len(first))
+			copy(closing, first)
+			rings[i] = append(ring, closing)
+			changed = true
+		}
+	}
+	return changed
+}
+
+func coordinatesEqual(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
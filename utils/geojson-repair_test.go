@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCloseRings(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		changed bool
+	}{
+		{
+			name:    "open polygon ring",
+			input:   `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`,
+			want:    `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`,
+			changed: true,
+		},
+		{
+			name:    "closed polygon ring",
+			input:   `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`,
+			want:    `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`,
+			changed: false,
+		},
+		{
+			name:    "open hole",
+			input:   `{"type":"Polygon","coordinates":[[[0,0],[4,0],[4,4],[0,4],[0,0]],[[1,1],[1,2],[2,2],[2,1]]]}`,
+			want:    `{"type":"Polygon","coordinates":[[[0,0],[4,0],[4,4],[0,4],[0,0]],[[1,1],[1,2],[2,2],[2,1],[1,1]]]}`,
+			changed: true,
+		},
+		{
+			name:    "multipolygon with one open ring",
+			input:   `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,2],[3,2],[3,3]]]]}`,
+			want:    `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]],[[[2,2],[3,2],[3,3],[2,2]]]]}`,
+			changed: true,
+		},
+		{
+			name:    "open ring inside a geometry collection",
+			input:   `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[5,5]},{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1]]]}]}`,
+			want:    `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[5,5]},{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}]}`,
+			changed: true,
+		},
+		{
+			name:    "line string is left alone",
+			input:   `{"type":"LineString","coordinates":[[0,0],[1,1]]}`,
+			want:    `{"type":"LineString","coordinates":[[0,0],[1,1]]}`,
+			changed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, err := CloseRings(json.RawMessage(tt.input))
+			if err != nil {
+				t.Fatalf("CloseRings: %v", err)
+			}
+			if changed != tt.changed {
+				t.Errorf("changed = %v, want %v", changed, tt.changed)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestCloseRingsMalformedCoordinates(t *testing.T) {
+	if _, _, err := CloseRings(json.RawMessage(`{"type":"Polygon","coordinates":"nope"}`)); err == nil {
+		t.Error("CloseRings accepted malformed polygon coordinates")
+	}
+}
+
+// assertJSONEqual fails the test unless got and want decode to the same value
+func assertJSONEqual(t testing.TB, got json.RawMessage, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("decoding %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("decoding %s: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// geoJSONGeometryTypes lists the geometry types defined by RFC 7946
+var geoJSONGeometryTypes = map[string]bool{
+	"Point":              true,
+	"MultiPoint":         true,
+	"LineString":         true,
+	"MultiLineString":    true,
+	"Polygon":            true,
+	"MultiPolygon":       true,
+	"GeometryCollection": true,
+}
+
+// coordinateDepths is the array nesting depth of each geometry type's coordinates
+var coordinateDepths = map[string]int{
+	"Point":           1,
+	"MultiPoint":      2,
+	"LineString":      2,
+	"MultiLineString": 3,
+	"Polygon":         3,
+	"MultiPolygon":    4,
+}
+
+// IsNullGeometry reports whether a feature's geometry is absent or JSON null,
+// which RFC 7946 allows for attribute-only features
+func IsNullGeometry(geometry json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(geometry)
+	return len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null"))
+}
+
+// IsPolygonalGeometry reports whether a GeoJSON geometry is a Polygon or MultiPolygon
+func IsPolygonalGeometry(geometry json.RawMessage) bool {
+	var geom struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(geometry, &geom); err != nil {
+		return false
+	}
+	return geom.Type == "Polygon" || geom.Type == "MultiPolygon"
+}
+
+// ValidateGeoJSONGeometry performs a cheap structural check of a GeoJSON geometry
+// so malformed input gets a precise message rather than an opaque GEOS error
+func ValidateGeoJSONGeometry(geometry json.RawMessage) error {
+	if IsNullGeometry(geometry) {
+		return fmt.Errorf("null geometry")
+	}
+	trimmed := bytes.TrimSpace(geometry)
+
+	var geom struct {
+		Type        string          `json:"type"`
+		Coordinates json.RawMessage `json:"coordinates"`
+		Geometries  json.RawMessage `json:"geometries"`
+	}
+	if err := json.Unmarshal(trimmed, &geom); err != nil {
+		return fmt.Errorf("malformed geometry: %v", err)
+	}
+
+	if geom.Type == "" {
+		return fmt.Errorf("missing geometry type")
+	}
+	if !geoJSONGeometryTypes[geom.Type] {
+		return fmt.Errorf("unrecognized geometry type %q", geom.Type)
+	}
+
+	if geom.Type == "GeometryCollection" {
+		if isEmptyJSONArray(geom.Geometries) {
+			return fmt.Errorf("missing geometries")
+		}
+		return CheckCoordinateNesting(trimmed)
+	}
+
+	if isEmptyJSONArray(geom.Coordinates) {
+		return fmt.Errorf("missing coordinates")
+	}
+
+	return CheckCoordinateNesting(trimmed)
+}
+
+// CheckCoordinateNesting reports a geometry whose coordinates are nested more or
+// less deeply than its type requires, most commonly Polygon coordinates posted
+// as a MultiPolygon or the reverse. GEOS only rejects these with an opaque
+// parse error, so the message names both the declared and the apparent type.
+// Geometries whose depth cannot be determined (e.g. empty arrays) pass.
+func CheckCoordinateNesting(geometry json.RawMessage) error {
+	var geom struct {
+		Type        string            `json:"type"`
+		Coordinates json.RawMessage   `json:"coordinates"`
+		Geometries  []json.RawMessage `json:"geometries"`
+	}
+	if err := json.Unmarshal(geometry, &geom); err != nil {
+		return nil
+	}
+
+	if geom.Type == "GeometryCollection" {
+		for i, member := range geom.Geometries {
+			if err := CheckCoordinateNesting(member); err != nil {
+				return fmt.Errorf("geometry %d: %v", i, err)
+			}
+		}
+		return nil
+	}
+
+	expected, ok := coordinateDepths[geom.Type]
+	if !ok {
+		return nil
+	}
+	depth, ok := coordinateDepth(geom.Coordinates)
+	if !ok || depth == expected {
+		return nil
+	}
+
+	switch {
+	case geom.Type == "MultiPolygon" && depth == 3:
+		return fmt.Errorf("type is MultiPolygon but coordinates are Polygon-shaped (nested 3 deep, expected 4): wrap them in another array or set type to Polygon")
+	case geom.Type == "Polygon" && depth == 4:
+		return fmt.Errorf("type is Polygon but coordinates are MultiPolygon-shaped (nested 4 deep, expected 3): remove the outer array or set type to MultiPolygon")
+	}
+	return fmt.Errorf("%s coordinates are nested %d deep, expected %d", geom.Type, depth, expected)
+}
+
+// coordinateDepth counts the array nesting of a coordinates value down to its
+// first number. It returns false when the first leaf is not a number, such as
+// for an empty array, since the depth is then undefined.
+func coordinateDepth(coordinates json.RawMessage) (int, bool) {
+	depth := 0
+	for _, c := range bytes.TrimSpace(coordinates) {
+		switch {
+		case c == '[':
+			depth++
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		case c == '-' || (c >= '0' && c <= '9'):
+			return depth, depth > 0
+		default:
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// isEmptyJSONArray reports whether a raw JSON value is absent, null or an empty array
+func isEmptyJSONArray(value json.RawMessage) bool {
+	trimmed := bytes.TrimSpace(value)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		return true
+	}
+	if trimmed[0] != '[' {
+		return true
+	}
+	return len(bytes.TrimSpace(trimmed[1:len(trimmed)-1])) == 0
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestValidateGeoJSONGeometry(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry string
+		wantErr  string
+	}{
+		{
+			name:     "polygon",
+			geometry: `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`,
+		},
+		{
+			name:     "multipolygon",
+			geometry: `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]]]}`,
+		},
+		{
+			name:     "multipolygon with polygon coordinates",
+			geometry: `{"type":"MultiPolygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}`,
+			wantErr:  "type is MultiPolygon but coordinates are Polygon-shaped",
+		},
+		{
+			name:     "polygon with multipolygon coordinates",
+			geometry: `{"type":"Polygon","coordinates":[[[[0,0],[1,0],[1,1],[0,0]]]]}`,
+			wantErr:  "type is Polygon but coordinates are MultiPolygon-shaped",
+		},
+		{
+			name:     "point nested as a line string",
+			geometry: `{"type":"Point","coordinates":[[0,0]]}`,
+			wantErr:  "Point coordinates are nested 2 deep, expected 1",
+		},
+		{
+			name:     "mismatch inside a geometry collection",
+			geometry: `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[0,0]},{"type":"MultiPolygon","coordinates":[[[0,0],[1,0],[1,1],[0,0]]]}]}`,
+			wantErr:  "geometry 1: type is MultiPolygon",
+		},
+		{
+			name:     "null",
+			geometry: `null`,
+			wantErr:  "null geometry",
+		},
+		{
+			name:     "missing coordinates",
+			geometry: `{"type":"Polygon","coordinates":[]}`,
+			wantErr:  "missing coordinates",
+		},
+		{
+			name:     "unknown type",
+			geometry: `{"type":"Circle","coordinates":[0,0]}`,
+			wantErr:  `unrecognized geometry type "Circle"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateGeoJSONGeometry(json.RawMessage(tt.geometry))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateGeoJSONGeometry: unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateGeoJSONGeometry error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
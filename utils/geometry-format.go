@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"strings"
+
+	"github.com/twpayne/go-geos"
+)
+
+// Geometry encodings accepted by endpoints that take a bare geometry
+const (
+	GeometryFormatGeoJSON = "geojson"
+	GeometryFormatWKT     = "wkt"
+	GeometryFormatWKB     = "wkb"
+)
+
+// GeometryFormat picks the encoding of a request body: an explicit format
+// option wins, otherwise the Content-Type decides, defaulting to GeoJSON
+func GeometryFormat(format string, contentType string) (string, error) {
+	if format != "" {
+		switch format = strings.ToLower(format); format {
+		case GeometryFormatGeoJSON, GeometryFormatWKT, GeometryFormatWKB:
+			return format, nil
+		default:
+			return "", fmt.Errorf("unsupported geometry format %q (expected geojson, wkt or wkb)", format)
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/wkt", "text/wkt", "text/plain":
+		return GeometryFormatWKT, nil
+	case "application/wkb", "application/octet-stream":
+		return GeometryFormatWKB, nil
+	default:
+		return GeometryFormatGeoJSON, nil
+	}
+}
+
+// ParseGeometry creates a GEOS geometry from a payload in the given format.
+// WKB may be raw binary or hex encoded, as Postgres tooling usually emits it.
+func ParseGeometry(payload []byte, format string) (*geos.Geom, error) {
+	switch format {
+	case GeometryFormatWKT:
+		return geos.NewGeomFromWKT(strings.TrimSpace(string(payload)))
+	case GeometryFormatWKB:
+		wkb := payload
+		if trimmed := strings.TrimSpace(string(payload)); isHex(trimmed) {
+			decoded, err := hex.DecodeString(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hex WKB: %v", err)
+			}
+			wkb = decoded
+		}
+		return geos.NewGeomFromWKB(wkb)
+	default:
+		return geos.NewGeomFromGeoJSON(string(payload))
+	}
+}
+
+// isHex reports whether s is a non-empty, even-length string of hex digits
+func isHex(s string) bool {
+	if s == "" || len(s)%2 != 0 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatGeometry encodes geom in the given format, the counterpart of
+// ParseGeometry. WKB is hex encoded, as Postgres tooling emits it. With GEOS
+// 3.12 or later WKT and WKB carry M ordinates; GeoJSON has no room for them.
+func FormatGeometry(geom *geos.Geom, format string) []byte {
+	switch format {
+	case GeometryFormatWKT:
+		return []byte(geom.ToWKT())
+	case GeometryFormatWKB:
+		return []byte(strings.ToUpper(hex.EncodeToString(geom.ToWKB())))
+	default:
+		return []byte(geom.ToGeoJSON(-1))
+	}
+}
+
+// GeometryContentType is the Content-Type of a geometry encoded by FormatGeometry
+func GeometryContentType(format string) string {
+	switch format {
+	case GeometryFormatWKT:
+		return "text/wkt"
+	case GeometryFormatWKB:
+		return "application/wkb"
+	default:
+		return "application/geo+json"
+	}
+}
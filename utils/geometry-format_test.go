@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestGeometryFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		format      string
+		contentType string
+		want        string
+		wantErr     bool
+	}{
+		{name: "default", want: GeometryFormatGeoJSON},
+		{name: "geojson content type", contentType: "application/geo+json", want: GeometryFormatGeoJSON},
+		{name: "wkt content type", contentType: "text/wkt", want: GeometryFormatWKT},
+		{name: "plain text is wkt", contentType: "text/plain; charset=utf-8", want: GeometryFormatWKT},
+		{name: "wkb content type", contentType: "application/wkb", want: GeometryFormatWKB},
+		{name: "binary is wkb", contentType: "application/octet-stream", want: GeometryFormatWKB},
+		{name: "format option wins", format: "WKT", contentType: "application/octet-stream", want: GeometryFormatWKT},
+		{name: "unknown format option", format: "kml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := GeometryFormat(tt.format, tt.contentType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GeometryFormat error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GeometryFormat(%q, %q) = %q, want %q", tt.format, tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGeometry(t *testing.T) {
+	// POINT (1 2) as little-endian WKB
+	const pointHex = "0101000000000000000000F03F0000000000000040"
+	pointWKB, err := hex.DecodeString(pointHex)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		payload []byte
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{name: "geojson", payload: []byte(`{"type":"Point","coordinates":[1,2]}`), format: GeometryFormatGeoJSON, want: "POINT (1 2)"},
+		{name: "wkt", payload: []byte("  POINT (1 2)\n"), format: GeometryFormatWKT, want: "POINT (1 2)"},
+		{name: "wkt polygon", payload: []byte("POLYGON ((0 0, 1 0, 1 1, 0 0))"), format: GeometryFormatWKT, want: "POLYGON ((0 0, 1 0, 1 1, 0 0))"},
+		{name: "hex wkb", payload: []byte(pointHex + "\n"), format: GeometryFormatWKB, want: "POINT (1 2)"},
+		{name: "lower-case hex wkb", payload: []byte("0101000000000000000000f03f0000000000000040"), format: GeometryFormatWKB, want: "POINT (1 2)"},
+		{name: "raw wkb", payload: pointWKB, format: GeometryFormatWKB, want: "POINT (1 2)"},
+		{name: "malformed wkt", payload: []byte("POINT (1"), format: GeometryFormatWKT, wantErr: true},
+		{name: "malformed wkb", payload: []byte("0101"), format: GeometryFormatWKB, wantErr: true},
+		{name: "geojson sent as wkt", payload: []byte(`{"type":"Point","coordinates":[1,2]}`), format: GeometryFormatWKT, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom, err := ParseGeometry(tt.payload, tt.format)
+			if tt.wantErr {
+				if err == nil {
+					geom.Destroy()
+					t.Error("ParseGeometry accepted a malformed payload")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseGeometry: %v", err)
+			}
+			defer geom.Destroy()
+
+			want := mustGeom(t, tt.want)
+			defer want.Destroy()
+			if !geom.EqualsExact(want, 0) {
+				t.Errorf("ParseGeometry = %s, want %s", geom.ToWKT(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// DefaultOutputName is the base name of generated zips and their members when
+// the request supplies none
+const DefaultOutputName = "cleaned_topology"
+
+// maxOutputNameLength keeps derived names well inside filesystem limits
+const maxOutputNameLength = 100
+
+// OutputBaseName derives a safe base name for generated files from a
+// client-supplied name such as an uploaded filename: any directory part and
+// extensions (including .gz) are dropped, and characters other than letters,
+// digits, '-' and '_' are replaced with '_'. It returns DefaultOutputName
+// when nothing usable remains.
+func OutputBaseName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if index := strings.Index(name, "."); index >= 0 {
+		name = name[:index]
+	}
+
+	base := sanitizeName(name)
+	if base == "" {
+		return DefaultOutputName
+	}
+	return base
+}
+
+// sanitizeName replaces characters other than ASCII letters, digits, '-' and
+// '_' with '_', trims leading and trailing '_' and '-' and caps the length
+func sanitizeName(name string) string {
+	var builder strings.Builder
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_') {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+
+	base := strings.Trim(builder.String(), "_-")
+	if len(base) > maxOutputNameLength {
+		base = base[:maxOutputNameLength]
+	}
+	return base
+}
+
+// ResolveOutputPath maps a client-supplied input file path onto a file inside
+// outputDir. Paths under inputDir keep their layout relative to it
+// (<inputDir>/a/b.json -> <outputDir>/a/b<suffix>); other relative paths are
+// placed under outputDir as given. The extension is replaced with suffix.
+// Absolute paths outside inputDir and paths that escape outputDir via ".."
+// are rejected.
+func ResolveOutputPath(inputDir string, outputDir string, clientPath string, suffix string) (string, error) {
+	if clientPath == "" {
+		return "", fmt.Errorf("no filepath supplied")
+	}
+
+	root, err := filepath.Abs(outputDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve output directory: %w", err)
+	}
+
+	relative, ok := "", false
+	if inputDir != "" {
+		relative, ok = pathWithin(inputDir, clientPath)
+	}
+	if !ok {
+		if filepath.IsAbs(clientPath) || strings.HasPrefix(filepath.ToSlash(clientPath), "/") || filepath.VolumeName(clientPath) != "" {
+			return "", fmt.Errorf("filepath %q must be relative or inside the input directory", clientPath)
+		}
+		relative, ok = pathWithin(".", clientPath)
+	}
+	if !ok || relative == "." {
+		return "", fmt.Errorf("filepath %q escapes the output directory", clientPath)
+	}
+
+	relative = strings.TrimSuffix(relative, filepath.Ext(relative)) + suffix
+	target := filepath.Join(root, relative)
+
+	// Belt and braces: the joined path must still live under the output directory
+	if _, ok := pathWithin(root, target); !ok {
+		return "", fmt.Errorf("filepath %q escapes the output directory", clientPath)
+	}
+
+	return target, nil
+}
+
+// ResolveInputPath checks that a client-supplied file path to read from lies
+// inside inputDir and returns it as an absolute path. Paths outside inputDir,
+// including relative paths that climb out of it via "..", are rejected.
+func ResolveInputPath(inputDir string, clientPath string) (string, error) {
+	if clientPath == "" {
+		return "", fmt.Errorf("no filepath supplied")
+	}
+	if inputDir == "" {
+		return "", fmt.Errorf("reading from filepath is disabled: no input directory configured")
+	}
+
+	relative, ok := pathWithin(inputDir, clientPath)
+	if !ok || relative == "." {
+		return "", fmt.Errorf("filepath %q must be inside the input directory", clientPath)
+	}
+
+	root, err := filepath.Abs(inputDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve input directory: %w", err)
+	}
+	return filepath.Join(root, relative), nil
+}
+
+// pathWithin returns path relative to dir, reporting false if it lies outside dir.
+// Relative arguments are resolved against the working directory.
+func pathWithin(dir string, path string) (string, bool) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	absPath, err := filepath.Abs(filepath.FromSlash(path))
+	if err != nil {
+		return "", false
+	}
+
+	relative, err := filepath.Rel(absDir, absPath)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return relative, true
+}
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveOutputPathRejectsTraversal(t *testing.T) {
+	inputDir := filepath.Join(t.TempDir(), "files")
+	outputDir := filepath.Join(t.TempDir(), "output")
+
+	tests := []struct {
+		name       string
+		clientPath string
+	}{
+		{"parent directories", "../../etc/passwd"},
+		{"parent directories after a subdirectory", "parcels/../../../etc/cron.d/job.json"},
+		{"absolute path outside the input directory", "/etc/passwd"},
+		{"input directory then parent", filepath.Join(inputDir, "..", "..", "outside.json")},
+		{"parent only", ".."},
+		{"current directory", "."},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := ResolveOutputPath(inputDir, outputDir, tt.clientPath, ".zip")
+			if err == nil {
+				t.Errorf("ResolveOutputPath(%q) = %q, want an error", tt.clientPath, target)
+			}
+		})
+	}
+}
+
+func TestResolveOutputPath(t *testing.T) {
+	inputDir := filepath.Join(t.TempDir(), "files")
+	outputDir := filepath.Join(t.TempDir(), "output")
+
+	tests := []struct {
+		name       string
+		clientPath string
+		suffix     string
+		want       string
+	}{
+		{"file in the input directory", filepath.Join(inputDir, "parcels.json"), "_cleaned.json", "parcels_cleaned.json"},
+		{"nested file in the input directory", filepath.Join(inputDir, "2024", "q1", "parcels.geojson"), ".zip", filepath.Join("2024", "q1", "parcels.zip")},
+		{"relative path", "parcels.json", ".zip", "parcels.zip"},
+		{"relative nested path", "county/parcels.json", ".zip", filepath.Join("county", "parcels.zip")},
+		{"relative path through a parent that stays inside", "county/../parcels.json", ".zip", "parcels.zip"},
+		{"only the last extension is replaced", "parcels.v2.json", ".zip", "parcels.v2.zip"},
+		{"no extension", "parcels", ".zip", "parcels.zip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := ResolveOutputPath(inputDir, outputDir, tt.clientPath, tt.suffix)
+			if err != nil {
+				t.Fatalf("ResolveOutputPath(%q): %v", tt.clientPath, err)
+			}
+			if want := filepath.Join(outputDir, tt.want); target != want {
+				t.Errorf("ResolveOutputPath(%q) = %q, want %q", tt.clientPath, target, want)
+			}
+		})
+	}
+}
+
+func TestResolveOutputPathWithoutInputDirectory(t *testing.T) {
+	outputDir := t.TempDir()
+
+	if _, err := ResolveOutputPath("", outputDir, "/data/files/parcels.json", ".zip"); err == nil {
+		t.Error("accepted an absolute path with no input directory configured")
+	}
+	target, err := ResolveOutputPath("", outputDir, "parcels.json", ".zip")
+	if err != nil {
+		t.Fatalf("ResolveOutputPath: %v", err)
+	}
+	if want := filepath.Join(outputDir, "parcels.zip"); target != want {
+		t.Errorf("target = %q, want %q", target, want)
+	}
+}
+
+func TestOutputBaseName(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"uploaded filename", "parcels.geojson", "parcels"},
+		{"gzipped upload", "Parcels 2024.geojson.gz", "Parcels_2024"},
+		{"unix directories", "../../etc/passwd", "passwd"},
+		{"windows directories", `C:\data\parcels.shp`, "parcels"},
+		{"quote injection", `a"; filename=evil.exe`, "a___filename_evil"},
+		{"non-ascii", "räksmörgås.json", "r_ksm_rg_s"},
+		{"hidden file", ".hidden", DefaultOutputName},
+		{"nothing usable", "***.json", DefaultOutputName},
+		{"empty", "", DefaultOutputName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OutputBaseName(tt.input); got != tt.want {
+				t.Errorf("OutputBaseName(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveInputPath(t *testing.T) {
+	inputDir := filepath.Join(t.TempDir(), "files")
+
+	tests := []struct {
+		name       string
+		inputDir   string
+		clientPath string
+		want       string // empty when the path is rejected
+	}{
+		{"file in the input directory", inputDir, filepath.Join(inputDir, "parcels.json"), "parcels.json"},
+		{"nested file in the input directory", inputDir, filepath.Join(inputDir, "2024", "parcels.json"), filepath.Join("2024", "parcels.json")},
+		{"parent directories", inputDir, "../../etc/passwd", ""},
+		{"absolute path outside the input directory", inputDir, "/etc/passwd", ""},
+		{"input directory then parent", inputDir, filepath.Join(inputDir, "..", "outside.json"), ""},
+		{"input directory itself", inputDir, inputDir, ""},
+		{"empty", inputDir, "", ""},
+		{"no input directory configured", "", filepath.Join(inputDir, "parcels.json"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := ResolveInputPath(tt.inputDir, tt.clientPath)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("ResolveInputPath(%q) = %q, want an error", tt.clientPath, target)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveInputPath(%q): %v", tt.clientPath, err)
+			}
+			if want := filepath.Join(inputDir, tt.want); target != want {
+				t.Errorf("ResolveInputPath(%q) = %q, want %q", tt.clientPath, target, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PartLimits caps the number of polygon parts a request may carry, so a
+// payload with millions of tiny MultiPolygon parts is rejected before any
+// geometry work starts. A zero limit disables that check.
+type PartLimits struct {
+	PerFeature int
+	PerRequest int
+}
+
+// PartLimitError reports which limit a payload exceeded. Feature is the
+// position of the offending feature, or -1 when the request as a whole is
+// over PerRequest.
+type PartLimitError struct {
+	Feature int
+	Parts   int
+	Limit   int
+}
+
+func (e *PartLimitError) Error() string {
+	if e.Feature < 0 {
+		return fmt.Sprintf("request has %d polygon parts, more than the limit of %d", e.Parts, e.Limit)
+	}
+	return fmt.Sprintf("feature %d has %d polygon parts, more than the limit of %d", e.Feature, e.Parts, e.Limit)
+}
+
+// PerRequest reports whether the request total, rather than a single feature, exceeded its limit
+func (e *PartLimitError) PerRequest() bool {
+	return e.Feature < 0
+}
+
+// partCountObject decodes just enough of any GeoJSON object to count its
+// polygon parts; coordinates are kept raw so rings are never parsed
+type partCountObject struct {
+	Type        string            `json:"type"`
+	Features    []partCountObject `json:"features"`
+	Geometry    *partCountObject  `json:"geometry"`
+	Geometries  []partCountObject `json:"geometries"`
+	Coordinates []json.RawMessage `json:"coordinates"`
+	Layers      []partCountObject `json:"layers"`
+}
+
+// CheckPartLimits counts the polygon parts of a FeatureCollection, Feature,
+// bare geometry or multi-layer ({"layers": [...]}) payload and returns a
+// *PartLimitError if any feature, or the payload in total, carries more than
+// limits allow. A bare geometry counts as feature 0, and features of a
+// multi-layer payload are numbered across all layers in order. Payloads that
+// are not valid JSON pass unchecked, leaving the caller's own parsing to
+// report the error.
+func CheckPartLimits(payload string, limits PartLimits) error {
+	if limits.PerFeature <= 0 && limits.PerRequest <= 0 {
+		return nil
+	}
+
+	var object partCountObject
+	if err := json.Unmarshal([]byte(payload), &object); err != nil {
+		return nil
+	}
+
+	geometries := []*partCountObject{&object}
+	switch object.Type {
+	case "FeatureCollection":
+		geometries = make([]*partCountObject, len(object.Features))
+		for i := range object.Features {
+			geometries[i] = object.Features[i].Geometry
+		}
+	case "Feature":
+		geometries = []*partCountObject{object.Geometry}
+	case "":
+		if object.Layers != nil {
+			geometries = make([]*partCountObject, 0)
+			for _, layer := range object.Layers {
+				for i := range layer.Features {
+					geometries = append(geometries, layer.Features[i].Geometry)
+				}
+			}
+		}
+	}
+
+	total := 0
+	for i, geometry := range geometries {
+		parts := countPolygonParts(geometry)
+		if limits.PerFeature > 0 && parts > limits.PerFeature {
+			return &PartLimitError{Feature: i, Parts: parts, Limit: limits.PerFeature}
+		}
+		total += parts
+	}
+	if limits.PerRequest > 0 && total > limits.PerRequest {
+		return &PartLimitError{Feature: -1, Parts: total, Limit: limits.PerRequest}
+	}
+	return nil
+}
+
+func countPolygonParts(geometry *partCountObject) int {
+	if geometry == nil {
+		return 0
+	}
+
+	switch geometry.Type {
+	case "Polygon":
+		return 1
+	case "MultiPolygon":
+		return len(geometry.Coordinates)
+	case "GeometryCollection":
+		parts := 0
+		for i := range geometry.Geometries {
+			parts += countPolygonParts(&geometry.Geometries[i])
+		}
+		return parts
+	default:
+		return 0
+	}
+}
@@ -1,8 +1,14 @@
 package utils
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
 	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/twpayne/go-geos"
 )
@@ -23,67 +29,74 @@ type Features struct {
 }
 
 
-var PRECISION int = 7
+// DefaultPrecision is the number of decimal places coordinates are truncated to
+// when a request does not specify one (7 decimals is roughly 1cm in WGS84)
+const DefaultPrecision = 7
+
+// DefaultQuadSegs is the number of segments used to approximate a quarter circle
+// in buffer operations. Higher values give smoother, more accurate curves at the
+// cost of more vertices and slower downstream operations. The buffers in the
+// cleaning pipeline are only used for proximity tests, so lower values (e.g. 2-4)
+// noticeably speed up large jobs for a sub-centimetre loss of accuracy.
+const DefaultQuadSegs = 8
 
 // func createGoRoutine(polygon *geos.Geom) (*geos.Geom, error) {
 
 // }
 
-func TruncateFullGeometry(feature *geos.Geom) (*geos.Geom, error) {
+// TruncateFullGeometry rounds every polygon in the geometry to the given number
+// of decimal places. Precision is passed explicitly so concurrent requests with
+// different settings never share state.
+func TruncateFullGeometry(feature *geos.Geom, precision int) (*geos.Geom, error) {
 	if feature == nil {
 		return nil, fmt.Errorf(`geometry is nil`)
 	}
 
-	// Count total number of polygons to process
-	totalPolygons := 0
+	// Gather the polygons to process
+	parts := make([]*geos.Geom, 0)
 	for i := range feature.NumGeometries() {
 		geometry := feature.Geometry(i)
 		if geometry.IsValid() {
 			if geometry.TypeID() == 3 {
-				totalPolygons++
+				parts = append(parts, geometry)
 			} else if geometry.TypeID() == 6 {
 				for j := range geometry.NumGeometries() {
 					singlePolygon := geometry.Geometry(j)
 					if singlePolygon.TypeID() == 3 {
-						totalPolygons++
+						parts = append(parts, singlePolygon)
 					}
 				}
 			}
 		}
 	}
 
-	if totalPolygons == 0 {
+	if len(parts) == 0 {
 		return nil, fmt.Errorf("no valid polygons found")
 	}
 
-	// Create channel with correct buffer size
-	polygons := make(chan *geos.Geom, totalPolygons)
-	
-	// Launch goroutines to process polygons
-	for i := range feature.NumGeometries() {
-		geometry := feature.Geometry(i)
-		if geometry.IsValid() {
-			if geometry.TypeID() == 3 {
-				go func(polygon *geos.Geom) {
-					polygons <- TruncateSinglePolygon(polygon)
-				}(geometry)
-			} else if geometry.TypeID() == 6 {
-				for j := range geometry.NumGeometries() {
-					singlePolygon := geometry.Geometry(j)
-					if singlePolygon.TypeID() == 3 {
-						go func(polygon *geos.Geom) {
-							polygons <- TruncateSinglePolygon(polygon)
-						}(singlePolygon)
-					}
-				}
+	// A fixed number of workers keeps a MultiPolygon with a huge part count from
+	// spawning a goroutine per part; results keep the input part order
+	truncated := make([]*geos.Geom, len(parts))
+	next := make(chan int, len(parts))
+	for i := range parts {
+		next <- i
+	}
+	close(next)
+
+	var wg sync.WaitGroup
+	for range min(runtime.NumCPU(), len(parts)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				truncated[i] = TruncateSinglePolygon(parts[i], precision)
 			}
-		}
+		}()
 	}
+	wg.Wait()
 
-	// Collect results from all goroutines
-	var newPolygons = make([]*geos.Geom, 0, totalPolygons)
-	for i := 0; i < totalPolygons; i++ {
-		result := <-polygons
+	var newPolygons = make([]*geos.Geom, 0, len(parts))
+	for _, result := range truncated {
 		if result != nil {
 			newPolygons = append(newPolygons, result)
 		}
@@ -100,54 +113,285 @@ func TruncateFullGeometry(feature *geos.Geom) (*geos.Geom, error) {
 	return geos.NewCollection(geos.TypeIDMultiPolygon, newPolygons), nil
 }
 
-func TruncateSinglePolygon(polygon *geos.Geom) *geos.Geom {
-	var rings [][][]float64
-	var outerRing [][]float64
-	if polygon.ExteriorRing() != nil && polygon.ExteriorRing().CoordSeq().Size() > 3 {
-		// fmt.Println("in ext ring")
-		for j := range polygon.ExteriorRing().CoordSeq().Size() {
-			x := polygon.ExteriorRing().CoordSeq().X(j)
-			y := polygon.ExteriorRing().CoordSeq().Y(j)
-
-			newX, newY := truncateCoordinates(x, y)
-			outerRing = append(outerRing, []float64{newX, newY})
-		}
-		rings = append(rings, outerRing)
-		outerRing = nil
-		// fmt.Println("interior", polygon.NumInteriorRings())
-		if polygon.NumInteriorRings() > 0 {
-			for r := range polygon.NumInteriorRings() {
-				var ringCoords [][]float64
-				ring := polygon.InteriorRing(r)
-				if ring.CoordSeq().Size() > 3 {
-					for k := range ring.CoordSeq().Size() {
-						x := ring.CoordSeq().X(k)
-						y := ring.CoordSeq().Y(k)
-
-						newX, newY := truncateCoordinates(x, y)
-						ringCoords = append(ringCoords, []float64{newX, newY})
-					}
-					testPolygon := geos.NewPolygon([][][]float64{ringCoords})
-					if len(ringCoords) > 0 && testPolygon.IsValid() {
-						rings = append(rings, ringCoords)
-					}
-					ringCoords = nil
-					testPolygon.Destroy()
-				}
+// TruncateSinglePolygon rounds a polygon's rings to precision decimal places.
+// Interior rings that are degenerate or invalid once rounded are dropped. If
+// the exterior ring is degenerate (too few coordinates to close an area), the
+// largest valid interior ring is promoted to the exterior so the polygon's
+// area survives as far as possible; it returns nil only when no ring is
+// usable. Every discarded ring is logged.
+func TruncateSinglePolygon(polygon *geos.Geom, precision int) *geos.Geom {
+	exterior := polygon.ExteriorRing()
+	if exterior != nil && exterior.CoordSeq().Size() > 3 {
+		rings := [][][]float64{truncateRing(exterior, precision)}
+		for r := range polygon.NumInteriorRings() {
+			ringCoords, ok := validTruncatedRing(polygon.InteriorRing(r), precision)
+			if !ok {
+				log.Printf("Dropping degenerate or invalid interior ring %d while truncating polygon", r)
+				continue
 			}
+			rings = append(rings, ringCoords)
 		}
 
 		return geos.NewPolygon(rings)
 	}
 
-	return nil
+	// The exterior cannot form an area; keep the largest usable interior ring instead
+	var promoted [][]float64
+	promotedArea := 0.0
+	for r := range polygon.NumInteriorRings() {
+		ringCoords, ok := validTruncatedRing(polygon.InteriorRing(r), precision)
+		if !ok {
+			continue
+		}
+		candidate := geos.NewPolygon([][][]float64{ringCoords})
+		if area := candidate.Area(); area > promotedArea {
+			promoted, promotedArea = ringCoords, area
+		}
+		candidate.Destroy()
+	}
+
+	if promoted == nil {
+		log.Printf("Dropping polygon with a degenerate exterior ring and %d unusable interior ring(s)", polygon.NumInteriorRings())
+		return nil
+	}
+
+	log.Printf("Polygon has a degenerate exterior ring; promoting its largest interior ring (of %d) to the exterior", polygon.NumInteriorRings())
+	return geos.NewPolygon([][][]float64{promoted})
 }
 
-func truncateCoordinates(x float64, y float64) (float64, float64) {
-	return roundFloat(x, uint(PRECISION)), roundFloat(y, uint(PRECISION))
+// truncateRing returns a ring's coordinates rounded to precision decimal places
+func truncateRing(ring *geos.Geom, precision int) [][]float64 {
+	coordSeq := ring.CoordSeq()
+	ringCoords := make([][]float64, 0, coordSeq.Size())
+	for k := range coordSeq.Size() {
+		newX, newY := truncateCoordinates(coordSeq.X(k), coordSeq.Y(k), precision)
+		ringCoords = append(ringCoords, []float64{newX, newY})
+	}
+	return ringCoords
+}
+
+// validTruncatedRing rounds a ring and reports whether it still forms a valid polygon on its own
+func validTruncatedRing(ring *geos.Geom, precision int) ([][]float64, bool) {
+	if ring == nil || ring.CoordSeq().Size() <= 3 {
+		return nil, false
+	}
+
+	ringCoords := truncateRing(ring, precision)
+	testPolygon := geos.NewPolygon([][][]float64{ringCoords})
+	defer testPolygon.Destroy()
+	if !testPolygon.IsValid() {
+		return nil, false
+	}
+	return ringCoords, true
+}
+
+func truncateCoordinates(x float64, y float64, precision int) (float64, float64) {
+	return roundFloat(x, uint(precision)), roundFloat(y, uint(precision))
 }
 
 func roundFloat(val float64, precision uint) float64 {
 	ratio := math.Pow(10, float64(precision))
 	return math.Round(val*ratio) / ratio
 }
+
+// RoundGeoJSONCoordinates rounds every coordinate of a GeoJSON geometry to
+// precision decimal places. It works on the serialized GeoJSON so output can be
+// emitted at a lower precision than geometries are processed at, without
+// another pass through GEOS.
+func RoundGeoJSONCoordinates(geometry json.RawMessage, precision int) (json.RawMessage, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(geometry, &members); err != nil {
+		return nil, fmt.Errorf("malformed geometry: %v", err)
+	}
+
+	if rawCoordinates, ok := members["coordinates"]; ok {
+		var coordinates interface{}
+		if err := json.Unmarshal(rawCoordinates, &coordinates); err != nil {
+			return nil, fmt.Errorf("malformed coordinates: %v", err)
+		}
+		rounded, err := json.Marshal(roundCoordinates(coordinates, uint(precision)))
+		if err != nil {
+			return nil, err
+		}
+		members["coordinates"] = rounded
+	}
+
+	if rawGeometries, ok := members["geometries"]; ok {
+		var geometries []json.RawMessage
+		if err := json.Unmarshal(rawGeometries, &geometries); err != nil {
+			return nil, fmt.Errorf("malformed geometries: %v", err)
+		}
+		for i, member := range geometries {
+			rounded, err := RoundGeoJSONCoordinates(member, precision)
+			if err != nil {
+				return nil, err
+			}
+			geometries[i] = rounded
+		}
+		rounded, err := json.Marshal(geometries)
+		if err != nil {
+			return nil, err
+		}
+		members["geometries"] = rounded
+	}
+
+	return json.Marshal(members)
+}
+
+// roundCoordinates rounds the numbers of an arbitrarily nested coordinate array
+func roundCoordinates(value interface{}, precision uint) interface{} {
+	switch v := value.(type) {
+	case float64:
+		return roundFloat(v, precision)
+	case []interface{}:
+		for i := range v {
+			v[i] = roundCoordinates(v[i], precision)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// BBox returns geom's bounds as an RFC 7946 bbox [minX, minY, maxX, maxY],
+// rounded to precision decimal places unless precision is negative. Empty
+// geometries have no bbox and return nil.
+func BBox(geom *geos.Geom, precision int) []float64 {
+	if geom == nil || geom.IsEmpty() {
+		return nil
+	}
+
+	bounds := geom.Bounds()
+	bbox := []float64{bounds.MinX, bounds.MinY, bounds.MaxX, bounds.MaxY}
+	if precision >= 0 {
+		for i := range bbox {
+			bbox[i] = roundFloat(bbox[i], uint(precision))
+		}
+	}
+	return bbox
+}
+
+// MergeBBox returns the smallest bbox covering both a and b; either may be nil
+func MergeBBox(a, b []float64) []float64 {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return []float64{math.Min(a[0], b[0]), math.Min(a[1], b[1]), math.Max(a[2], b[2]), math.Max(a[3], b[3])}
+}
+
+// Force2D returns a copy of the geometry with any Z/M ordinates removed
+func Force2D(geom *geos.Geom) *geos.Geom {
+	if geom == nil {
+		return nil
+	}
+
+	if geom.IsEmpty() {
+		return geom.Clone()
+	}
+
+	switch geom.TypeID() {
+	case geos.TypeIDPoint:
+		return geos.NewPoint([]float64{geom.X(), geom.Y()})
+	case geos.TypeIDLineString:
+		return geos.NewLineString(coords2D(geom.CoordSeq()))
+	case geos.TypeIDLinearRing:
+		return geos.NewLinearRing(coords2D(geom.CoordSeq()))
+	case geos.TypeIDPolygon:
+		rings := [][][]float64{coords2D(geom.ExteriorRing().CoordSeq())}
+		for i := range geom.NumInteriorRings() {
+			rings = append(rings, coords2D(geom.InteriorRing(i).CoordSeq()))
+		}
+		return geos.NewPolygon(rings)
+	default:
+		parts := make([]*geos.Geom, 0, geom.NumGeometries())
+		for i := range geom.NumGeometries() {
+			parts = append(parts, Force2D(geom.Geometry(i)))
+		}
+		return geos.NewCollection(geom.TypeID(), parts)
+	}
+}
+
+// Explode returns a copy of each single part of a multi-part geometry,
+// descending into nested collections. Empty parts are dropped, and a single
+// geometry is returned as a one-element slice. The caller owns the results.
+func Explode(geom *geos.Geom) []*geos.Geom {
+	if geom == nil || geom.IsEmpty() {
+		return nil
+	}
+
+	switch geom.TypeID() {
+	case geos.TypeIDMultiPoint, geos.TypeIDMultiLineString, geos.TypeIDMultiPolygon, geos.TypeIDGeometryCollection:
+		parts := make([]*geos.Geom, 0, geom.NumGeometries())
+		for i := range geom.NumGeometries() {
+			parts = append(parts, Explode(geom.Geometry(i))...)
+		}
+		return parts
+	default:
+		return []*geos.Geom{geom.Clone()}
+	}
+}
+
+func coords2D(coordSeq *geos.CoordSeq) [][]float64 {
+	coords := make([][]float64, coordSeq.Size())
+	for i := range coordSeq.Size() {
+		coords[i] = []float64{coordSeq.X(i), coordSeq.Y(i)}
+	}
+	return coords
+}
+
+// Coordinates returns every vertex of the geometry as [x, y] pairs
+func Coordinates(geom *geos.Geom) [][]float64 {
+	if geom == nil || geom.IsEmpty() {
+		return nil
+	}
+
+	switch geom.TypeID() {
+	case geos.TypeIDPoint:
+		return [][]float64{{geom.X(), geom.Y()}}
+	case geos.TypeIDLineString, geos.TypeIDLinearRing:
+		return coords2D(geom.CoordSeq())
+	case geos.TypeIDPolygon:
+		coords := coords2D(geom.ExteriorRing().CoordSeq())
+		for i := range geom.NumInteriorRings() {
+			coords = append(coords, coords2D(geom.InteriorRing(i).CoordSeq())...)
+		}
+		return coords
+	default:
+		var coords [][]float64
+		for i := range geom.NumGeometries() {
+			coords = append(coords, Coordinates(geom.Geometry(i))...)
+		}
+		return coords
+	}
+}
+
+// InvalidityLocation extracts the point GEOS reports an invalid geometry at,
+// from the trailing "[x y]" of an IsValidReason such as
+// "Self-intersection[3.5 2.5]", as a GeoJSON Point object. It reports false
+// when the reason carries no finite location.
+func InvalidityLocation(reason string) (map[string]interface{}, bool) {
+	start := strings.LastIndex(reason, "[")
+	end := strings.LastIndex(reason, "]")
+	if start < 0 || end < start {
+		return nil, false
+	}
+
+	fields := strings.Fields(reason[start+1 : end])
+	if len(fields) < 2 {
+		return nil, false
+	}
+	x, errX := strconv.ParseFloat(fields[0], 64)
+	y, errY := strconv.ParseFloat(fields[1], 64)
+	// Invalid Coordinate reasons report NaN, which JSON cannot carry
+	if errX != nil || errY != nil || math.IsNaN(x) || math.IsNaN(y) || math.IsInf(x, 0) || math.IsInf(y, 0) {
+		return nil, false
+	}
+
+	return map[string]interface{}{
+		"type":        "Point",
+		"coordinates": []float64{x, y},
+	}, true
+}
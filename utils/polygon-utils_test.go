@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+)
+
+// mustGeom parses WKT, failing the test on error
+func mustGeom(t testing.TB, wkt string) *geos.Geom {
+	t.Helper()
+	geom, err := geos.NewGeomFromWKT(wkt)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", wkt, err)
+	}
+	return geom
+}
+
+func TestForce2D(t *testing.T) {
+	tests := []struct {
+		name string
+		wkt  string
+	}{
+		{"point", "POINT Z (1 2 3)"},
+		{"line string", "LINESTRING Z (0 0 1, 1 1 2)"},
+		{"polygon", "POLYGON Z ((0 0 5, 1 0 5, 1 1 5, 0 1 5, 0 0 5))"},
+		{"polygon with hole", "POLYGON Z ((0 0 5, 4 0 5, 4 4 5, 0 4 5, 0 0 5), (1 1 5, 1 2 5, 2 2 5, 2 1 5, 1 1 5))"},
+		{"multipolygon", "MULTIPOLYGON Z (((0 0 1, 1 0 1, 1 1 1, 0 0 1)), ((2 2 1, 3 2 1, 3 3 1, 2 2 1)))"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom := mustGeom(t, tt.wkt)
+			defer geom.Destroy()
+			if !geom.HasZ() {
+				t.Fatalf("input %s has no Z", tt.wkt)
+			}
+
+			flattened := Force2D(geom)
+			defer flattened.Destroy()
+			if flattened.HasZ() {
+				t.Errorf("Force2D(%s) = %s, still has Z", tt.wkt, flattened.ToWKT())
+			}
+			if flattened.TypeID() != geom.TypeID() {
+				t.Errorf("Force2D changed the type from %v to %v", geom.TypeID(), flattened.TypeID())
+			}
+			if !flattened.Equals(geom) {
+				t.Errorf("Force2D(%s) = %s, want the same X/Y", tt.wkt, flattened.ToWKT())
+			}
+		})
+	}
+}
+
+// TestTruncateFullGeometryConcurrentPrecisions truncates geometries at mixed
+// precisions from many goroutines at once. Run it with -race: precision was a
+// package variable, and concurrent requests must not see each other's setting.
+func TestTruncateFullGeometryConcurrentPrecisions(t *testing.T) {
+	const (
+		wkt        = "MULTIPOLYGON (((0.123456789 0.123456789, 1.123456789 0.123456789, 1.123456789 1.123456789, 0.123456789 1.123456789, 0.123456789 0.123456789)), ((2.987654321 0.987654321, 3.987654321 0.987654321, 3.987654321 1.987654321, 2.987654321 0.987654321)))"
+		goroutines = 32
+		iterations = 20
+	)
+	precisions := []int{2, 4, 7, 9}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, goroutines*iterations)
+	for g := range goroutines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range iterations {
+				precision := precisions[(g+i)%len(precisions)]
+				if err := checkTruncation(wkt, precision); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// checkTruncation truncates wkt to precision and checks every vertex was
+// rounded to exactly that many decimals
+func checkTruncation(wkt string, precision int) error {
+	geom, err := geos.NewGeomFromWKT(wkt)
+	if err != nil {
+		return err
+	}
+	defer geom.Destroy()
+
+	truncated, err := TruncateFullGeometry(geom, precision)
+	if err != nil {
+		return fmt.Errorf("precision %d: %v", precision, err)
+	}
+	defer truncated.Destroy()
+
+	if truncated.NumGeometries() != geom.NumGeometries() {
+		return fmt.Errorf("precision %d: %d polygons, want %d", precision, truncated.NumGeometries(), geom.NumGeometries())
+	}
+	for p := range geom.NumGeometries() {
+		want := geom.Geometry(p).ExteriorRing().CoordSeq()
+		got := truncated.Geometry(p).ExteriorRing().CoordSeq()
+		for k := range want.Size() {
+			wantX, wantY := roundFloat(want.X(k), uint(precision)), roundFloat(want.Y(k), uint(precision))
+			if got.X(k) != wantX || got.Y(k) != wantY {
+				return fmt.Errorf("precision %d: vertex %d of polygon %d = (%v, %v), want (%v, %v)", precision, k, p, got.X(k), got.Y(k), wantX, wantY)
+			}
+		}
+	}
+	return nil
+}
+
+// decimalsPattern matches the fractional digits of a JSON number
+var decimalsPattern = regexp.MustCompile(`\.(\d+)`)
+
+// maxDecimals returns the most fractional digits of any number in data
+func maxDecimals(data []byte) int {
+	most := 0
+	for _, match := range decimalsPattern.FindAllSubmatch(data, -1) {
+		most = max(most, len(match[1]))
+	}
+	return most
+}
+
+func TestRoundGeoJSONCoordinates(t *testing.T) {
+	tests := []struct {
+		name      string
+		geometry  string
+		precision int
+		want      string
+	}{
+		{
+			name:      "point",
+			geometry:  `{"type":"Point","coordinates":[1.23456789,-2.98765432]}`,
+			precision: 3,
+			want:      `{"type":"Point","coordinates":[1.235,-2.988]}`,
+		},
+		{
+			name:      "polygon",
+			geometry:  `{"type":"Polygon","coordinates":[[[0.1234567,0],[1.7654321,0],[1.7654321,1.1111111],[0.1234567,0]]]}`,
+			precision: 6,
+			want:      `{"type":"Polygon","coordinates":[[[0.123457,0],[1.765432,0],[1.765432,1.111111],[0.123457,0]]]}`,
+		},
+		{
+			name:      "zero decimals",
+			geometry:  `{"type":"LineString","coordinates":[[10.4,20.6],[30.5,40.49]]}`,
+			precision: 0,
+			want:      `{"type":"LineString","coordinates":[[10,21],[31,40]]}`,
+		},
+		{
+			name:      "geometry collection",
+			geometry:  `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1.55555,2.44444]}]}`,
+			precision: 2,
+			want:      `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[1.56,2.44]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rounded, err := RoundGeoJSONCoordinates(json.RawMessage(tt.geometry), tt.precision)
+			if err != nil {
+				t.Fatalf("RoundGeoJSONCoordinates: %v", err)
+			}
+			if decimals := maxDecimals(rounded); decimals > tt.precision {
+				t.Errorf("output has %d decimals, want at most %d: %s", decimals, tt.precision, rounded)
+			}
+			assertJSONEqual(t, rounded, tt.want)
+		})
+	}
+}
+
+func TestExplode(t *testing.T) {
+	tests := []struct {
+		name  string
+		wkt   string
+		parts []string
+	}{
+		{
+			name:  "three-part multipolygon",
+			wkt:   "MULTIPOLYGON (((0 0, 1 0, 1 1, 0 0)), ((2 0, 3 0, 3 1, 2 0)), ((4 0, 5 0, 5 1, 4 0)))",
+			parts: []string{"POLYGON ((0 0, 1 0, 1 1, 0 0))", "POLYGON ((2 0, 3 0, 3 1, 2 0))", "POLYGON ((4 0, 5 0, 5 1, 4 0))"},
+		},
+		{
+			name:  "single polygon",
+			wkt:   "POLYGON ((0 0, 1 0, 1 1, 0 0))",
+			parts: []string{"POLYGON ((0 0, 1 0, 1 1, 0 0))"},
+		},
+		{
+			name:  "nested collection",
+			wkt:   "GEOMETRYCOLLECTION (POINT (9 9), GEOMETRYCOLLECTION (LINESTRING (0 0, 1 1), MULTIPOINT ((5 5), (6 6))))",
+			parts: []string{"POINT (9 9)", "LINESTRING (0 0, 1 1)", "POINT (5 5)", "POINT (6 6)"},
+		},
+		{
+			name:  "empty parts are dropped",
+			wkt:   "GEOMETRYCOLLECTION (POLYGON EMPTY, POINT (1 1))",
+			parts: []string{"POINT (1 1)"},
+		},
+		{
+			name: "empty geometry",
+			wkt:  "MULTIPOLYGON EMPTY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom := mustGeom(t, tt.wkt)
+			defer geom.Destroy()
+
+			parts := Explode(geom)
+			defer func() {
+				for _, part := range parts {
+					part.Destroy()
+				}
+			}()
+
+			if len(parts) != len(tt.parts) {
+				t.Fatalf("Explode gave %d parts, want %d", len(parts), len(tt.parts))
+			}
+			for i, wkt := range tt.parts {
+				want := mustGeom(t, wkt)
+				if !parts[i].EqualsExact(want, 0) {
+					t.Errorf("part %d = %s, want %s", i, parts[i].ToWKT(), wkt)
+				}
+				want.Destroy()
+			}
+		})
+	}
+}
+
+func TestTruncateSinglePolygon(t *testing.T) {
+	tests := []struct {
+		name      string
+		wkt       string
+		precision int
+		want      string // empty when the polygon is dropped
+	}{
+		{
+			name:      "exterior rounded",
+			wkt:       "POLYGON ((0.04 0.04, 9.96 0, 10 9.96, 0 10, 0.04 0.04))",
+			precision: 1,
+			want:      "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))",
+		},
+		{
+			name:      "hole kept",
+			wkt:       "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 3, 3 3, 3 2, 2 2))",
+			precision: 2,
+			want:      "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 3, 3 3, 3 2, 2 2))",
+		},
+		{
+			name:      "hole collapsed by rounding is dropped",
+			wkt:       "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (1 1, 1.001 1, 1.001 1.001, 1 1), (2 2, 2 3, 3 3, 3 2, 2 2))",
+			precision: 2,
+			want:      "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 3, 3 3, 3 2, 2 2))",
+		},
+		{
+			// GEOS refuses to build a polygon whose holes sit in an empty or
+			// sub-four-point exterior, so an empty polygon is the degenerate
+			// exterior that can reach TruncateSinglePolygon
+			name:      "empty exterior without usable holes",
+			wkt:       "POLYGON EMPTY",
+			precision: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polygon := mustGeom(t, tt.wkt)
+			defer polygon.Destroy()
+
+			truncated := TruncateSinglePolygon(polygon, tt.precision)
+			if tt.want == "" {
+				if truncated != nil {
+					t.Errorf("TruncateSinglePolygon = %s, want nil", truncated.ToWKT())
+					truncated.Destroy()
+				}
+				return
+			}
+			if truncated == nil {
+				t.Fatalf("TruncateSinglePolygon dropped the polygon, want %s", tt.want)
+			}
+			defer truncated.Destroy()
+
+			want := mustGeom(t, tt.want)
+			defer want.Destroy()
+			if !truncated.EqualsExact(want, 0) {
+				t.Errorf("TruncateSinglePolygon = %s, want %s", truncated.ToWKT(), tt.want)
+			}
+		})
+	}
+}
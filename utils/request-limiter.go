@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"log"
+	"net/http"
+	"time"
+)
+
+// RequestLimiter caps the number of heavy requests processed concurrently.
+// Every heavy request already fans out to NumCPU workers, so running many at
+// once oversubscribes the CPU; excess requests wait in a queue instead.
+type RequestLimiter struct {
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+// NewRequestLimiter creates a limiter allowing maxConcurrent requests at once.
+// Queued requests give up after queueTimeout; a zero timeout rejects immediately
+// when every slot is busy.
+func NewRequestLimiter(maxConcurrent int, queueTimeout time.Duration) *RequestLimiter {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+
+	return &RequestLimiter{
+		slots:        make(chan struct{}, maxConcurrent),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Acquire waits for a free slot and reports whether one was obtained
+func (rl *RequestLimiter) Acquire(r *http.Request) bool {
+	select {
+	case rl.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if rl.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(rl.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case rl.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// Release frees a slot obtained with Acquire
+func (rl *RequestLimiter) Release() {
+	<-rl.slots
+}
+
+// InFlight returns the number of requests currently holding a slot
+func (rl *RequestLimiter) InFlight() int {
+	return len(rl.slots)
+}
+
+// Limit wraps a handler so it only runs while holding a slot, responding with
+// 503 Service Unavailable when no slot frees up within the queue timeout
+func (rl *RequestLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !rl.Acquire(r) {
+			log.Printf("Rejecting %s: %d requests already processing", r.URL.Path, rl.InFlight())
+			w.Header().Set("Retry-After", "30")
+			http.Error(w, "Server busy, try again later", http.StatusServiceUnavailable)
+			return
+		}
+		defer rl.Release()
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRequestLimiterCapsConcurrency(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxConcurrent int
+		requests      int
+	}{
+		{"one at a time", 1, 10},
+		{"two at a time", 2, 20},
+		{"more slots than requests", 8, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRequestLimiter(tt.maxConcurrent, time.Minute)
+
+			var running, peak int32
+			handler := limiter.Limit(func(w http.ResponseWriter, r *http.Request) {
+				current := atomic.AddInt32(&running, 1)
+				for {
+					seen := atomic.LoadInt32(&peak)
+					if current <= seen || atomic.CompareAndSwapInt32(&peak, seen, current) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+			})
+
+			var wg sync.WaitGroup
+			statuses := make([]int, tt.requests)
+			for i := range tt.requests {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					recorder := httptest.NewRecorder()
+					handler(recorder, httptest.NewRequest(http.MethodPost, "/clean-topology", nil))
+					statuses[i] = recorder.Code
+				}()
+			}
+			wg.Wait()
+
+			if peak > int32(tt.maxConcurrent) {
+				t.Errorf("%d requests ran at once, want at most %d", peak, tt.maxConcurrent)
+			}
+			for i, status := range statuses {
+				if status != http.StatusOK {
+					t.Errorf("request %d got status %d, want queued until served", i, status)
+				}
+			}
+			if inFlight := limiter.InFlight(); inFlight != 0 {
+				t.Errorf("InFlight() = %d after all requests finished, want 0", inFlight)
+			}
+		})
+	}
+}
+
+func TestRequestLimiterRejectsWhenBusy(t *testing.T) {
+	const maxConcurrent = 2
+	limiter := NewRequestLimiter(maxConcurrent, 0)
+
+	release := make(chan struct{})
+	handler := limiter.Limit(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	// Fill every slot, then check further requests are turned away at once
+	var wg sync.WaitGroup
+	for range maxConcurrent {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/clean-topology", nil))
+		}()
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for limiter.InFlight() < maxConcurrent {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d of %d slots filled", limiter.InFlight(), maxConcurrent)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest(http.MethodPost, "/clean-topology", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d while every slot is busy", recorder.Code, http.StatusServiceUnavailable)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Error("missing Retry-After header on a rejected request")
+	}
+
+	close(release)
+	wg.Wait()
+}
@@ -1,24 +1,39 @@
 package utils
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type MultipartResult struct {
 	File       string
 	Properties Properties
+	// Err is a *DecompressedSizeError when a gzip body or upload inflated past
+	// the cap; other read errors leave the result empty as before
+	Err error
 }
 
 type Properties struct {
+	// FileName is the client's name for the uploaded file, if any
+	FileName          string
 	FilePath          string
 	SaveFile          bool
 	FeatureCollection string
 }
 
-func ReadMultiPartForm(r *http.Request, fileKey string) MultipartResult {
-	r.ParseMultipartForm(999999999999999)
+// ReadMultiPartForm parses a multipart request and reads the fileKey upload.
+// An upload named .gz, or starting with the gzip magic number, is
+// decompressed up to maxDecompressedBytes; zero or less means no cap.
+func ReadMultiPartForm(r *http.Request, fileKey string, maxDecompressedBytes int64) MultipartResult {
+	parseErr := r.ParseMultipartForm(999999999999999)
 	var fileHeader *multipart.FileHeader
 	result := MultipartResult{
 		File: "",
@@ -29,6 +44,12 @@ func ReadMultiPartForm(r *http.Request, fileKey string) MultipartResult {
 		},
 	}
 	
+	var sizeErr *DecompressedSizeError
+	if errors.As(parseErr, &sizeErr) {
+		result.Err = sizeErr
+		return result
+	}
+
 	// Check if MultipartForm was successfully parsed
 	if r.MultipartForm != nil {
 		for key, value := range r.MultipartForm.File {
@@ -57,6 +78,7 @@ func ReadMultiPartForm(r *http.Request, fileKey string) MultipartResult {
 	}
 
 	if fileHeader != nil {
+		result.Properties.FileName = fileHeader.Filename
 
 		file, _ := fileHeader.Open()
 
@@ -64,8 +86,203 @@ func ReadMultiPartForm(r *http.Request, fileKey string) MultipartResult {
 
 		fullFile, _ := io.ReadAll(file)
 
+		// Uploads named .gz (or carrying the gzip magic number) are decompressed transparently
+		if strings.HasSuffix(strings.ToLower(fileHeader.Filename), ".gz") || isGzip(fullFile) {
+			decompressed, err := gunzip(fullFile, maxDecompressedBytes)
+			if errors.As(err, &sizeErr) {
+				log.Printf("Rejecting uploaded file %s: %v", fileHeader.Filename, err)
+				result.Err = sizeErr
+				return result
+			} else if err != nil {
+				log.Printf("Failed to decompress uploaded file %s: %v", fileHeader.Filename, err)
+				fullFile = nil
+			} else {
+				fullFile = decompressed
+			}
+		}
+
 		result.File = string(fullFile)
 	}
 
 	return result
 }
+
+// RequestOptions holds optional processing flags supplied as URL query
+// parameters or multipart form values
+type RequestOptions map[string]string
+
+// ReadRequestOptions collects options from the URL query and, when the request
+// has already been parsed as multipart, from the form values. Form values take
+// precedence over query parameters with the same key.
+func ReadRequestOptions(r *http.Request) RequestOptions {
+	options := RequestOptions{}
+
+	for key, value := range r.URL.Query() {
+		if len(value) > 0 {
+			options[key] = value[0]
+		}
+	}
+
+	if r.MultipartForm != nil {
+		for key, value := range r.MultipartForm.Value {
+			if len(value) > 0 {
+				options[key] = value[0]
+			}
+		}
+	}
+
+	return options
+}
+
+// Bool returns the option as a boolean, or defaultValue if it is missing or malformed
+func (o RequestOptions) Bool(key string, defaultValue bool) bool {
+	value, ok := o[key]
+	if !ok {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// String returns the option value, or defaultValue if it is missing or empty
+func (o RequestOptions) String(key string, defaultValue string) string {
+	value, ok := o[key]
+	if !ok || value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// Float returns the option as a float, or defaultValue if it is missing or malformed
+func (o RequestOptions) Float(key string, defaultValue float64) float64 {
+	value, ok := o[key]
+	if !ok {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// Int returns the option as an integer, or defaultValue if it is missing or malformed
+func (o RequestOptions) Int(key string, defaultValue int) int {
+	value, ok := o[key]
+	if !ok {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// List returns the option as a comma-separated list with surrounding spaces
+// and empty entries removed, or nil if it is missing or empty
+func (o RequestOptions) List(key string) []string {
+	var list []string
+	for _, value := range strings.Split(o[key], ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			list = append(list, value)
+		}
+	}
+	return list
+}
+
+// DecompressedSizeError is returned while reading a gzip body or upload that
+// inflates to more than Limit bytes, so a small gzip bomb cannot exhaust memory
+type DecompressedSizeError struct {
+	Limit int64
+}
+
+func (e *DecompressedSizeError) Error() string {
+	return fmt.Sprintf("decompressed request is larger than the limit of %d bytes", e.Limit)
+}
+
+// DecompressRequestBody replaces the body of a request sent with
+// Content-Encoding: gzip by its decompressed stream, so the body (including a
+// multipart form) can be read as if it had been sent uncompressed. Reading
+// more than maxBytes decompressed bytes fails with a *DecompressedSizeError;
+// zero or less means no cap.
+func DecompressRequestBody(r *http.Request, maxBytes int64) error {
+	if !strings.EqualFold(strings.TrimSpace(r.Header.Get("Content-Encoding")), "gzip") {
+		return nil
+	}
+
+	reader, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return fmt.Errorf("invalid gzip request body: %v", err)
+	}
+
+	r.Body = &gzipBody{Reader: reader, limited: newDecompressionLimiter(reader, maxBytes), body: r.Body}
+	r.Header.Del("Content-Encoding")
+	r.ContentLength = -1
+	return nil
+}
+
+// gzipBody reads the decompressed stream through its size cap and closes both
+// the gzip reader and the underlying request body
+type gzipBody struct {
+	*gzip.Reader
+	limited io.Reader
+	body    io.ReadCloser
+}
+
+func (g *gzipBody) Read(p []byte) (int, error) {
+	return g.limited.Read(p)
+}
+
+func (g *gzipBody) Close() error {
+	g.Reader.Close()
+	return g.body.Close()
+}
+
+// decompressionLimiter fails reads once more than limit bytes have been read,
+// like http.MaxBytesReader does for the raw body
+type decompressionLimiter struct {
+	reader io.Reader
+	read   int64
+	limit  int64
+}
+
+// newDecompressionLimiter caps reader at limit bytes; zero or less leaves it uncapped
+func newDecompressionLimiter(reader io.Reader, limit int64) io.Reader {
+	if limit <= 0 {
+		return reader
+	}
+	// One byte past the limit is enough to tell that it was exceeded
+	return &decompressionLimiter{reader: io.LimitReader(reader, limit+1), limit: limit}
+}
+
+func (l *decompressionLimiter) Read(p []byte) (int, error) {
+	n, err := l.reader.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n - int(l.read-l.limit), &DecompressedSizeError{Limit: l.limit}
+	}
+	return n, err
+}
+
+// isGzip reports whether data starts with the gzip magic number
+func isGzip(data []byte) bool {
+	return len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b
+}
+
+// gunzip decompresses a complete gzip payload of at most maxBytes decompressed
+// bytes; zero or less means no cap
+func gunzip(data []byte, maxBytes int64) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(newDecompressionLimiter(reader, maxBytes))
+}
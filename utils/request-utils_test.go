@@ -0,0 +1,141 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const gzipTestCollection = `{"type":"FeatureCollection","features":[{"type":"Feature","geometry":{"type":"Point","coordinates":[0,0]},"properties":{}}]}`
+
+// gzipBytes compresses data, failing the test on error
+func gzipBytes(t testing.TB, data string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buffer.Bytes()
+}
+
+func TestDecompressRequestBody(t *testing.T) {
+	size := int64(len(gzipTestCollection))
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		maxBytes int64
+		want     string
+		wantErr  bool
+		tooLarge bool
+	}{
+		{name: "plain body is left alone", body: []byte(gzipTestCollection), maxBytes: 10, want: gzipTestCollection},
+		{name: "gzip body", body: gzipBytes(t, gzipTestCollection), encoding: "gzip", maxBytes: size, want: gzipTestCollection},
+		{name: "gzip body without a cap", body: gzipBytes(t, gzipTestCollection), encoding: " GZIP ", maxBytes: 0, want: gzipTestCollection},
+		{name: "gzip body over the cap", body: gzipBytes(t, gzipTestCollection), encoding: "gzip", maxBytes: size - 1, tooLarge: true},
+		{name: "invalid gzip body", body: []byte("not gzip"), encoding: "gzip", maxBytes: size, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/clean-topology", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				request.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			err := DecompressRequestBody(request, tt.maxBytes)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("DecompressRequestBody accepted an invalid gzip body")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DecompressRequestBody: %v", err)
+			}
+			if encoding := request.Header.Get("Content-Encoding"); encoding != "" {
+				t.Errorf("Content-Encoding = %q after decompression, want it removed", encoding)
+			}
+
+			body, err := io.ReadAll(request.Body)
+			var sizeErr *DecompressedSizeError
+			if tt.tooLarge {
+				if !errors.As(err, &sizeErr) || sizeErr.Limit != tt.maxBytes {
+					t.Errorf("reading the body: error %v, want a *DecompressedSizeError with limit %d", err, tt.maxBytes)
+				}
+				if int64(len(body)) > tt.maxBytes {
+					t.Errorf("read %d bytes, more than the limit of %d", len(body), tt.maxBytes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reading the body: %v", err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("body = %q, want %q", body, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadMultiPartFormGzipUpload(t *testing.T) {
+	size := int64(len(gzipTestCollection))
+
+	tests := []struct {
+		name     string
+		fileName string
+		content  []byte
+		maxBytes int64
+		want     string
+		tooLarge bool
+	}{
+		{name: "plain upload", fileName: "parcels.geojson", content: []byte(gzipTestCollection), maxBytes: 1, want: gzipTestCollection},
+		{name: "upload named .gz", fileName: "parcels.geojson.gz", content: gzipBytes(t, gzipTestCollection), maxBytes: size, want: gzipTestCollection},
+		{name: "gzip upload without the extension", fileName: "parcels.geojson", content: gzipBytes(t, gzipTestCollection), maxBytes: size, want: gzipTestCollection},
+		{name: "upload over the cap", fileName: "parcels.geojson.gz", content: gzipBytes(t, gzipTestCollection), maxBytes: size / 2, tooLarge: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body bytes.Buffer
+			form := multipart.NewWriter(&body)
+			part, err := form.CreateFormFile("geometry", tt.fileName)
+			if err != nil {
+				t.Fatalf("CreateFormFile: %v", err)
+			}
+			part.Write(tt.content)
+			form.Close()
+
+			request := httptest.NewRequest(http.MethodPost, "/clean-topology", &body)
+			request.Header.Set("Content-Type", form.FormDataContentType())
+
+			result := ReadMultiPartForm(request, "geometry", tt.maxBytes)
+			var sizeErr *DecompressedSizeError
+			if tt.tooLarge {
+				if !errors.As(result.Err, &sizeErr) {
+					t.Errorf("Err = %v, want a *DecompressedSizeError", result.Err)
+				}
+				return
+			}
+			if result.Err != nil {
+				t.Fatalf("Err = %v", result.Err)
+			}
+			if result.File != tt.want {
+				t.Errorf("File = %q, want %q", result.File, tt.want)
+			}
+			if result.Properties.FileName != tt.fileName {
+				t.Errorf("FileName = %q, want %q", result.Properties.FileName, tt.fileName)
+			}
+		})
+	}
+}
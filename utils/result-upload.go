@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ResultUploader stores a response body at a destination and returns the
+// location it was stored at, safe to show to the client
+type ResultUploader interface {
+	Upload(ctx context.Context, destination *url.URL, contentType string, data []byte) (string, error)
+}
+
+// ResultUploaders uploads results to client-named destinations within an
+// allow list, choosing an uploader by URL scheme. http and https destinations,
+// such as presigned S3 PUT URLs, are uploaded with a plain PUT. Schemes that
+// need a cloud SDK, such as s3://, have no built-in uploader and must be
+// added with Register, which keeps those dependencies out of the default build.
+type ResultUploaders struct {
+	*URLAllowList
+	uploaders map[string]ResultUploader
+}
+
+// NewResultUploaders creates uploaders for destinations allowed by allowList,
+// with the built-in PUT uploader for http and https bounded by timeout
+func NewResultUploaders(allowList *URLAllowList, timeout time.Duration) *ResultUploaders {
+	put := &httpPutUploader{client: &http.Client{Timeout: timeout, CheckRedirect: allowList.checkRedirect}}
+	return &ResultUploaders{
+		URLAllowList: allowList,
+		uploaders:    map[string]ResultUploader{"http": put, "https": put},
+	}
+}
+
+// Register sets the uploader for destinations with the given URL scheme
+func (ru *ResultUploaders) Register(scheme string, uploader ResultUploader) {
+	ru.uploaders[strings.ToLower(scheme)] = uploader
+}
+
+// CheckDestination reports whether rawURL can be uploaded to, so a request
+// can be rejected before any processing
+func (ru *ResultUploaders) CheckDestination(rawURL string) (*url.URL, error) {
+	if !ru.Enabled() {
+		return nil, fmt.Errorf("destinationUrl is not enabled on this server")
+	}
+	destination, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destinationUrl: %v", err)
+	}
+	if err := ru.Check(destination); err != nil {
+		return nil, fmt.Errorf("destinationUrl %v", err)
+	}
+	if ru.uploaders[strings.ToLower(destination.Scheme)] == nil {
+		return nil, fmt.Errorf("destinationUrl scheme %q has no uploader in this build", destination.Scheme)
+	}
+	return destination, nil
+}
+
+// Upload stores data at rawURL, returning the stored location
+func (ru *ResultUploaders) Upload(ctx context.Context, rawURL string, contentType string, data []byte) (string, error) {
+	destination, err := ru.CheckDestination(rawURL)
+	if err != nil {
+		return "", err
+	}
+	return ru.uploaders[strings.ToLower(destination.Scheme)].Upload(ctx, destination, contentType, data)
+}
+
+// httpPutUploader uploads with a single HTTP PUT, as presigned URLs expect
+type httpPutUploader struct {
+	client *http.Client
+}
+
+func (u *httpPutUploader) Upload(ctx context.Context, destination *url.URL, contentType string, data []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, destination.String(), bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid destinationUrl: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to destinationUrl: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("failed to upload to destinationUrl: %s", resp.Status)
+	}
+
+	// The query of a presigned URL is its signature, which must not be echoed back
+	location := *destination
+	location.User = nil
+	location.RawQuery = ""
+	location.Fragment = ""
+	return location.String(), nil
+}
@@ -5,8 +5,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -19,14 +22,40 @@ type GeometryFromGeoJSON struct {
 	Coordinates json.RawMessage `json:"coordinates"`
 }
 
-// GenerateShapefileZip creates a zip file containing both JSON and shapefile formats
-func GenerateShapefileZip(jsonData []byte, features []interface{}) ([]byte, error) {
+// ZipEntry is an additional named file to be written into a generated zip
+type ZipEntry struct {
+	Name string
+	Data []byte
+}
+
+// ShapefileFeature is a single feature to be written as a shapefile record
+type ShapefileFeature struct {
+	Geometry   json.RawMessage
+	Properties map[string]interface{}
+}
+
+// ShapefileMetadata is written to metadata.json alongside the shapefile
+type ShapefileMetadata struct {
+	Fields []DBFFieldMapping `json:"fields"`
+}
+
+// ShapefileFeatureSource passes features to write one at a time, so records are
+// written as they are produced instead of from a second in-memory copy of the
+// collection. It should stop and return the error if write fails.
+type ShapefileFeatureSource func(write func(feature ShapefileFeature) error) error
+
+// GenerateShapefileZip creates a zip file containing both JSON and shapefile formats,
+// named <baseName>.json and <baseName>.shp/.shx/.dbf, followed by any extra
+// entries supplied by the caller. fieldDecimals overrides the decimal places
+// of numeric properties by name (see createFieldsFromProperties); nil infers
+// them all from the data.
+func GenerateShapefileZip(baseName string, jsonData []byte, features ShapefileFeatureSource, fieldDecimals map[string]int, extraEntries ...ZipEntry) ([]byte, error) {
 	// Create a buffer to write the zip file
 	var zipBuffer bytes.Buffer
 	zipWriter := zip.NewWriter(&zipBuffer)
 
 	// Add JSON file to zip
-	jsonFile, err := zipWriter.Create("cleaned_topology.json")
+	jsonFile, err := zipWriter.Create(baseName + ".json")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create JSON file in zip: %v", err)
 	}
@@ -35,10 +64,36 @@ func GenerateShapefileZip(jsonData []byte, features []interface{}) ([]byte, erro
 		return nil, fmt.Errorf("failed to write JSON data to zip: %v", err)
 	}
 
+	// Add any extra entries (e.g. comparison layers) to zip
+	for _, entry := range extraEntries {
+		entryFile, err := zipWriter.Create(entry.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s in zip: %v", entry.Name, err)
+		}
+		_, err = entryFile.Write(entry.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s to zip: %v", entry.Name, err)
+		}
+	}
+
 	// Generate shapefile and add to zip
-	err = addShapefileToZip(zipWriter, features)
+	mappings, err := addShapefileToZip(zipWriter, baseName, features, fieldDecimals)
 	if err != nil {
-		return nil, fmt.Errorf("failed to add shapefile to zip: %v", err)
+		return nil, fmt.Errorf("failed to add shapefile to zip: %w", err)
+	}
+
+	// Report how property names and types were mapped onto DBF fields
+	metadata, err := json.MarshalIndent(ShapefileMetadata{Fields: mappings}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal shapefile metadata: %v", err)
+	}
+	metadataFile, err := zipWriter.Create("metadata.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata.json in zip: %v", err)
+	}
+	_, err = metadataFile.Write(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write metadata.json to zip: %v", err)
 	}
 
 	// Close the zip writer
@@ -50,12 +105,13 @@ func GenerateShapefileZip(jsonData []byte, features []interface{}) ([]byte, erro
 	return zipBuffer.Bytes(), nil
 }
 
-// addShapefileToZip creates shapefile components and adds them to the zip
-func addShapefileToZip(zipWriter *zip.Writer, features []interface{}) error {
+// addShapefileToZip creates shapefile components and adds them to the zip as
+// <baseName>.shp/.shx/.dbf, returning the DBF field mapping
+func addShapefileToZip(zipWriter *zip.Writer, baseName string, features ShapefileFeatureSource, fieldDecimals map[string]int) ([]DBFFieldMapping, error) {
 	// Create temporary directory for shapefile generation
 	tempDir, err := os.MkdirTemp("", "shapefile_")
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %v", err)
+		return nil, fmt.Errorf("failed to create temp directory: %v", err)
 	}
 	defer os.RemoveAll(tempDir)
 
@@ -63,9 +119,9 @@ func addShapefileToZip(zipWriter *zip.Writer, features []interface{}) error {
 	shapefilePath := filepath.Join(tempDir, "cleaned_topology.shp")
 
 	// Generate shapefile
-	err = generateShapefile(shapefilePath, features)
+	mappings, err := generateShapefile(shapefilePath, features, fieldDecimals)
 	if err != nil {
-		return fmt.Errorf("failed to generate shapefile: %v", err)
+		return nil, fmt.Errorf("failed to generate shapefile: %w", err)
 	}
 
 	// Add shapefile components to zip
@@ -78,144 +134,224 @@ func addShapefileToZip(zipWriter *zip.Writer, features []interface{}) error {
 			continue
 		}
 
-		// Read file content
-		fileContent, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read shapefile component %s: %v", ext, err)
-		}
-
 		// Add to zip
-		zipFile, err := zipWriter.Create("cleaned_topology" + ext)
+		zipFile, err := zipWriter.Create(baseName + ext)
 		if err != nil {
-			return fmt.Errorf("failed to create %s file in zip: %v", ext, err)
+			return nil, fmt.Errorf("failed to create %s file in zip: %v", ext, err)
 		}
 
-		_, err = zipFile.Write(fileContent)
+		// Stream the component rather than reading it into memory
+		err = copyFileTo(zipFile, filePath)
 		if err != nil {
-			return fmt.Errorf("failed to write %s data to zip: %v", ext, err)
+			return nil, fmt.Errorf("failed to write %s data to zip: %v", ext, err)
 		}
 	}
 
-	return nil
+	return mappings, nil
 }
 
-// generateShapefile creates a shapefile from the feature collection
-func generateShapefile(shapefilePath string, features []interface{}) error {
-	if len(features) == 0 {
-		return fmt.Errorf("no features to write to shapefile")
+// copyFileTo copies the contents of the file at path to w
+func copyFileTo(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
 	}
+	defer file.Close()
 
-	// Determine geometry type from first feature
-	firstFeature, ok := features[0].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("invalid feature format")
-	}
+	_, err = io.Copy(w, file)
+	return err
+}
 
-	geometryRaw, ok := firstFeature["geometry"]
-	if !ok {
-		return fmt.Errorf("feature missing geometry")
-	}
+// generateShapefile creates a shapefile from the features yielded by source,
+// writing each record as it arrives. The first feature determines the shape
+// type and the DBF fields, whose mapping from property names is returned. A
+// shapefile holds a single shape type, so a later feature of a different
+// supported type fails with a *MixedGeometryTypesError rather than being
+// written as a corrupt record.
+// go-shp panics instead of returning errors on some malformed field specs
+// (such as a zero-length field); such panics are returned as errors so a bad
+// field fails only the shapefile, not the request handler.
+func generateShapefile(shapefilePath string, source ShapefileFeatureSource, fieldDecimals map[string]int) (mappings []DBFFieldMapping, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			mappings, err = nil, fmt.Errorf("shapefile writer failed: %v", r)
+		}
+	}()
 
-	// Parse geometry to determine type
-	var geom GeometryFromGeoJSON
-	geometryBytes, err := json.Marshal(geometryRaw)
+	mappings, err = writeShapefile(shapefilePath, source, fieldDecimals)
 	if err != nil {
-		return fmt.Errorf("failed to marshal geometry: %v", err)
+		return nil, err
 	}
+	return mappings, renameDBF(shapefilePath)
+}
 
-	err = json.Unmarshal(geometryBytes, &geom)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal geometry: %v", err)
+// renameDBF moves the DBF go-shp v0.1.1 creates as <name>dbf, without the dot,
+// to <name>.dbf where readers (go-shp's own included) and the zip look for it
+func renameDBF(shapefilePath string) error {
+	base := strings.TrimSuffix(shapefilePath, filepath.Ext(shapefilePath))
+	misnamed := base + "dbf"
+	if _, err := os.Stat(misnamed); err != nil {
+		return nil
 	}
-
-	// Map GeoJSON geometry type to shapefile type
-	var shapeType shp.ShapeType
-	switch geom.Type {
-	case "Point":
-		shapeType = shp.POINT
-	case "LineString", "MultiLineString":
-		shapeType = shp.POLYLINE
-	case "Polygon", "MultiPolygon":
-		shapeType = shp.POLYGON
-	default:
-		return fmt.Errorf("unsupported geometry type: %s", geom.Type)
+	if err := os.Rename(misnamed, base+".dbf"); err != nil {
+		return fmt.Errorf("failed to rename DBF file: %v", err)
 	}
+	return nil
+}
 
-	// Create shapefile
-	shape, err := shp.Create(shapefilePath, shapeType)
-	if err != nil {
-		return fmt.Errorf("failed to create shapefile: %v", err)
-	}
-	defer shape.Close()
-
-	// Determine fields from properties of first feature
-	properties, ok := firstFeature["properties"].(map[string]interface{})
-	if !ok {
-		properties = make(map[string]interface{})
-	}
+func writeShapefile(shapefilePath string, source ShapefileFeatureSource, fieldDecimals map[string]int) ([]DBFFieldMapping, error) {
+	var shape *shp.Writer
+	var shapeType shp.ShapeType
+	var fields []shp.Field
+	var mappings []DBFFieldMapping
+	var lookup *fieldLookup
+	defer func() {
+		if shape != nil {
+			shape.Close()
+		}
+	}()
 
-	fields := createFieldsFromProperties(properties)
-	shape.SetFields(fields)
+	featureIndex := 0
+	recordIndex := 0
+	err := source(func(feature ShapefileFeature) error {
+		i := featureIndex
+		featureIndex++
 
-	// Write features to shapefile
-	for i, featureRaw := range features {
-		feature, ok := featureRaw.(map[string]interface{})
-		if !ok {
-			continue
+		var geom GeometryFromGeoJSON
+		if err := json.Unmarshal(feature.Geometry, &geom); err != nil {
+			if shape == nil {
+				return fmt.Errorf("failed to unmarshal geometry: %v", err)
+			}
+			fmt.Printf("Warning: failed to unmarshal geometry for feature %d: %v\n", i, err)
+			return nil
 		}
 
-		// Parse geometry
-		geometryRaw, ok := feature["geometry"]
-		if !ok {
-			continue
+		properties := feature.Properties
+		if properties == nil {
+			properties = make(map[string]interface{})
 		}
 
-		geometryBytes, err := json.Marshal(geometryRaw)
-		if err != nil {
-			fmt.Printf("Warning: failed to marshal geometry for feature %d: %v\n", i, err)
-			continue
+		if shape == nil {
+			var err error
+			shapeType, err = shapeTypeForGeometry(geom.Type)
+			if err != nil {
+				return err
+			}
+			shape, err = shp.Create(shapefilePath, shapeType)
+			if err != nil {
+				return fmt.Errorf("failed to create shapefile: %v", err)
+			}
+			fields, mappings = createFieldsFromProperties(properties, fieldDecimals)
+			shape.SetFields(fields)
+			lookup = newFieldLookup(fields, mappings)
 		}
 
-		var geom GeometryFromGeoJSON
-		err = json.Unmarshal(geometryBytes, &geom)
-		if err != nil {
-			fmt.Printf("Warning: failed to unmarshal geometry for feature %d: %v\n", i, err)
-			continue
+		// Null and unsupported geometries are skipped below; anything else must match
+		if geom.Type != "" {
+			if featureType, err := shapeTypeForGeometry(geom.Type); err == nil && featureType != shapeType {
+				return &MixedGeometryTypesError{Feature: i, Type: geom.Type, ShapeType: shapeTypeName(shapeType)}
+			}
 		}
 
 		// Convert geometry to shapefile format and write
-		err = writeGeometryToShapefile(shape, &geom, shapeType)
-		if err != nil {
+		if err := writeGeometryToShapefile(shape, &geom, shapeType, i); err != nil {
 			fmt.Printf("Warning: failed to write geometry for feature %d: %v\n", i, err)
-			continue
+			return nil
 		}
 
-		// Write attributes
-		properties, ok := feature["properties"].(map[string]interface{})
-		if !ok {
-			properties = make(map[string]interface{})
-		}
-
-		err = writeAttributesToShapefile(shape, properties, fields, i)
-		if err != nil {
+		// Attributes are addressed by record number, which skips failed features
+		if err := writeAttributesToShapefile(shape, properties, fields, lookup, recordIndex); err != nil {
 			fmt.Printf("Warning: failed to write attributes for feature %d: %v\n", i, err)
 		}
+		recordIndex++
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	if shape == nil {
+		return nil, fmt.Errorf("no features to write to shapefile")
+	}
+
+	return mappings, nil
+}
+
+// MixedGeometryTypesError reports a feature whose geometry type cannot be
+// written to a shapefile already holding another shape type
+type MixedGeometryTypesError struct {
+	Feature   int
+	Type      string
+	ShapeType string
+}
+
+func (e *MixedGeometryTypesError) Error() string {
+	return fmt.Sprintf("feature %d is a %s but the shapefile holds %s shapes: a shapefile cannot mix geometry types", e.Feature, e.Type, e.ShapeType)
+}
+
+// shapeTypeName names a shape type returned by shapeTypeForGeometry
+func shapeTypeName(shapeType shp.ShapeType) string {
+	switch shapeType {
+	case shp.POINT:
+		return "point"
+	case shp.POLYLINE:
+		return "polyline"
+	case shp.POLYGON:
+		return "polygon"
+	default:
+		return fmt.Sprintf("type %d", shapeType)
+	}
+}
+
+// shapeTypeForGeometry maps a GeoJSON geometry type to a shapefile type
+func shapeTypeForGeometry(geometryType string) (shp.ShapeType, error) {
+	switch geometryType {
+	case "Point":
+		return shp.POINT, nil
+	case "LineString", "MultiLineString":
+		return shp.POLYLINE, nil
+	case "Polygon", "MultiPolygon":
+		return shp.POLYGON, nil
+	default:
+		return 0, fmt.Errorf("unsupported geometry type: %s", geometryType)
+	}
 }
 
-// createFieldsFromProperties analyzes properties to create DBF fields
-func createFieldsFromProperties(properties map[string]interface{}) []shp.Field {
+// DBFFieldMapping records how a feature property was stored in the DBF, so
+// truncated names and coerced types are visible without opening the file
+type DBFFieldMapping struct {
+	Property  string `json:"property"`
+	Field     string `json:"field"`
+	Type      string `json:"type"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Coercion  string `json:"coercion,omitempty"`
+}
+
+// createFieldsFromProperties analyzes properties to create DBF fields, in
+// property name order, and reports the mapping from each property to its
+// field. Float fields are sized from the value (see floatField) unless
+// fieldDecimals names the property.
+func createFieldsFromProperties(properties map[string]interface{}, fieldDecimals map[string]int) ([]shp.Field, []DBFFieldMapping) {
 	fields := []shp.Field{}
+	mappings := []DBFFieldMapping{}
 
-	for key, value := range properties {
-		// Limit field name to 10 characters (DBF limitation)
-		fieldName := key
-		if len(fieldName) > 10 {
-			fieldName = fieldName[:10]
-		}
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Attributes are matched to fields case-insensitively, so names that only
+	// differ in case after truncation share a field
+	fieldOwners := make(map[string]string)
+
+	for _, key := range keys {
+		value := properties[key]
 
+		fieldName := dbfFieldName(key)
+		mapping := DBFFieldMapping{Property: key, Field: fieldName, Truncated: fieldName != key}
+
+		var coercions []string
 		switch v := value.(type) {
 		case string:
 			// Determine appropriate length, max 254 for DBF
@@ -227,35 +363,156 @@ func createFieldsFromProperties(properties map[string]interface{}) []shp.Field {
 				length = 254
 			}
 			fields = append(fields, shp.StringField(fieldName, uint8(length)))
+			mapping.Type = "string"
+			coercions = append(coercions, fmt.Sprintf("values longer than %d bytes are cut off", length))
 		case float64:
-			fields = append(fields, shp.FloatField(fieldName, 15, 5))
+			decimals, override := fieldDecimals[key]
+			if !override {
+				decimals = -1
+			}
+			field, fieldType, coercion := floatField(fieldName, v, decimals)
+			fields = append(fields, field)
+			mapping.Type = fieldType
+			if coercion != "" {
+				coercions = append(coercions, coercion)
+			}
 		case int, int32, int64:
 			fields = append(fields, shp.NumberField(fieldName, 15))
+			mapping.Type = "number"
 		case bool:
 			fields = append(fields, shp.StringField(fieldName, 5)) // Store as "true"/"false"
+			mapping.Type = "string"
+			coercions = append(coercions, `boolean stored as "true"/"false" text`)
 		default:
 			// Default to string field for unknown types
 			fields = append(fields, shp.StringField(fieldName, 100))
+			mapping.Type = "string"
+			coercions = append(coercions, fmt.Sprintf("%s value stored as text of up to 100 bytes", jsonTypeName(value)))
+		}
+
+		if owner, exists := fieldOwners[strings.ToLower(fieldName)]; exists {
+			coercions = append(coercions, fmt.Sprintf("field name collides with property %q; values may be taken from either", owner))
+		} else {
+			fieldOwners[strings.ToLower(fieldName)] = key
 		}
+
+		mapping.Coercion = strings.Join(coercions, "; ")
+		mappings = append(mappings, mapping)
 	}
 
 	// Add a default ID field if no fields exist
 	if len(fields) == 0 {
 		fields = append(fields, shp.NumberField("ID", 10))
+		mappings = append(mappings, DBFFieldMapping{Field: "ID", Type: "number", Coercion: "record number; features have no properties"})
 	}
 
-	return fields
+	return fields, mappings
+}
+
+// DBF numeric field limits; fractional floats keep at least minDBFDecimals places
+const (
+	maxDBFNumericWidth = 20
+	maxDBFDecimals     = 15
+	minDBFDecimals     = 5
+)
+
+// floatField chooses a DBF field for a float property from its value in the
+// first feature, since fields are fixed before later records are seen. With
+// decimals negative, an integer-valued float becomes a number field and a
+// fractional one keeps every decimal it needs, and at least minDBFDecimals;
+// otherwise decimals is used as given, zero meaning a number field. The width
+// fits the value's integer digits, within the DBF limit. It returns the field,
+// its mapping type and a coercion note.
+func floatField(name string, value float64, decimals int) (shp.Field, string, string) {
+	if decimals < 0 {
+		if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+			return shp.NumberField(name, 15), "number", "integer-valued in the first feature; fractions in later features are dropped"
+		}
+		decimals = max(fractionDigits(value), minDBFDecimals)
+	}
+	if decimals == 0 {
+		return shp.NumberField(name, 15), "number", "fractions are dropped"
+	}
+
+	// Room for the integer digits, the sign and the decimal point
+	integerDigits := len(strconv.FormatFloat(math.Trunc(math.Abs(value)), 'f', 0, 64))
+	decimals = min(decimals, maxDBFDecimals, max(maxDBFNumericWidth-integerDigits-2, 0))
+	width := min(max(15, integerDigits+decimals+2), maxDBFNumericWidth)
+	return shp.FloatField(name, uint8(width), uint8(decimals)), "float", fmt.Sprintf("rounded to %d decimal places", decimals)
+}
+
+// fractionDigits counts the decimals in the shortest representation of value
+func fractionDigits(value float64) int {
+	text := strconv.FormatFloat(value, 'f', -1, 64)
+	if dot := strings.IndexByte(text, '.'); dot >= 0 {
+		return len(text) - dot - 1
+	}
+	return 0
+}
+
+// dbfFieldName limits a property name to the 10 characters a DBF field name allows
+func dbfFieldName(key string) string {
+	if len(key) > 10 {
+		return key[:10]
+	}
+	return key
+}
+
+// fieldLookup resolves property names to DBF field indices. It is built once
+// per shapefile so each record is written with direct lookups rather than by
+// scanning every property for every field.
+type fieldLookup struct {
+	// byName maps the lower-cased field name; matching is case-insensitive
+	byName map[string]int
+	// owners holds the property each field was created from, which wins when
+	// several properties map onto the same field
+	owners []string
+	// recordNumberField is the index of the synthetic ID field, or -1
+	recordNumberField int
+}
+
+func newFieldLookup(fields []shp.Field, mappings []DBFFieldMapping) *fieldLookup {
+	lookup := &fieldLookup{
+		byName:            make(map[string]int, len(fields)),
+		owners:            make([]string, len(fields)),
+		recordNumberField: -1,
+	}
+	for i, mapping := range mappings {
+		if mapping.Property == "" {
+			lookup.recordNumberField = i
+			continue
+		}
+		if _, exists := lookup.byName[strings.ToLower(mapping.Field)]; !exists {
+			lookup.byName[strings.ToLower(mapping.Field)] = i
+			lookup.owners[i] = mapping.Property
+		}
+	}
+	return lookup
+}
+
+// jsonTypeName describes a decoded JSON value for coercion reports
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
 }
 
 // writeGeometryToShapefile converts GeoJSON geometry to shapefile format and writes it
-func writeGeometryToShapefile(shape *shp.Writer, geom *GeometryFromGeoJSON, shapeType shp.ShapeType) error {
+func writeGeometryToShapefile(shape *shp.Writer, geom *GeometryFromGeoJSON, shapeType shp.ShapeType, featureIndex int) error {
 	switch geom.Type {
 	case "Point":
 		return writePointGeometry(shape, geom)
 	case "Polygon":
-		return writePolygonGeometry(shape, geom)
+		return writePolygonGeometry(shape, geom, featureIndex)
 	case "MultiPolygon":
-		return writeMultiPolygonGeometry(shape, geom)
+		return writeMultiPolygonGeometry(shape, geom, featureIndex)
 	case "LineString":
 		return writeLineStringGeometry(shape, geom)
 	case "MultiLineString":
@@ -283,71 +540,120 @@ func writePointGeometry(shape *shp.Writer, geom *GeometryFromGeoJSON) error {
 }
 
 // writePolygonGeometry writes a polygon geometry to shapefile
-func writePolygonGeometry(shape *shp.Writer, geom *GeometryFromGeoJSON) error {
+func writePolygonGeometry(shape *shp.Writer, geom *GeometryFromGeoJSON, featureIndex int) error {
 	var coords [][][]float64
 	err := json.Unmarshal(geom.Coordinates, &coords)
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal polygon coordinates: %v", err)
 	}
 
-	polygon := &shp.Polygon{}
-
-	for _, ring := range coords {
-		var points []shp.Point
-		for _, coord := range ring {
-			if len(coord) >= 2 {
-				points = append(points, shp.Point{X: coord[0], Y: coord[1]})
-			}
-		}
-		if len(points) > 0 {
-			polygon.Points = append(polygon.Points, points...)
-			polygon.Parts = append(polygon.Parts, int32(len(polygon.Points)-len(points)))
-		}
-	}
-
-	// Fix parts indexing
-	if len(polygon.Parts) > 0 {
-		polygon.Parts[0] = 0
-		for i := 1; i < len(polygon.Parts); i++ {
-			polygon.Parts[i] = polygon.Parts[i-1] + int32(len(coords[i-1]))
-		}
-	}
-
-	shape.Write(polygon)
-	return nil
+	return writePolygonRings(shape, [][][][]float64{coords}, featureIndex)
 }
 
 // writeMultiPolygonGeometry writes a multipolygon geometry to shapefile
-func writeMultiPolygonGeometry(shape *shp.Writer, geom *GeometryFromGeoJSON) error {
+func writeMultiPolygonGeometry(shape *shp.Writer, geom *GeometryFromGeoJSON, featureIndex int) error {
 	var coords [][][][]float64
 	err := json.Unmarshal(geom.Coordinates, &coords)
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal multipolygon coordinates: %v", err)
 	}
 
-	polygon := &shp.Polygon{}
-	partIndex := int32(0)
+	return writePolygonRings(shape, coords, featureIndex)
+}
 
-	for _, poly := range coords {
-		for _, ring := range poly {
-			var points []shp.Point
+// writePolygonRings writes polygons as a single shapefile polygon record. A
+// shapefile has no polygon grouping, only parts, and tells outer rings from
+// holes by winding: outer rings are clockwise and holes counter-clockwise,
+// the reverse of GeoJSON. Each ring is therefore reoriented by its role. The
+// area of the written parts is checked against the input so anything the
+// shapefile cannot represent is reported rather than exported silently wrong.
+func writePolygonRings(shape *shp.Writer, polygons [][][][]float64, featureIndex int) error {
+	parts := make([][]shp.Point, 0)
+	expectedArea := 0.0
+
+	for _, polygon := range polygons {
+		var outer []shp.Point
+		for ringIndex, ring := range polygon {
+			points := make([]shp.Point, 0, len(ring))
 			for _, coord := range ring {
 				if len(coord) >= 2 {
 					points = append(points, shp.Point{X: coord[0], Y: coord[1]})
 				}
 			}
-			if len(points) > 0 {
-				polygon.Parts = append(polygon.Parts, partIndex)
-				polygon.Points = append(polygon.Points, points...)
-				partIndex += int32(len(points))
+
+			area := signedRingArea(points)
+			isHole := ringIndex > 0
+			if isHole {
+				expectedArea -= math.Abs(area)
+			} else {
+				expectedArea += math.Abs(area)
 			}
+
+			if len(points) < 4 {
+				fmt.Printf("Warning: feature %d: dropping ring with %d points, a shapefile ring needs at least 4\n", featureIndex, len(points))
+				continue
+			}
+			// Readers assign holes to the outer ring containing them, so a stray hole is misread
+			if isHole && outer != nil && !pointInRing(points[0], outer) {
+				fmt.Printf("Warning: feature %d: hole %d lies outside its outer ring\n", featureIndex, ringIndex)
+			}
+
+			// Clockwise (negative signed area) for outer rings, counter-clockwise for holes
+			if (area > 0) != isHole {
+				reversePoints(points)
+			}
+			if !isHole {
+				outer = points
+			}
+			parts = append(parts, points)
 		}
 	}
 
-	shape.Write(polygon)
+	if len(parts) == 0 {
+		return fmt.Errorf("no rings that a shapefile can represent")
+	}
+
+	// Shapefile area is the sum of clockwise parts minus the counter-clockwise ones
+	writtenArea := 0.0
+	for _, part := range parts {
+		writtenArea -= signedRingArea(part)
+	}
+	if math.Abs(writtenArea-expectedArea) > 1e-12+1e-6*math.Abs(expectedArea) {
+		fmt.Printf("Warning: feature %d: shapefile polygon area %g differs from input area %g\n", featureIndex, writtenArea, expectedArea)
+	}
+
+	shape.Write((*shp.Polygon)(shp.NewPolyLine(parts)))
 	return nil
 }
 
+// signedRingArea returns the shoelace area of a ring, positive when counter-clockwise
+func signedRingArea(points []shp.Point) float64 {
+	area := 0.0
+	for i := 0; i < len(points)-1; i++ {
+		area += points[i].X*points[i+1].Y - points[i+1].X*points[i].Y
+	}
+	return area / 2
+}
+
+// pointInRing reports whether point lies inside ring, by ray casting
+func pointInRing(point shp.Point, ring []shp.Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		if (ring[i].Y > point.Y) != (ring[j].Y > point.Y) &&
+			point.X < (ring[j].X-ring[i].X)*(point.Y-ring[i].Y)/(ring[j].Y-ring[i].Y)+ring[i].X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// reversePoints reverses a ring in place
+func reversePoints(points []shp.Point) {
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+}
+
 // writeLineStringGeometry writes a linestring geometry to shapefile
 func writeLineStringGeometry(shape *shp.Writer, geom *GeometryFromGeoJSON) error {
 	var coords [][]float64
@@ -394,30 +700,32 @@ func writeMultiLineStringGeometry(shape *shp.Writer, geom *GeometryFromGeoJSON)
 	return nil
 }
 
-// writeAttributesToShapefile writes feature properties as DBF attributes
-func writeAttributesToShapefile(shape *shp.Writer, properties map[string]interface{}, fields []shp.Field, recordIndex int) error {
+// writeAttributesToShapefile writes feature properties as DBF attributes.
+// shp.Writer seeks within a single DBF file for every attribute, so records
+// cannot be written concurrently; the per-record cost is kept to one pass over
+// the properties instead.
+func writeAttributesToShapefile(shape *shp.Writer, properties map[string]interface{}, fields []shp.Field, lookup *fieldLookup, recordIndex int) error {
+	// Match properties to fields (case insensitive and truncated)
+	values := make([]interface{}, len(fields))
+	found := make([]bool, len(fields))
+	for propKey, propValue := range properties {
+		i, ok := lookup.byName[strings.ToLower(dbfFieldName(propKey))]
+		if !ok || (found[i] && propKey != lookup.owners[i]) {
+			continue
+		}
+		values[i] = propValue
+		found[i] = true
+	}
+
 	for i, field := range fields {
-		fieldName := string(field.Name[:])
-		
 		// Handle special ID field
-		if fieldName == "ID" && len(properties) == 0 {
+		if i == lookup.recordNumberField {
 			shape.WriteAttribute(recordIndex, i, strconv.Itoa(recordIndex+1))
 			continue
 		}
 
-		// Find matching property (case insensitive and truncated)
-		var value interface{}
-		found := false
-		for propKey, propValue := range properties {
-			if strings.EqualFold(propKey, fieldName) || 
-			   (len(propKey) > 10 && strings.EqualFold(propKey[:10], fieldName)) {
-				value = propValue
-				found = true
-				break
-			}
-		}
-
-		if !found {
+		value := values[i]
+		if !found[i] {
 			// Use empty value for missing properties
 			switch field.Fieldtype {
 			case 'C': // Character/String
@@ -433,7 +741,12 @@ func writeAttributesToShapefile(shape *shp.Writer, properties map[string]interfa
 		// Convert value to appropriate type
 		switch field.Fieldtype {
 		case 'C': // Character/String
-			shape.WriteAttribute(recordIndex, i, fmt.Sprintf("%v", value))
+			// Values longer than the field would be rejected outright, so cut them to fit
+			text := fmt.Sprintf("%v", value)
+			if len(text) > int(field.Size) {
+				text = text[:field.Size]
+			}
+			shape.WriteAttribute(recordIndex, i, text)
 		case 'N': // Numeric
 			if numVal, ok := value.(float64); ok {
 				shape.WriteAttribute(recordIndex, i, int(numVal))
@@ -0,0 +1,462 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jonas-p/go-shp"
+)
+
+// gridShapefileFeatures decodes a GenerateGrid collection into shapefile features
+func gridShapefileFeatures(tb testing.TB, rows, cols int) []ShapefileFeature {
+	tb.Helper()
+	var collection struct {
+		Features []struct {
+			Geometry   json.RawMessage        `json:"geometry"`
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(GenerateGrid(rows, cols, 0.001), &collection); err != nil {
+		tb.Fatalf("decoding generated grid: %v", err)
+	}
+
+	features := make([]ShapefileFeature, len(collection.Features))
+	for i, feature := range collection.Features {
+		features[i] = ShapefileFeature{Geometry: feature.Geometry, Properties: feature.Properties}
+	}
+	return features
+}
+
+// sliceSource yields features one at a time, as the cleaning pipeline does
+func sliceSource(features []ShapefileFeature) ShapefileFeatureSource {
+	return func(write func(feature ShapefileFeature) error) error {
+		for _, feature := range features {
+			if err := write(feature); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// BenchmarkGenerateShapefile measures time and memory for streaming features
+// into a shapefile; run with -benchmem to compare allocations per feature
+func BenchmarkGenerateShapefile(b *testing.B) {
+	sizes := []struct {
+		rows, cols int
+	}{
+		{10, 100},   // 1k features
+		{100, 100},  // 10k features
+		{100, 1000}, // 100k features
+	}
+
+	for _, size := range sizes {
+		features := gridShapefileFeatures(b, size.rows, size.cols)
+		b.Run(fmt.Sprintf("%d features", len(features)), func(b *testing.B) {
+			shapefilePath := filepath.Join(b.TempDir(), "grid.shp")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := generateShapefile(shapefilePath, sliceSource(features), nil); err != nil {
+					b.Fatalf("generateShapefile: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// readPolygonParts reads back the rings of each polygon record in a shapefile
+func readPolygonParts(t testing.TB, shapefilePath string) [][][]shp.Point {
+	t.Helper()
+	reader, err := shp.Open(shapefilePath)
+	if err != nil {
+		t.Fatalf("opening %s: %v", shapefilePath, err)
+	}
+	defer reader.Close()
+
+	records := make([][][]shp.Point, 0)
+	for reader.Next() {
+		_, shape := reader.Shape()
+		polygon, ok := shape.(*shp.Polygon)
+		if !ok {
+			t.Fatalf("record is a %T, want *shp.Polygon", shape)
+		}
+		parts := make([][]shp.Point, len(polygon.Parts))
+		for i, start := range polygon.Parts {
+			end := len(polygon.Points)
+			if i+1 < len(polygon.Parts) {
+				end = int(polygon.Parts[i+1])
+			}
+			parts[i] = polygon.Points[start:end]
+		}
+		records = append(records, parts)
+	}
+	return records
+}
+
+func TestGenerateShapefilePolygonHoles(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry string
+		// holes marks which written parts must be holes (counter-clockwise)
+		holes []bool
+		area  float64
+	}{
+		{
+			name:     "polygon with hole",
+			geometry: `{"type":"Polygon","coordinates":[[[0,0],[4,0],[4,4],[0,4],[0,0]],[[1,1],[1,2],[2,2],[2,1],[1,1]]]}`,
+			holes:    []bool{false, true},
+			area:     15,
+		},
+		{
+			name:     "multipolygon with holes",
+			geometry: `{"type":"MultiPolygon","coordinates":[[[[0,0],[4,0],[4,4],[0,4],[0,0]],[[1,1],[1,2],[2,2],[2,1],[1,1]]],[[[10,0],[14,0],[14,4],[10,4],[10,0]],[[11,1],[11,2],[12,2],[12,1],[11,1]],[[12.5,2.5],[12.5,3.5],[13.5,3.5],[13.5,2.5],[12.5,2.5]]]]}`,
+			holes:    []bool{false, true, false, true, true},
+			area:     15 + 14,
+		},
+		{
+			name:     "rings wound the wrong way",
+			geometry: `{"type":"MultiPolygon","coordinates":[[[[0,0],[0,4],[4,4],[4,0],[0,0]],[[1,1],[2,1],[2,2],[1,2],[1,1]]]]}`,
+			holes:    []bool{false, true},
+			area:     15,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shapefilePath := filepath.Join(t.TempDir(), "holes.shp")
+			feature := ShapefileFeature{Geometry: json.RawMessage(tt.geometry), Properties: map[string]interface{}{"id": 1}}
+			if _, err := generateShapefile(shapefilePath, sliceSource([]ShapefileFeature{feature}), nil); err != nil {
+				t.Fatalf("generateShapefile: %v", err)
+			}
+
+			records := readPolygonParts(t, shapefilePath)
+			if len(records) != 1 {
+				t.Fatalf("wrote %d records, want 1", len(records))
+			}
+			parts := records[0]
+			if len(parts) != len(tt.holes) {
+				t.Fatalf("wrote %d parts, want %d", len(parts), len(tt.holes))
+			}
+
+			area := 0.0
+			for i, part := range parts {
+				signed := signedRingArea(part)
+				if isHole := signed > 0; isHole != tt.holes[i] {
+					t.Errorf("part %d has signed area %v, want hole = %v", i, signed, tt.holes[i])
+				}
+				area -= signed
+			}
+			if area != tt.area {
+				t.Errorf("shapefile area = %v, want %v", area, tt.area)
+			}
+		})
+	}
+}
+
+// readAttributes reads back every DBF record of a shapefile as field name -> value
+func readAttributes(t testing.TB, shapefilePath string) []map[string]string {
+	t.Helper()
+	reader, err := shp.Open(shapefilePath)
+	if err != nil {
+		t.Fatalf("opening %s: %v", shapefilePath, err)
+	}
+	defer reader.Close()
+
+	fields := reader.Fields()
+	records := make([]map[string]string, reader.AttributeCount())
+	for row := range records {
+		records[row] = make(map[string]string, len(fields))
+		for i, field := range fields {
+			records[row][field.String()] = strings.Trim(reader.ReadAttribute(row, i), " \x00")
+		}
+	}
+	return records
+}
+
+// writeTestShapefile writes features to a shapefile in a temporary directory
+// and returns its path
+func writeTestShapefile(t testing.TB, features []ShapefileFeature, fieldDecimals map[string]int) string {
+	t.Helper()
+	shapefilePath := filepath.Join(t.TempDir(), "attributes.shp")
+	if _, err := generateShapefile(shapefilePath, sliceSource(features), fieldDecimals); err != nil {
+		t.Fatalf("generateShapefile: %v", err)
+	}
+	return shapefilePath
+}
+
+func TestGenerateShapefileAttributes(t *testing.T) {
+	const square = `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`
+
+	tests := []struct {
+		name       string
+		properties []map[string]interface{}
+		want       []map[string]string
+	}{
+		{
+			name: "values by type",
+			properties: []map[string]interface{}{
+				{"name": "north", "count": 12.0, "area": 1.25, "flag": true},
+				{"name": "south", "count": 3.0, "area": 2.5, "flag": false},
+			},
+			want: []map[string]string{
+				{"name": "north", "count": "12", "area": "1.25000", "flag": "true"},
+				{"name": "south", "count": "3", "area": "2.50000", "flag": "false"},
+			},
+		},
+		{
+			name: "missing and differently cased properties",
+			properties: []map[string]interface{}{
+				{"name": "north", "count": 12.0},
+				{"NAME": "south"},
+			},
+			want: []map[string]string{
+				{"name": "north", "count": "12"},
+				{"name": "south", "count": "0"},
+			},
+		},
+		{
+			name: "long property names are truncated",
+			properties: []map[string]interface{}{
+				{"parcel_identifier": "P-1"},
+				{"parcel_identifier": "P-2"},
+			},
+			want: []map[string]string{
+				{"parcel_ide": "P-1"},
+				{"parcel_ide": "P-2"},
+			},
+		},
+		{
+			name:       "no properties get a record number",
+			properties: []map[string]interface{}{nil, {}},
+			want: []map[string]string{
+				{"ID": "1"},
+				{"ID": "2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := make([]ShapefileFeature, len(tt.properties))
+			for i, properties := range tt.properties {
+				features[i] = ShapefileFeature{Geometry: json.RawMessage(square), Properties: properties}
+			}
+
+			records := readAttributes(t, writeTestShapefile(t, features, nil))
+			if len(records) != len(tt.want) {
+				t.Fatalf("wrote %d records, want %d", len(records), len(tt.want))
+			}
+			for row, want := range tt.want {
+				if !reflect.DeepEqual(records[row], want) {
+					t.Errorf("record %d = %v, want %v", row, records[row], want)
+				}
+			}
+		})
+	}
+}
+
+// wideShapefileFeatures adds fields properties, a mix of text, integers and
+// fractions, to every feature of a grid
+func wideShapefileFeatures(tb testing.TB, rows, cols, fields int) []ShapefileFeature {
+	features := gridShapefileFeatures(tb, rows, cols)
+	for i := range features {
+		properties := make(map[string]interface{}, fields)
+		for f := range fields {
+			key := fmt.Sprintf("field_%02d", f)
+			switch f % 3 {
+			case 0:
+				properties[key] = fmt.Sprintf("value %d-%d", i, f)
+			case 1:
+				properties[key] = float64(i + f)
+			default:
+				properties[key] = float64(i) + 0.125
+			}
+		}
+		features[i].Properties = properties
+	}
+	return features
+}
+
+// BenchmarkShapefileAttributes measures writing wide attribute tables, where
+// matching properties to fields dominates export time
+func BenchmarkShapefileAttributes(b *testing.B) {
+	for _, fields := range []int{5, 50} {
+		features := wideShapefileFeatures(b, 100, 1000, fields) // 100k records
+		b.Run(fmt.Sprintf("%d fields", fields), func(b *testing.B) {
+			shapefilePath := filepath.Join(b.TempDir(), "wide.shp")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := generateShapefile(shapefilePath, sliceSource(features), nil); err != nil {
+					b.Fatalf("generateShapefile: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateShapefileRecoversPanics(t *testing.T) {
+	square := ShapefileFeature{
+		Geometry:   json.RawMessage(`{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`),
+		Properties: map[string]interface{}{"name": "square"},
+	}
+
+	tests := []struct {
+		name   string
+		source ShapefileFeatureSource
+	}{
+		// go-shp has no errors for some bad field specs, only runtime panics
+		{
+			name: "panic before the first record",
+			source: func(write func(feature ShapefileFeature) error) error {
+				panic("field spec rejected")
+			},
+		},
+		{
+			name: "panic after records were written",
+			source: func(write func(feature ShapefileFeature) error) error {
+				if err := write(square); err != nil {
+					return err
+				}
+				panic("record rejected")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shapefilePath := filepath.Join(t.TempDir(), "panic.shp")
+			mappings, err := generateShapefile(shapefilePath, tt.source, nil)
+			if err == nil || !strings.Contains(err.Error(), "shapefile writer failed") {
+				t.Errorf("generateShapefile error = %v, want the panic returned as an error", err)
+			}
+			if mappings != nil {
+				t.Errorf("mappings = %v, want none after a panic", mappings)
+			}
+		})
+	}
+}
+
+func TestGenerateShapefileMixedGeometryTypes(t *testing.T) {
+	const (
+		polygon      = `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`
+		multiPolygon = `{"type":"MultiPolygon","coordinates":[[[[2,0],[3,0],[3,1],[2,0]]]]}`
+		line         = `{"type":"LineString","coordinates":[[0,0],[1,1]]}`
+		point        = `{"type":"Point","coordinates":[0,0]}`
+	)
+
+	tests := []struct {
+		name       string
+		geometries []string
+		want       *MixedGeometryTypesError // nil when the shapefile is written
+	}{
+		{
+			name:       "polygon then line string",
+			geometries: []string{polygon, polygon, line},
+			want:       &MixedGeometryTypesError{Feature: 2, Type: "LineString", ShapeType: "polygon"},
+		},
+		{
+			name:       "line string then point",
+			geometries: []string{line, point},
+			want:       &MixedGeometryTypesError{Feature: 1, Type: "Point", ShapeType: "polyline"},
+		},
+		{
+			name:       "polygons and multipolygons share a shape type",
+			geometries: []string{polygon, multiPolygon},
+		},
+		{
+			name:       "null geometries are skipped",
+			geometries: []string{polygon, "null", polygon},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := make([]ShapefileFeature, len(tt.geometries))
+			for i, geometry := range tt.geometries {
+				features[i] = ShapefileFeature{Geometry: json.RawMessage(geometry)}
+			}
+
+			shapefilePath := filepath.Join(t.TempDir(), "mixed.shp")
+			_, err := generateShapefile(shapefilePath, sliceSource(features), nil)
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("generateShapefile: %v", err)
+				}
+				return
+			}
+
+			var mixed *MixedGeometryTypesError
+			if !errors.As(err, &mixed) {
+				t.Fatalf("generateShapefile error = %v, want a *MixedGeometryTypesError", err)
+			}
+			if *mixed != *tt.want {
+				t.Errorf("error = %+v, want %+v", *mixed, *tt.want)
+			}
+			if !strings.Contains(err.Error(), tt.want.Type) {
+				t.Errorf("error %q does not name the %s geometry", err, tt.want.Type)
+			}
+		})
+	}
+}
+
+func TestFloatField(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     float64
+		decimals  int // negative infers them from value
+		fieldType byte
+		size      uint8
+		precision uint8
+	}{
+		{"integer-like float", 1234567, -1, 'N', 15, 0},
+		{"negative integer-like float", -42, -1, 'N', 15, 0},
+		{"few decimals keep the minimum", 1.25, -1, 'F', 15, 5},
+		{"high-precision measure", 0.123456789012, -1, 'F', 15, 12},
+		{"width grows for integer digits", 1234567.123456789, -1, 'F', 18, 9},
+		{"decimals give way to integer digits", 123456789012345.67, -1, 'F', 20, 3},
+		{"override", 1.23456, 2, 'F', 15, 2},
+		{"override on an integer-like float", 3, 3, 'F', 15, 3},
+		{"zero override is a number field", 1.5, 0, 'N', 15, 0},
+		{"override capped at the DBF limit", 0.5, 30, 'F', 18, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, _, _ := floatField("value", tt.value, tt.decimals)
+			if field.Fieldtype != tt.fieldType || field.Size != tt.size || field.Precision != tt.precision {
+				t.Errorf("floatField(%v, %d) = %c(%d, %d), want %c(%d, %d)", tt.value, tt.decimals,
+					field.Fieldtype, field.Size, field.Precision, tt.fieldType, tt.size, tt.precision)
+			}
+		})
+	}
+}
+
+func TestGenerateShapefileFloatPrecision(t *testing.T) {
+	const square = `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,0]]]}`
+	features := []ShapefileFeature{
+		{
+			Geometry:   json.RawMessage(square),
+			Properties: map[string]interface{}{"id": 1234567.0, "measure": 0.123456789012, "ratio": 1.23456},
+		},
+		{
+			Geometry:   json.RawMessage(square),
+			Properties: map[string]interface{}{"id": 42.7, "measure": 2.5, "ratio": 0.5},
+		},
+	}
+	want := []map[string]string{
+		{"id": "1234567", "measure": "0.123456789012", "ratio": "1.23"},
+		{"id": "42", "measure": "2.500000000000", "ratio": "0.50"},
+	}
+
+	records := readAttributes(t, writeTestShapefile(t, features, map[string]int{"ratio": 2}))
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+}
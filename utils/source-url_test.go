@@ -0,0 +1,139 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestURLAllowListCheck(t *testing.T) {
+	allowList := NewURLAllowList("https", " data.example.com, .S3.amazonaws.com ")
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://data.example.com/parcels.geojson", true},
+		{"HTTPS://DATA.EXAMPLE.COM/parcels.geojson", true},
+		{"https://bucket.s3.amazonaws.com/key?X-Amz-Signature=abc", true},
+		{"https://data.example.com:8443/parcels.geojson", true},
+		{"http://data.example.com/parcels.geojson", false},
+		{"file:///etc/passwd", false},
+		{"https://other.example.com/parcels.geojson", false},
+		{"https://data.example.com.evil.test/parcels.geojson", false},
+		{"https://s3.amazonaws.com.evil.test/key", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			target, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatalf("parsing %q: %v", tt.url, err)
+			}
+			if err := allowList.Check(target); (err == nil) != tt.allowed {
+				t.Errorf("Check(%s) = %v, want allowed %v", tt.url, err, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestURLAllowListWithoutHostsAllowsNothing(t *testing.T) {
+	fetcher := NewSourceFetcher(NewURLAllowList("http,https", ""), 1024, time.Second)
+	if fetcher.Enabled() {
+		t.Error("Enabled() = true with no allowed hosts")
+	}
+	if _, err := fetcher.Fetch(context.Background(), "https://data.example.com/parcels.geojson"); err == nil {
+		t.Error("Fetch succeeded with no allowed hosts")
+	}
+}
+
+func TestSourceFetcherFetch(t *testing.T) {
+	const (
+		collection = `{"type":"FeatureCollection","features":[]}`
+		maxBytes   = 64
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/collection", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(collection))
+	})
+	mux.HandleFunc("/exact", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat(" ", maxBytes)))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat(" ", maxBytes+1)))
+	})
+	mux.HandleFunc("/large-chunked", func(w http.ResponseWriter, r *http.Request) {
+		// Flushing before the body is complete sends no Content-Length
+		for range maxBytes + 1 {
+			w.Write([]byte(" "))
+			w.(http.Flusher).Flush()
+		}
+	})
+	mux.HandleFunc("/empty", func(w http.ResponseWriter, r *http.Request) {})
+	mux.HandleFunc("/missing", http.NotFound)
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/collection", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	// The allow list names the server by IP, so "localhost" is another host
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/redirect-elsewhere", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://localhost:"+serverURL.Port()+"/collection", http.StatusFound)
+	})
+	fetcher := NewSourceFetcher(NewURLAllowList("http", serverURL.Hostname()), maxBytes, 100*time.Millisecond)
+
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr string
+	}{
+		{name: "collection", url: server.URL + "/collection", want: collection},
+		{name: "body at the size cap", url: server.URL + "/exact", want: strings.Repeat(" ", maxBytes)},
+		{name: "body over the size cap", url: server.URL + "/large", wantErr: "limit"},
+		{name: "streamed body over the size cap", url: server.URL + "/large-chunked", wantErr: "limit"},
+		{name: "empty body", url: server.URL + "/empty", wantErr: "empty"},
+		{name: "error status", url: server.URL + "/missing", wantErr: "404"},
+		{name: "timeout", url: server.URL + "/slow", wantErr: "failed to fetch"},
+		{name: "redirect within the allow list", url: server.URL + "/redirect", want: collection},
+		{name: "redirect outside the allow list", url: server.URL + "/redirect-elsewhere", wantErr: "not allowed"},
+		{name: "host not allowed", url: "http://localhost:" + serverURL.Port() + "/collection", wantErr: "not allowed"},
+		{name: "scheme not allowed", url: "https://" + serverURL.Host + "/collection", wantErr: "not allowed"},
+		{name: "unparseable url", url: "http://%zz", wantErr: "invalid sourceUrl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fetcher.Fetch(context.Background(), tt.url)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Fetch(%s) error = %v, want one containing %q", tt.url, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Fetch(%s): %v", tt.url, err)
+			}
+			if got != tt.want {
+				t.Errorf("Fetch(%s) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
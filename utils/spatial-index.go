@@ -3,6 +3,7 @@ package utils
 import (
 	"fmt"
 	"math"
+	"sort"
 
 	"github.com/twpayne/go-geos"
 )
@@ -11,6 +12,7 @@ type SpatialIndex struct {
 	geometries []*IndexedGeometry
 	cellSize   float64
 	grid       map[string][]*IndexedGeometry
+	quadSegs   int
 }
 
 type IndexedGeometry struct {
@@ -24,13 +26,28 @@ func NewSpatialIndex(cellSize float64) *SpatialIndex {
 		geometries: make([]*IndexedGeometry, 0),
 		cellSize:   cellSize,
 		grid:       make(map[string][]*IndexedGeometry),
+		quadSegs:   DefaultQuadSegs,
 	}
 }
 
-func (si *SpatialIndex) AddGeometry(geom *geos.Geom, index int, properties map[string]interface{}) {
+// SetQuadSegs sets the buffer quadrant segments used by FindNeighbors
+func (si *SpatialIndex) SetQuadSegs(quadSegs int) {
+	if quadSegs > 0 {
+		si.quadSegs = quadSegs
+	}
+}
+
+// AddGeometry indexes geom under index. Geometries whose bounds are not finite
+// (NaN or Inf coordinates) would land in meaningless grid cells, so they are
+// rejected with an error and left out of the index.
+func (si *SpatialIndex) AddGeometry(geom *geos.Geom, index int, properties map[string]interface{}) error {
 	if geom == nil {
 		fmt.Printf("Warning: nil geometry passed to AddGeometry at index %d\n", index)
-		return
+		return fmt.Errorf("geometry %d is nil", index)
+	}
+
+	if err := CheckFiniteBounds(geom); err != nil {
+		return fmt.Errorf("cannot index geometry %d: %v", index, err)
 	}
 
 	indexedGeom := &IndexedGeometry{
@@ -41,6 +58,28 @@ func (si *SpatialIndex) AddGeometry(geom *geos.Geom, index int, properties map[s
 
 	si.geometries = append(si.geometries, indexedGeom)
 	si.addToGrid(indexedGeom)
+	return nil
+}
+
+// CheckFiniteBounds returns an error if any of geom's bounds is NaN or infinite
+func CheckFiniteBounds(geom *geos.Geom) error {
+	bounds := geom.Bounds()
+	if bounds == nil {
+		return fmt.Errorf("geometry has no bounds")
+	}
+	if !finiteBounds(bounds) {
+		return fmt.Errorf("geometry has non-finite bounds (%g, %g, %g, %g)", bounds.MinX, bounds.MinY, bounds.MaxX, bounds.MaxY)
+	}
+	return nil
+}
+
+func finiteBounds(bounds *geos.Box2D) bool {
+	for _, value := range []float64{bounds.MinX, bounds.MinY, bounds.MaxX, bounds.MaxY} {
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return false
+		}
+	}
+	return true
 }
 
 func (si *SpatialIndex) addToGrid(indexedGeom *IndexedGeometry) {
@@ -79,7 +118,7 @@ func (si *SpatialIndex) addToGrid(indexedGeom *IndexedGeometry) {
 }
 
 func (si *SpatialIndex) FindNeighbors(geom *geos.Geom, distance float64) []*IndexedGeometry {
-	buffer := geom.Buffer(distance, 8)
+	buffer := geom.Buffer(distance, si.quadSegs)
 	if buffer == nil {
 		fmt.Printf("Warning: failed to create buffer in FindNeighbors\n")
 		return []*IndexedGeometry{}
@@ -93,6 +132,13 @@ func (si *SpatialIndex) FindNeighbors(geom *geos.Geom, distance float64) []*Inde
 		return []*IndexedGeometry{}
 	}
 
+	// Non-finite bounds would turn the cell range below into a near-endless loop
+	if !finiteBounds(bounds) {
+		fmt.Printf("Warning: non-finite bounds for buffered geometry in FindNeighbors\n")
+		buffer.Destroy()
+		return []*IndexedGeometry{}
+	}
+
 	// Access bounds directly from Box2D struct
 	minX := bounds.MinX
 	minY := bounds.MinY
@@ -125,12 +171,62 @@ func (si *SpatialIndex) FindNeighbors(geom *geos.Geom, distance float64) []*Inde
 			neighbors = append(neighbors, candidate)
 		}
 	}
+	// Map iteration order is random, and callers such as snapping apply
+	// neighbours one after another, so order them for reproducible output
+	sort.Slice(neighbors, func(i, j int) bool {
+		return neighbors[i].Index < neighbors[j].Index
+	})
 
 	buffer.Destroy()
 
 	return neighbors
 }
 
+// GridCell is an occupied cell of a SpatialIndex grid and the indices of the
+// geometries whose bounds touch it
+type GridCell struct {
+	Bounds  geos.Box2D
+	Indices []int
+}
+
+// Cells returns every occupied grid cell, ordered by cell column then row, for
+// inspecting how geometries were bucketed
+func (si *SpatialIndex) Cells() []GridCell {
+	type cellCoordinate struct{ x, y int }
+	coordinates := make([]cellCoordinate, 0, len(si.grid))
+	for cellKey := range si.grid {
+		var coordinate cellCoordinate
+		if _, err := fmt.Sscanf(cellKey, "%d,%d", &coordinate.x, &coordinate.y); err == nil {
+			coordinates = append(coordinates, coordinate)
+		}
+	}
+	sort.Slice(coordinates, func(i, j int) bool {
+		if coordinates[i].x != coordinates[j].x {
+			return coordinates[i].x < coordinates[j].x
+		}
+		return coordinates[i].y < coordinates[j].y
+	})
+
+	cells := make([]GridCell, 0, len(coordinates))
+	for _, coordinate := range coordinates {
+		indexedGeoms := si.grid[getCellKey(coordinate.x, coordinate.y)]
+		indices := make([]int, 0, len(indexedGeoms))
+		for _, indexedGeom := range indexedGeoms {
+			indices = append(indices, indexedGeom.Index)
+		}
+		cells = append(cells, GridCell{
+			Bounds: geos.Box2D{
+				MinX: float64(coordinate.x) * si.cellSize,
+				MinY: float64(coordinate.y) * si.cellSize,
+				MaxX: float64(coordinate.x+1) * si.cellSize,
+				MaxY: float64(coordinate.y+1) * si.cellSize,
+			},
+			Indices: indices,
+		})
+	}
+	return cells
+}
+
 func getCellKey(x, y int) string {
 	return fmt.Sprintf("%d,%d", x, y)
 }
@@ -146,3 +242,10 @@ func CalculateWGS84ToleranceFromMeters(meters float64) float64 {
 	const metersPerDegree = 111000.0
 	return meters / metersPerDegree
 }
+
+// CalculateMetersFromWGS84Degrees converts WGS84 degrees to approximate meters,
+// the inverse of CalculateWGS84ToleranceFromMeters
+func CalculateMetersFromWGS84Degrees(degrees float64) float64 {
+	const metersPerDegree = 111000.0
+	return degrees * metersPerDegree
+}
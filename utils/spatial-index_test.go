@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+)
+
+func TestSpatialIndexRejectsNonFiniteGeometry(t *testing.T) {
+	inf, nan := math.Inf(1), math.NaN()
+
+	tests := []struct {
+		name    string
+		geom    func() *geos.Geom
+		wantErr bool
+	}{
+		{"finite polygon", func() *geos.Geom {
+			return geos.NewPolygon([][][]float64{{{1, 0}, {2, 0}, {2, 1}, {1, 1}, {1, 0}}})
+		}, false},
+		{"polygon with an Inf coordinate", func() *geos.Geom {
+			return geos.NewPolygon([][][]float64{{{1, 0}, {inf, 0}, {2, 1}, {1, 1}, {1, 0}}})
+		}, true},
+		{"line string with a -Inf coordinate", func() *geos.Geom {
+			return geos.NewLineString([][]float64{{1, 0}, {2, -inf}})
+		}, true},
+		{"point with a NaN coordinate", func() *geos.Geom {
+			return geos.NewPointFromXY(nan, 0.5)
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom := tt.geom()
+			defer geom.Destroy()
+
+			if err := CheckFiniteBounds(geom); (err != nil) != tt.wantErr {
+				t.Errorf("CheckFiniteBounds error = %v, want error %v", err, tt.wantErr)
+			}
+
+			index := NewSpatialIndex(1)
+			query := mustGeom(t, "POLYGON ((0 0, 1 0, 1 1, 0 1, 0 0))")
+			defer query.Destroy()
+			if err := index.AddGeometry(query, 0, nil); err != nil {
+				t.Fatalf("AddGeometry(query): %v", err)
+			}
+
+			err := index.AddGeometry(geom, 1, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AddGeometry error = %v, want error %v", err, tt.wantErr)
+			}
+
+			// A rejected geometry must not turn up as anyone's neighbour
+			neighbors := index.FindNeighbors(query, 0.5)
+			wantNeighbors := 1
+			if tt.wantErr {
+				wantNeighbors = 0
+			}
+			if len(neighbors) != wantNeighbors {
+				t.Errorf("FindNeighbors found %d neighbours, want %d", len(neighbors), wantNeighbors)
+			}
+		})
+	}
+}
+
+// gridGeoms parses the polygons of a GenerateGrid collection of 0.001° cells
+func gridGeoms(tb testing.TB, rows, cols int) []*geos.Geom {
+	tb.Helper()
+	var collection struct {
+		Features []struct {
+			Geometry json.RawMessage `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.Unmarshal(GenerateGrid(rows, cols, 0.001), &collection); err != nil {
+		tb.Fatalf("decoding generated grid: %v", err)
+	}
+
+	geoms := make([]*geos.Geom, len(collection.Features))
+	for i, feature := range collection.Features {
+		geom, err := geos.NewGeomFromGeoJSON(string(feature.Geometry))
+		if err != nil {
+			tb.Fatalf("parsing feature %d: %v", i, err)
+		}
+		geoms[i] = geom
+	}
+	tb.Cleanup(func() {
+		for _, geom := range geoms {
+			geom.Destroy()
+		}
+	})
+	return geoms
+}
+
+// indexGeoms adds geoms to a new index under their positions
+func indexGeoms(tb testing.TB, geoms []*geos.Geom, cellSize float64) *SpatialIndex {
+	tb.Helper()
+	index := NewSpatialIndex(cellSize)
+	for i, geom := range geoms {
+		if err := index.AddGeometry(geom, i, nil); err != nil {
+			tb.Fatalf("AddGeometry(%d): %v", i, err)
+		}
+	}
+	return index
+}
+
+func TestSpatialIndexFindNeighborsGrid(t *testing.T) {
+	// A 5x5 grid numbered row by row from the south-west
+	geoms := gridGeoms(t, 5, 5)
+	want := map[int][]int{
+		0:  {1, 5, 6},
+		4:  {3, 8, 9},
+		12: {6, 7, 8, 11, 13, 16, 17, 18},
+		22: {16, 17, 18, 21, 23},
+	}
+
+	// The answer must not depend on how the index divides space
+	for _, cellSize := range []float64{0.0004, 0.001, 0.0025, 0.01} {
+		t.Run(fmt.Sprintf("cell size %g", cellSize), func(t *testing.T) {
+			index := indexGeoms(t, geoms, cellSize)
+			for query, wantNeighbors := range want {
+				got := make([]int, 0)
+				for _, neighbor := range index.FindNeighbors(geoms[query], 1e-7) {
+					got = append(got, neighbor.Index)
+				}
+				if !reflect.DeepEqual(got, wantNeighbors) {
+					t.Errorf("neighbours of cell %d = %v, want %v", query, got, wantNeighbors)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkSpatialIndex measures building an index over a generated grid and
+// querying the neighbours of every cell, the pattern the snapping phase follows
+func BenchmarkSpatialIndex(b *testing.B) {
+	sizes := []struct {
+		rows, cols int
+	}{
+		{10, 100},  // 1k geometries
+		{100, 100}, // 10k geometries
+		{200, 250}, // 50k geometries
+	}
+	// Ten grid cells to an index cell, and a search distance of about 1 cm
+	const cellSize, distance = 0.01, 1e-7
+
+	for _, size := range sizes {
+		geoms := gridGeoms(b, size.rows, size.cols)
+		b.Run(fmt.Sprintf("%d geometries/build", len(geoms)), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				indexGeoms(b, geoms, cellSize)
+			}
+		})
+		b.Run(fmt.Sprintf("%d geometries/query", len(geoms)), func(b *testing.B) {
+			index := indexGeoms(b, geoms, cellSize)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				for _, geom := range geoms {
+					index.FindNeighbors(geom, distance)
+				}
+			}
+		})
+	}
+}
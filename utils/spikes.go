@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"math"
+
+	"github.com/twpayne/go-geos"
+)
+
+// DefaultSpikeAngleDegrees is the vertex angle below which RemoveSpikes treats
+// a vertex as the tip of a spike when a request does not specify one
+const DefaultSpikeAngleDegrees = 1.0
+
+// RemoveSpikes drops ring vertices whose interior angle is below minAngleDeg:
+// the tips of spikes where a boundary juts out and comes straight back, which
+// stay in the output of MakeValid as near-zero-area protrusions. Unlike
+// simplification it leaves every other vertex alone, however close. Removal
+// repeats until no spike remains, since dropping a tip can expose another.
+// Interior rings and polygons left with fewer than three distinct vertices
+// are dropped. It returns a new geometry owned by the caller, nil when
+// nothing survives, and the number of vertices removed. Non-polygonal
+// geometries are returned as copies. Z ordinates are not kept.
+func RemoveSpikes(geom *geos.Geom, minAngleDeg float64) (*geos.Geom, int) {
+	if geom == nil {
+		return nil, 0
+	}
+
+	minAngle := minAngleDeg * math.Pi / 180
+	switch geom.TypeID() {
+	case geos.TypeIDPolygon:
+		polygon, removed := removePolygonSpikes(geom, minAngle)
+		if polygon == nil {
+			return nil, removed
+		}
+		return geos.NewPolygon(polygon), removed
+	case geos.TypeIDMultiPolygon:
+		polygons := make([]*geos.Geom, 0, geom.NumGeometries())
+		total := 0
+		for i := range geom.NumGeometries() {
+			polygon, removed := removePolygonSpikes(geom.Geometry(i), minAngle)
+			total += removed
+			if polygon != nil {
+				polygons = append(polygons, geos.NewPolygon(polygon))
+			}
+		}
+		if len(polygons) == 0 {
+			return nil, total
+		}
+		return geos.NewCollection(geos.TypeIDMultiPolygon, polygons), total
+	default:
+		return geom.Clone(), 0
+	}
+}
+
+// removePolygonSpikes returns the polygon's rings with spikes removed, or nil
+// if the exterior ring collapses
+func removePolygonSpikes(polygon *geos.Geom, minAngle float64) ([][][]float64, int) {
+	if polygon.IsEmpty() {
+		return nil, 0
+	}
+
+	exterior, total := removeRingSpikes(coords2D(polygon.ExteriorRing().CoordSeq()), minAngle)
+	if exterior == nil {
+		return nil, total
+	}
+
+	rings := [][][]float64{exterior}
+	for i := range polygon.NumInteriorRings() {
+		interior, removed := removeRingSpikes(coords2D(polygon.InteriorRing(i).CoordSeq()), minAngle)
+		total += removed
+		if interior != nil {
+			rings = append(rings, interior)
+		}
+	}
+	return rings, total
+}
+
+// removeRingSpikes removes spike vertices from a closed ring, returning the
+// closed result, or nil when fewer than three distinct vertices remain
+func removeRingSpikes(ring [][]float64, minAngle float64) ([][]float64, int) {
+	// Work on the open ring; repeated vertices carry no angle and are merged
+	vertices := make([][]float64, 0, len(ring))
+	for _, vertex := range ring {
+		if len(vertices) == 0 || !samePoint(vertices[len(vertices)-1], vertex) {
+			vertices = append(vertices, vertex)
+		}
+	}
+	for len(vertices) > 1 && samePoint(vertices[0], vertices[len(vertices)-1]) {
+		vertices = vertices[:len(vertices)-1]
+	}
+
+	removed := 0
+	for len(vertices) >= 3 {
+		spike := -1
+		for i := range vertices {
+			previous := vertices[(i+len(vertices)-1)%len(vertices)]
+			next := vertices[(i+1)%len(vertices)]
+			if vertexAngle(previous, vertices[i], next) < minAngle {
+				spike = i
+				break
+			}
+		}
+		if spike < 0 {
+			break
+		}
+
+		vertices = append(vertices[:spike], vertices[spike+1:]...)
+		removed++
+
+		// Dropping a tip can leave its neighbours coincident
+		if len(vertices) > 1 {
+			after := spike % len(vertices)
+			before := (after + len(vertices) - 1) % len(vertices)
+			if samePoint(vertices[before], vertices[after]) {
+				vertices = append(vertices[:after], vertices[after+1:]...)
+			}
+		}
+	}
+
+	if len(vertices) < 3 {
+		return nil, removed
+	}
+	return append(vertices, vertices[0]), removed
+}
+
+// vertexAngle returns the angle in radians at vertex between the segments to previous and next
+func vertexAngle(previous, vertex, next []float64) float64 {
+	ax, ay := previous[0]-vertex[0], previous[1]-vertex[1]
+	bx, by := next[0]-vertex[0], next[1]-vertex[1]
+	return math.Abs(math.Atan2(ax*by-ay*bx, ax*bx+ay*by))
+}
+
+func samePoint(a, b []float64) bool {
+	return a[0] == b[0] && a[1] == b[1]
+}
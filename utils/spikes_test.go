@@ -0,0 +1,88 @@
+package utils
+
+import "testing"
+
+func TestRemoveSpikes(t *testing.T) {
+	const square = "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0))"
+
+	tests := []struct {
+		name    string
+		wkt     string
+		want    string // empty when nothing survives
+		removed int
+	}{
+		{
+			name:    "spike on the exterior",
+			wkt:     "POLYGON ((0 0, 10 0, 10 10, 5 10, 5 20, 5 10, 0 10, 0 0))",
+			want:    square,
+			removed: 1,
+		},
+		{
+			name:    "spike with a narrow base",
+			wkt:     "POLYGON ((0 0, 10 0, 10 10, 5.02 10, 5.01 20, 5 10, 0 10, 0 0))",
+			want:    square,
+			removed: 1,
+		},
+		{
+			name:    "spike on a hole",
+			wkt:     "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 4, 3 4, 3 7, 3 4, 4 4, 4 2, 2 2))",
+			want:    "POLYGON ((0 0, 10 0, 10 10, 0 10, 0 0), (2 2, 2 4, 4 4, 4 2, 2 2))",
+			removed: 1,
+		},
+		{
+			name: "no spikes",
+			wkt:  square,
+			want: square,
+		},
+		{
+			name: "sharp corner above the threshold",
+			wkt:  "POLYGON ((0 0, 10 0, 5 1, 0 0))",
+			want: "POLYGON ((0 0, 10 0, 5 1, 0 0))",
+		},
+		{
+			name:    "collapsed part of a multipolygon is dropped",
+			wkt:     "MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0)), ((20 0, 30 0, 20 0.0001, 20 0)))",
+			want:    "MULTIPOLYGON (((0 0, 10 0, 10 10, 0 10, 0 0)))",
+			removed: 1,
+		},
+		{
+			name:    "polygon that is all spike",
+			wkt:     "POLYGON ((20 0, 30 0, 20 0.0001, 20 0))",
+			removed: 1,
+		},
+		{
+			name: "line strings are copied",
+			wkt:  "LINESTRING (0 0, 5 5, 0 0.01)",
+			want: "LINESTRING (0 0, 5 5, 0 0.01)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			geom := mustGeom(t, tt.wkt)
+			defer geom.Destroy()
+
+			cleaned, removed := RemoveSpikes(geom, DefaultSpikeAngleDegrees)
+			if removed != tt.removed {
+				t.Errorf("removed %d vertices, want %d", removed, tt.removed)
+			}
+			if tt.want == "" {
+				if cleaned != nil {
+					t.Errorf("RemoveSpikes = %s, want nil", cleaned.ToWKT())
+					cleaned.Destroy()
+				}
+				return
+			}
+			if cleaned == nil {
+				t.Fatalf("RemoveSpikes = nil, want %s", tt.want)
+			}
+			defer cleaned.Destroy()
+
+			want := mustGeom(t, tt.want)
+			defer want.Destroy()
+			if cleaned.TypeID() != want.TypeID() || !cleaned.Equals(want) {
+				t.Errorf("RemoveSpikes = %s, want %s", cleaned.ToWKT(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"encoding/json"
+	"math/rand"
+)
+
+// GridDefects injects topology errors into a GenerateGridWithDefects grid.
+// Each ratio is the fraction of cells, between 0 and 1, given that defect;
+// a cell gets at most one, checked in the order gap, overlap, invalid.
+type GridDefects struct {
+	// GapRatio cells have their east edge pulled in by DefectWidthDeg,
+	// leaving a gap to the eastern neighbour
+	GapRatio float64
+	// OverlapRatio cells have their east edge pushed out by DefectWidthDeg,
+	// overlapping the eastern neighbour
+	OverlapRatio float64
+	// InvalidRatio cells have two corners swapped, making a self-intersecting bow-tie
+	InvalidRatio float64
+	// DefectWidthDeg is the gap or overlap width; zero means a tenth of the cell size
+	DefectWidthDeg float64
+	// Seed picks the defective cells; the same seed gives the same grid
+	Seed int64
+}
+
+// GridOriginLon and GridOriginLat are the south-west corner of generated grids.
+// The equator keeps degrees and meters in the fixed ratio the tolerances assume.
+const (
+	GridOriginLon = 0.0
+	GridOriginLat = 0.0
+)
+
+// GenerateGrid returns a GeoJSON FeatureCollection of rows x cols square
+// polygons of cellSizeDeg degrees that share their edges exactly, a clean
+// coverage. Features are ordered row by row from the south-west and carry
+// their id, row and col. The output is deterministic, for benchmark fixtures.
+func GenerateGrid(rows, cols int, cellSizeDeg float64) []byte {
+	return GenerateGridWithDefects(rows, cols, cellSizeDeg, GridDefects{})
+}
+
+// GenerateGridWithDefects is GenerateGrid with gaps, overlaps and invalid
+// geometries injected as described by defects. Defective features carry the
+// defect name in a "defect" property.
+func GenerateGridWithDefects(rows, cols int, cellSizeDeg float64, defects GridDefects) []byte {
+	random := rand.New(rand.NewSource(defects.Seed))
+	width := defects.DefectWidthDeg
+	if width <= 0 {
+		width = cellSizeDeg / 10
+	}
+
+	type feature struct {
+		Type       string                 `json:"type"`
+		Geometry   map[string]interface{} `json:"geometry"`
+		Properties map[string]interface{} `json:"properties"`
+	}
+	features := make([]feature, 0, rows*cols)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			minX := GridOriginLon + float64(col)*cellSizeDeg
+			minY := GridOriginLat + float64(row)*cellSizeDeg
+			maxX, maxY := minX+cellSizeDeg, minY+cellSizeDeg
+			properties := map[string]interface{}{"id": row*cols + col, "row": row, "col": col}
+
+			// One draw per cell keeps the defects of a seed stable across ratios
+			draw := random.Float64()
+			ring := [][]float64{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}, {minX, minY}}
+			switch {
+			case draw < defects.GapRatio:
+				ring[1][0], ring[2][0] = maxX-width, maxX-width
+				properties["defect"] = "gap"
+			case draw < defects.GapRatio+defects.OverlapRatio:
+				ring[1][0], ring[2][0] = maxX+width, maxX+width
+				properties["defect"] = "overlap"
+			case draw < defects.GapRatio+defects.OverlapRatio+defects.InvalidRatio:
+				ring[2], ring[3] = ring[3], ring[2]
+				properties["defect"] = "invalid"
+			}
+
+			features = append(features, feature{
+				Type:       "Feature",
+				Geometry:   map[string]interface{}{"type": "Polygon", "coordinates": [][][]float64{ring}},
+				Properties: properties,
+			})
+		}
+	}
+
+	// Marshalling plain maps and slices cannot fail
+	data, _ := json.Marshal(map[string]interface{}{"type": "FeatureCollection", "features": features})
+	return data
+}
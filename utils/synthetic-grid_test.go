@@ -0,0 +1,212 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// gridCell is a decoded GenerateGrid feature
+type gridCell struct {
+	Geometry struct {
+		Type        string        `json:"type"`
+		Coordinates [][][]float64 `json:"coordinates"`
+	} `json:"geometry"`
+	Properties struct {
+		ID     int    `json:"id"`
+		Row    int    `json:"row"`
+		Col    int    `json:"col"`
+		Defect string `json:"defect"`
+	} `json:"properties"`
+}
+
+func decodeGrid(t testing.TB, data []byte) []gridCell {
+	t.Helper()
+	var collection struct {
+		Type     string     `json:"type"`
+		Features []gridCell `json:"features"`
+	}
+	if err := json.Unmarshal(data, &collection); err != nil {
+		t.Fatalf("decoding generated grid: %v", err)
+	}
+	if collection.Type != "FeatureCollection" {
+		t.Fatalf("type = %q, want FeatureCollection", collection.Type)
+	}
+	return collection.Features
+}
+
+func TestGenerateGrid(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows, cols int
+		cellSize   float64
+	}{
+		{"single cell", 1, 1, 1},
+		{"wide grid", 2, 5, 0.001},
+		{"tall grid", 4, 3, 0.25},
+		{"empty grid", 0, 10, 0.001},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := GenerateGrid(tt.rows, tt.cols, tt.cellSize)
+			if !bytes.Equal(data, GenerateGrid(tt.rows, tt.cols, tt.cellSize)) {
+				t.Error("GenerateGrid is not deterministic")
+			}
+
+			cells := decodeGrid(t, data)
+			if len(cells) != tt.rows*tt.cols {
+				t.Fatalf("got %d features, want %d", len(cells), tt.rows*tt.cols)
+			}
+			for i, cell := range cells {
+				row, col := i/tt.cols, i%tt.cols
+				if cell.Properties.ID != i || cell.Properties.Row != row || cell.Properties.Col != col || cell.Properties.Defect != "" {
+					t.Errorf("feature %d has properties %+v, want id %d row %d col %d", i, cell.Properties, i, row, col)
+				}
+
+				minX, minY := GridOriginLon+float64(col)*tt.cellSize, GridOriginLat+float64(row)*tt.cellSize
+				maxX, maxY := minX+tt.cellSize, minY+tt.cellSize
+				want := [][]float64{{minX, minY}, {maxX, minY}, {maxX, maxY}, {minX, maxY}, {minX, minY}}
+				if cell.Geometry.Type != "Polygon" || len(cell.Geometry.Coordinates) != 1 || !sameRing(cell.Geometry.Coordinates[0], want) {
+					t.Errorf("feature %d geometry = %v, want the ring %v", i, cell.Geometry.Coordinates, want)
+				}
+			}
+
+			// Neighbours share their edges exactly
+			for i, cell := range cells {
+				if cell.Properties.Col+1 < tt.cols {
+					east := cells[i+1].Geometry.Coordinates[0]
+					ring := cell.Geometry.Coordinates[0]
+					if ring[1][0] != east[0][0] || ring[2][0] != east[3][0] {
+						t.Errorf("feature %d does not share its east edge with feature %d", i, i+1)
+					}
+				}
+			}
+		})
+	}
+}
+
+func sameRing(a, b [][]float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if math.Abs(a[i][0]-b[i][0]) > 1e-12 || math.Abs(a[i][1]-b[i][1]) > 1e-12 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGenerateGridWithDefects(t *testing.T) {
+	const rows, cols, cellSize = 40, 50, 0.001
+
+	tests := []struct {
+		name    string
+		defects GridDefects
+		ratios  map[string]float64
+	}{
+		{"no defects", GridDefects{Seed: 1}, map[string]float64{}},
+		{"gaps only", GridDefects{GapRatio: 0.1, Seed: 1}, map[string]float64{"gap": 0.1}},
+		{"every kind", GridDefects{GapRatio: 0.05, OverlapRatio: 0.1, InvalidRatio: 0.2, Seed: 7}, map[string]float64{"gap": 0.05, "overlap": 0.1, "invalid": 0.2}},
+		{"every cell", GridDefects{OverlapRatio: 1, Seed: 3}, map[string]float64{"overlap": 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := GenerateGridWithDefects(rows, cols, cellSize, tt.defects)
+			if !bytes.Equal(data, GenerateGridWithDefects(rows, cols, cellSize, tt.defects)) {
+				t.Error("the same seed gave a different grid")
+			}
+
+			counts := make(map[string]int)
+			for _, cell := range decodeGrid(t, data) {
+				if cell.Properties.Defect == "" {
+					continue
+				}
+				counts[cell.Properties.Defect]++
+				checkDefect(t, cell, cellSize, cellSize/10)
+			}
+
+			for defect, ratio := range tt.ratios {
+				// A 2000-cell draw lands well within 3 percentage points of the ratio
+				got := float64(counts[defect]) / (rows * cols)
+				if math.Abs(got-ratio) > 0.03 {
+					t.Errorf("%s ratio = %.3f, want about %.3f", defect, got, ratio)
+				}
+			}
+			for defect, count := range counts {
+				if _, ok := tt.ratios[defect]; !ok {
+					t.Errorf("%d unexpected %s defects", count, defect)
+				}
+			}
+		})
+	}
+}
+
+// checkDefect verifies a defective cell's ring has the shape its defect names
+func checkDefect(t testing.TB, cell gridCell, cellSize, width float64) {
+	t.Helper()
+	ring := cell.Geometry.Coordinates[0]
+	minX := GridOriginLon + float64(cell.Properties.Col)*cellSize
+	maxX := minX + cellSize
+
+	var wantEast float64
+	switch cell.Properties.Defect {
+	case "gap":
+		wantEast = maxX - width
+	case "overlap":
+		wantEast = maxX + width
+	case "invalid":
+		// Swapping the north-east and north-west corners crosses the ring
+		if ring[2][0] >= ring[3][0] {
+			t.Errorf("feature %d is not a bow-tie: %v", cell.Properties.ID, ring)
+		}
+		return
+	default:
+		t.Errorf("feature %d has unknown defect %q", cell.Properties.ID, cell.Properties.Defect)
+		return
+	}
+	if math.Abs(ring[1][0]-wantEast) > 1e-12 || math.Abs(ring[2][0]-wantEast) > 1e-12 {
+		t.Errorf("feature %d %s has east edge at %v, want %v", cell.Properties.ID, cell.Properties.Defect, ring[1][0], wantEast)
+	}
+}
+
+func TestGenerateGridWithDefectsSeeds(t *testing.T) {
+	defectCells := func(defects GridDefects) map[int]string {
+		cells := make(map[int]string)
+		for _, cell := range decodeGrid(t, GenerateGridWithDefects(20, 20, 0.001, defects)) {
+			if cell.Properties.Defect != "" {
+				cells[cell.Properties.ID] = cell.Properties.Defect
+			}
+		}
+		return cells
+	}
+
+	seed1 := defectCells(GridDefects{GapRatio: 0.2, Seed: 1})
+	seed2 := defectCells(GridDefects{GapRatio: 0.2, Seed: 2})
+	if len(seed1) == 0 || len(seed2) == 0 {
+		t.Fatal("no defects injected")
+	}
+	same := len(seed1) == len(seed2)
+	for id := range seed1 {
+		if _, ok := seed2[id]; !ok {
+			same = false
+		}
+	}
+	if same {
+		t.Error("seeds 1 and 2 picked the same cells")
+	}
+
+	// Raising a ratio only adds defective cells to those a seed already picked
+	more := defectCells(GridDefects{GapRatio: 0.4, Seed: 1})
+	for id := range seed1 {
+		if more[id] != "gap" {
+			t.Errorf("cell %d has a gap at ratio 0.2 but not at 0.4", id)
+		}
+	}
+	if len(more) <= len(seed1) {
+		t.Errorf("ratio 0.4 gave %d gaps, want more than the %d of ratio 0.2", len(more), len(seed1))
+	}
+}
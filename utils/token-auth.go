@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// TokenAuth guards handlers with a shared bearer token. It is a simple
+// network-level guard, not per-user authentication: every client presents
+// the same token. An empty token disables it.
+type TokenAuth struct {
+	token []byte
+}
+
+// NewTokenAuth creates a guard that accepts requests carrying
+// "Authorization: Bearer <token>"; with an empty token every request passes
+func NewTokenAuth(token string) *TokenAuth {
+	return &TokenAuth{token: []byte(token)}
+}
+
+// Enabled reports whether a token is configured
+func (ta *TokenAuth) Enabled() bool {
+	return len(ta.token) > 0
+}
+
+// Authorized reports whether the request carries the configured token
+func (ta *TokenAuth) Authorized(r *http.Request) bool {
+	if !ta.Enabled() {
+		return true
+	}
+
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return false
+	}
+	// Constant time so the token cannot be guessed from response timings
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), ta.token) == 1
+}
+
+// Require wraps a handler so it only runs for authorized requests, responding
+// with 401 Unauthorized otherwise
+func (ta *TokenAuth) Require(next http.HandlerFunc) http.HandlerFunc {
+	if !ta.Enabled() {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !ta.Authorized(r) {
+			log.Printf("Rejecting %s from %s: missing or invalid bearer token", r.URL.Path, r.RemoteAddr)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="go-polygon-fixer"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,124 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// MaxMercatorLatitude is the latitude at which Web Mercator's square world
+// ends; the projection diverges towards the poles, so positions beyond it are
+// clamped
+const MaxMercatorLatitude = 85.05112878
+
+// WebMercatorCRSName names EPSG:3857 in a legacy GeoJSON crs member
+const WebMercatorCRSName = "urn:ogc:def:crs:EPSG::3857"
+
+// WebMercator projects a WGS84 longitude/latitude to EPSG:3857 meters,
+// clamping the latitude to ±MaxMercatorLatitude. It reports whether the
+// latitude was clamped.
+func WebMercator(lon, lat float64) (x, y float64, clamped bool) {
+	if lat > MaxMercatorLatitude {
+		lat, clamped = MaxMercatorLatitude, true
+	} else if lat < -MaxMercatorLatitude {
+		lat, clamped = -MaxMercatorLatitude, true
+	}
+
+	// EPSG:3857 uses the WGS84 semi-major axis as a sphere radius
+	const radius = 6378137.0
+	x = radius * lon * math.Pi / 180
+	y = radius * math.Log(math.Tan(math.Pi/4+lat*math.Pi/360))
+	return x, y, clamped
+}
+
+// WebMercatorGeoJSON projects every position of a GeoJSON geometry from WGS84
+// to EPSG:3857, keeping any Z ordinate. Like RoundGeoJSONCoordinates it works
+// on the serialized GeoJSON. It returns the projected geometry and how many
+// positions had their latitude clamped.
+func WebMercatorGeoJSON(geometry json.RawMessage) (json.RawMessage, int, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(geometry, &members); err != nil {
+		return nil, 0, fmt.Errorf("malformed geometry: %v", err)
+	}
+
+	clamped := 0
+	if rawCoordinates, ok := members["coordinates"]; ok {
+		var coordinates interface{}
+		if err := json.Unmarshal(rawCoordinates, &coordinates); err != nil {
+			return nil, 0, fmt.Errorf("malformed coordinates: %v", err)
+		}
+		clamped += projectCoordinates(coordinates)
+		projected, err := json.Marshal(coordinates)
+		if err != nil {
+			return nil, 0, err
+		}
+		members["coordinates"] = projected
+	}
+
+	if rawGeometries, ok := members["geometries"]; ok {
+		var geometries []json.RawMessage
+		if err := json.Unmarshal(rawGeometries, &geometries); err != nil {
+			return nil, 0, fmt.Errorf("malformed geometries: %v", err)
+		}
+		for i, member := range geometries {
+			projected, memberClamped, err := WebMercatorGeoJSON(member)
+			if err != nil {
+				return nil, 0, err
+			}
+			geometries[i] = projected
+			clamped += memberClamped
+		}
+		projected, err := json.Marshal(geometries)
+		if err != nil {
+			return nil, 0, err
+		}
+		members["geometries"] = projected
+	}
+
+	projected, err := json.Marshal(members)
+	return projected, clamped, err
+}
+
+// projectCoordinates projects, in place, the positions of an arbitrarily
+// nested coordinate array and returns how many were clamped
+func projectCoordinates(value interface{}) int {
+	coordinates, ok := value.([]interface{})
+	if !ok || len(coordinates) == 0 {
+		return 0
+	}
+
+	// A position is an array of numbers, anything else an array of arrays
+	lon, isPosition := coordinates[0].(float64)
+	if !isPosition {
+		clamped := 0
+		for _, member := range coordinates {
+			clamped += projectCoordinates(member)
+		}
+		return clamped
+	}
+	if len(coordinates) < 2 {
+		return 0
+	}
+	lat, ok := coordinates[1].(float64)
+	if !ok {
+		return 0
+	}
+
+	x, y, clamped := WebMercator(lon, lat)
+	coordinates[0], coordinates[1] = x, y
+	if clamped {
+		return 1
+	}
+	return 0
+}
+
+// WebMercatorBBox projects a [minX, minY, maxX, maxY] bbox to EPSG:3857. The
+// projection is monotonic in each axis, so the corners stay the bounds.
+func WebMercatorBBox(bbox []float64) []float64 {
+	if len(bbox) != 4 {
+		return bbox
+	}
+	minX, minY, _ := WebMercator(bbox[0], bbox[1])
+	maxX, maxY, _ := WebMercator(bbox[2], bbox[3])
+	return []float64{minX, minY, maxX, maxY}
+}
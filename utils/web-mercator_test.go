@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+// mercatorHalfWorld is the EPSG:3857 x of longitude 180, and the y of MaxMercatorLatitude
+const mercatorHalfWorld = 20037508.342789244
+
+func TestWebMercator(t *testing.T) {
+	tests := []struct {
+		name     string
+		lon, lat float64
+		x, y     float64
+		clamped  bool
+	}{
+		{"origin", 0, 0, 0, 0, false},
+		{"antimeridian", 180, 0, mercatorHalfWorld, 0, false},
+		{"west antimeridian", -180, 0, -mercatorHalfWorld, 0, false},
+		{"known coordinate", 10, 10, 1113194.9079327357, 1118889.9748579594, false},
+		{"southern hemisphere", -10, -10, -1113194.9079327357, -1118889.9748579594, false},
+		{"edge of the valid range", 0, MaxMercatorLatitude, 0, mercatorHalfWorld, false},
+		{"north pole is clamped", 0, 90, 0, mercatorHalfWorld, true},
+		{"south pole is clamped", 0, -90, 0, -mercatorHalfWorld, true},
+		{"just past the range is clamped", 45, 85.1, mercatorHalfWorld / 4, mercatorHalfWorld, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y, clamped := WebMercator(tt.lon, tt.lat)
+			// Within a centimetre
+			if math.Abs(x-tt.x) > 0.01 || math.Abs(y-tt.y) > 0.01 {
+				t.Errorf("WebMercator(%v, %v) = (%v, %v), want (%v, %v)", tt.lon, tt.lat, x, y, tt.x, tt.y)
+			}
+			if clamped != tt.clamped {
+				t.Errorf("clamped = %v, want %v", clamped, tt.clamped)
+			}
+		})
+	}
+}
+
+func TestWebMercatorGeoJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		geometry string
+		want     string
+		clamped  int
+	}{
+		{
+			name:     "point keeps Z",
+			geometry: `{"type":"Point","coordinates":[180,0,12.5]}`,
+			want:     `{"type":"Point","coordinates":[20037508.342789244,0,12.5]}`,
+		},
+		{
+			name:     "polygon over the pole",
+			geometry: `{"type":"Polygon","coordinates":[[[0,0],[180,0],[180,90],[0,90],[0,0]]]}`,
+			want:     `{"type":"Polygon","coordinates":[[[0,0],[20037508.342789244,0],[20037508.342789244,20037508.342789244],[0,20037508.342789244],[0,0]]]}`,
+			clamped:  2,
+		},
+		{
+			name:     "geometry collection",
+			geometry: `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[-180,-90]},{"type":"Point","coordinates":[0,0]}]}`,
+			want:     `{"type":"GeometryCollection","geometries":[{"type":"Point","coordinates":[-20037508.342789244,-20037508.342789244]},{"type":"Point","coordinates":[0,0]}]}`,
+			clamped:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projected, clamped, err := WebMercatorGeoJSON(json.RawMessage(tt.geometry))
+			if err != nil {
+				t.Fatalf("WebMercatorGeoJSON: %v", err)
+			}
+			if clamped != tt.clamped {
+				t.Errorf("clamped %d positions, want %d", clamped, tt.clamped)
+			}
+			assertCoordinatesNear(t, projected, tt.want)
+		})
+	}
+}
+
+func TestWebMercatorGeoJSONMalformed(t *testing.T) {
+	for _, geometry := range []string{`[]`, `{"type":"Point","coordinates":[0,`, `{"type":"GeometryCollection","geometries":{}}`} {
+		if _, _, err := WebMercatorGeoJSON(json.RawMessage(geometry)); err == nil {
+			t.Errorf("WebMercatorGeoJSON(%s) succeeded, want an error", geometry)
+		}
+	}
+}
+
+func TestWebMercatorBBox(t *testing.T) {
+	got := WebMercatorBBox([]float64{-180, -90, 10, 10})
+	want := []float64{-mercatorHalfWorld, -mercatorHalfWorld, 1113194.9079327357, 1118889.9748579594}
+	for i := range want {
+		if math.Abs(got[i]-want[i]) > 0.01 {
+			t.Errorf("WebMercatorBBox = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+// assertCoordinatesNear fails the test unless got and want are the same
+// GeoJSON up to a centimetre in any number
+func assertCoordinatesNear(t testing.TB, got json.RawMessage, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Fatalf("decoding %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("decoding %s: %v", want, err)
+	}
+	if !valuesNear(gotValue, wantValue) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func valuesNear(a, b interface{}) bool {
+	switch a := a.(type) {
+	case float64:
+		b, ok := b.(float64)
+		return ok && math.Abs(a-b) <= 0.01
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !valuesNear(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for key := range a {
+			if !valuesNear(a[key], b[key]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
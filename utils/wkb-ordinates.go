@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/twpayne/go-geos"
+)
+
+// Extended WKB flags GEOS and PostGIS set in the high bits of the type code
+const (
+	ewkbZ    = 0x80000000
+	ewkbM    = 0x40000000
+	ewkbSRID = 0x20000000
+)
+
+// TruncateXY rounds the X and Y of every position in geom to precision decimal
+// places, keeping Z and M ordinates as they are. Unlike TruncateFullGeometry,
+// which rebuilds polygons from X/Y alone, it works on any geometry type, so
+// measured (M) linework such as road segments survives. M is only read and
+// written by GEOS 3.12 and later.
+func TruncateXY(geom *geos.Geom, precision int) (*geos.Geom, error) {
+	if geom == nil {
+		return nil, fmt.Errorf(`geometry is nil`)
+	}
+
+	wkb, err := TruncateWKB(geom.ToWKB(), precision)
+	if err != nil {
+		return nil, err
+	}
+	return geos.NewGeomFromWKB(wkb)
+}
+
+// TruncateWKB rounds the X and Y of every position in a WKB geometry to
+// precision decimal places, leaving Z and M ordinates untouched. Both ISO WKB
+// (type codes offset by 1000 for Z, 2000 for M and 3000 for ZM) and extended
+// WKB (flag bits, with an optional SRID) are read, in either byte order. The
+// input is not modified.
+func TruncateWKB(wkb []byte, precision int) ([]byte, error) {
+	if precision < 0 {
+		return nil, fmt.Errorf("negative precision %d", precision)
+	}
+	rounder := &wkbRounder{data: bytes.Clone(wkb), precision: uint(precision)}
+	if err := rounder.geometry(); err != nil {
+		return nil, err
+	}
+	if rounder.offset != len(rounder.data) {
+		return nil, fmt.Errorf("malformed WKB: %d trailing bytes", len(rounder.data)-rounder.offset)
+	}
+	return rounder.data, nil
+}
+
+// wkbRounder walks a WKB geometry in place, rounding X and Y as it goes
+type wkbRounder struct {
+	data      []byte
+	offset    int
+	precision uint
+	order     binary.ByteOrder
+}
+
+// geometry rounds one geometry, including its byte order and type header
+func (r *wkbRounder) geometry() error {
+	if r.offset >= len(r.data) {
+		return fmt.Errorf("malformed WKB: truncated at byte %d", r.offset)
+	}
+	switch r.data[r.offset] {
+	case 0:
+		r.order = binary.BigEndian
+	case 1:
+		r.order = binary.LittleEndian
+	default:
+		return fmt.Errorf("malformed WKB: invalid byte order %d at byte %d", r.data[r.offset], r.offset)
+	}
+	r.offset++
+
+	typeCode, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	dimensions := 2
+	if typeCode&ewkbZ != 0 {
+		dimensions++
+	}
+	if typeCode&ewkbM != 0 {
+		dimensions++
+	}
+	if typeCode&ewkbSRID != 0 {
+		if _, err := r.uint32(); err != nil {
+			return err
+		}
+	}
+	typeCode &^= ewkbZ | ewkbM | ewkbSRID
+	switch typeCode / 1000 {
+	case 0:
+	case 1, 2:
+		dimensions++
+	case 3:
+		dimensions += 2
+	default:
+		return fmt.Errorf("malformed WKB: unsupported geometry type %d", typeCode)
+	}
+
+	switch typeCode % 1000 {
+	case 1: // Point
+		return r.positions(1, dimensions)
+	case 2: // LineString
+		return r.positionList(dimensions)
+	case 3: // Polygon
+		rings, err := r.uint32()
+		if err != nil {
+			return err
+		}
+		for range rings {
+			if err := r.positionList(dimensions); err != nil {
+				return err
+			}
+		}
+		return nil
+	case 4, 5, 6, 7: // MultiPoint, MultiLineString, MultiPolygon, GeometryCollection
+		parts, err := r.uint32()
+		if err != nil {
+			return err
+		}
+		for range parts {
+			if err := r.geometry(); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("malformed WKB: unsupported geometry type %d", typeCode)
+	}
+}
+
+// positionList rounds a count-prefixed run of positions
+func (r *wkbRounder) positionList(dimensions int) error {
+	count, err := r.uint32()
+	if err != nil {
+		return err
+	}
+	return r.positions(int(count), dimensions)
+}
+
+// positions rounds the first two ordinates of count positions of dimensions doubles each
+func (r *wkbRounder) positions(count int, dimensions int) error {
+	stride := dimensions * 8
+	if count > (len(r.data)-r.offset)/stride {
+		return fmt.Errorf("malformed WKB: %d positions overrun the data at byte %d", count, r.offset)
+	}
+
+	for i := range count {
+		for ordinate := range 2 {
+			at := r.offset + i*stride + ordinate*8
+			value := math.Float64frombits(r.order.Uint64(r.data[at:]))
+			r.order.PutUint64(r.data[at:], math.Float64bits(roundFloat(value, r.precision)))
+		}
+	}
+	r.offset += count * stride
+	return nil
+}
+
+// uint32 reads the next unsigned integer in the current byte order
+func (r *wkbRounder) uint32() (uint32, error) {
+	if len(r.data)-r.offset < 4 {
+		return 0, fmt.Errorf("malformed WKB: truncated at byte %d", r.offset)
+	}
+	value := r.order.Uint32(r.data[r.offset:])
+	r.offset += 4
+	return value, nil
+}
@@ -0,0 +1,283 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/twpayne/go-geos"
+)
+
+// wkbWriter builds WKB by hand so tests control the type codes and byte order
+type wkbWriter struct {
+	bytes.Buffer
+	order binary.ByteOrder
+}
+
+func newWKBWriter(order binary.ByteOrder) *wkbWriter {
+	return &wkbWriter{order: order}
+}
+
+// header writes a byte order marker and type code
+func (w *wkbWriter) header(typeCode uint32) *wkbWriter {
+	if w.order == binary.BigEndian {
+		w.WriteByte(0)
+	} else {
+		w.WriteByte(1)
+	}
+	return w.uint32(typeCode)
+}
+
+func (w *wkbWriter) uint32(value uint32) *wkbWriter {
+	binary.Write(w, w.order, value)
+	return w
+}
+
+// positions writes a count-prefixed run of positions
+func (w *wkbWriter) positions(positions ...[]float64) *wkbWriter {
+	w.uint32(uint32(len(positions)))
+	for _, position := range positions {
+		binary.Write(w, w.order, position)
+	}
+	return w
+}
+
+// decodeWKBPositions reads back every position of a single-level geometry
+// written by wkbWriter: a LineString, or a multi geometry of LineStrings
+func decodeWKBPositions(t *testing.T, wkb []byte, order binary.ByteOrder, dimensions int, hasSRID bool) [][]float64 {
+	t.Helper()
+	reader := bytes.NewReader(wkb)
+	var positions [][]float64
+	readHeader := func() uint32 {
+		reader.ReadByte()
+		var typeCode uint32
+		binary.Read(reader, order, &typeCode)
+		return typeCode
+	}
+
+	readLineString := func() {
+		var count uint32
+		binary.Read(reader, order, &count)
+		for range count {
+			position := make([]float64, dimensions)
+			binary.Read(reader, order, position)
+			positions = append(positions, position)
+		}
+	}
+
+	typeCode := readHeader()
+	if hasSRID {
+		var srid uint32
+		binary.Read(reader, order, &srid)
+	}
+	if (typeCode&^(ewkbZ|ewkbM|ewkbSRID))%1000 == 2 {
+		readLineString()
+		return positions
+	}
+
+	var parts uint32
+	binary.Read(reader, order, &parts)
+	for range parts {
+		readHeader()
+		readLineString()
+	}
+	if reader.Len() != 0 {
+		t.Fatalf("%d bytes left after decoding", reader.Len())
+	}
+	return positions
+}
+
+func TestTruncateWKB(t *testing.T) {
+	measured := [][]float64{
+		{0.123456789, 1.987654321, 10.123456789},
+		{2.555555555, 3.444444444, 20.987654321},
+	}
+	measured2 := [][]float64{
+		{-4.000000049, 5.999999951, 30.5},
+		{-6.1, 7.2, 40.000000001},
+	}
+
+	tests := []struct {
+		name       string
+		order      binary.ByteOrder
+		wkb        []byte
+		dimensions int
+		hasSRID    bool
+		want       [][]float64
+	}{
+		{
+			name:  "iso multilinestring m",
+			order: binary.LittleEndian,
+			wkb: func() []byte {
+				w := newWKBWriter(binary.LittleEndian).header(2005).uint32(2)
+				w.header(2002).positions(measured...)
+				w.header(2002).positions(measured2...)
+				return w.Bytes()
+			}(),
+			dimensions: 3,
+			want: [][]float64{
+				{0.1235, 1.9877, 10.123456789},
+				{2.5556, 3.4444, 20.987654321},
+				{-4, 6, 30.5},
+				{-6.1, 7.2, 40.000000001},
+			},
+		},
+		{
+			name:  "extended multilinestring m with srid, big-endian",
+			order: binary.BigEndian,
+			wkb: func() []byte {
+				w := newWKBWriter(binary.BigEndian).header(5 | ewkbM | ewkbSRID).uint32(4326).uint32(1)
+				w.header(2 | ewkbM).positions(measured...)
+				return w.Bytes()
+			}(),
+			dimensions: 3,
+			hasSRID:    true,
+			want: [][]float64{
+				{0.1235, 1.9877, 10.123456789},
+				{2.5556, 3.4444, 20.987654321},
+			},
+		},
+		{
+			name:       "iso linestring zm",
+			order:      binary.LittleEndian,
+			wkb:        newWKBWriter(binary.LittleEndian).header(3002).positions([]float64{1.23456, 2.34567, 100.123456, 5.654321}).Bytes(),
+			dimensions: 4,
+			want:       [][]float64{{1.2346, 2.3457, 100.123456, 5.654321}},
+		},
+		{
+			name:       "extended linestring z",
+			order:      binary.LittleEndian,
+			wkb:        newWKBWriter(binary.LittleEndian).header(2 | ewkbZ).positions([]float64{1.23456, 2.34567, 100.123456}).Bytes(),
+			dimensions: 3,
+			want:       [][]float64{{1.2346, 2.3457, 100.123456}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := bytes.Clone(tt.wkb)
+			truncated, err := TruncateWKB(tt.wkb, 4)
+			if err != nil {
+				t.Fatalf("TruncateWKB: %v", err)
+			}
+			if !bytes.Equal(tt.wkb, input) {
+				t.Error("TruncateWKB modified its input")
+			}
+			if len(truncated) != len(tt.wkb) {
+				t.Fatalf("truncated WKB is %d bytes, want %d", len(truncated), len(tt.wkb))
+			}
+
+			got := decodeWKBPositions(t, truncated, tt.order, tt.dimensions, tt.hasSRID)
+			if len(got) != len(tt.want) {
+				t.Fatalf("decoded %d positions, want %d", len(got), len(tt.want))
+			}
+			for i := range tt.want {
+				for d := range tt.want[i] {
+					if math.Abs(got[i][d]-tt.want[i][d]) > 1e-12 {
+						t.Errorf("position %d = %v, want %v", i, got[i], tt.want[i])
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestTruncateWKBPolygonAndCollection(t *testing.T) {
+	// GEOMETRYCOLLECTION (POINT (0.12345 0.98765), POLYGON ((0 0, 1.00004 0, 1 1, 0 0)))
+	w := newWKBWriter(binary.LittleEndian).header(7).uint32(2)
+	w.header(1)
+	binary.Write(w, w.order, []float64{0.12345, 0.98765})
+	w.header(3).uint32(1).positions([]float64{0, 0}, []float64{1.00004, 0}, []float64{1, 1}, []float64{0, 0})
+
+	truncated, err := TruncateWKB(w.Bytes(), 3)
+	if err != nil {
+		t.Fatalf("TruncateWKB: %v", err)
+	}
+
+	want := newWKBWriter(binary.LittleEndian).header(7).uint32(2)
+	want.header(1)
+	binary.Write(want, want.order, []float64{0.123, 0.988})
+	want.header(3).uint32(1).positions([]float64{0, 0}, []float64{1, 0}, []float64{1, 1}, []float64{0, 0})
+	if !bytes.Equal(truncated, want.Bytes()) {
+		t.Errorf("TruncateWKB = %x, want %x", truncated, want.Bytes())
+	}
+}
+
+func TestTruncateWKBMalformed(t *testing.T) {
+	lineString := newWKBWriter(binary.LittleEndian).header(2002).positions([]float64{1, 2, 3}).Bytes()
+
+	tests := []struct {
+		name      string
+		wkb       []byte
+		precision int
+	}{
+		{"empty", nil, 4},
+		{"invalid byte order", append([]byte{7}, lineString[1:]...), 4},
+		{"truncated header", lineString[:3], 4},
+		{"truncated positions", lineString[:len(lineString)-1], 4},
+		{"trailing bytes", append(bytes.Clone(lineString), 0), 4},
+		{"unsupported type", newWKBWriter(binary.LittleEndian).header(17).Bytes(), 4},
+		{"unsupported dimension offset", newWKBWriter(binary.LittleEndian).header(4002).positions().Bytes(), 4},
+		{"position count past the end", newWKBWriter(binary.LittleEndian).header(2).uint32(math.MaxUint32).Bytes(), 4},
+		{"negative precision", lineString, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := TruncateWKB(tt.wkb, tt.precision); err == nil {
+				t.Error("TruncateWKB accepted malformed input")
+			}
+		})
+	}
+}
+
+func TestTruncateXYMultiLineStringM(t *testing.T) {
+	if geos.VersionCompare(3, 12, 0) < 0 {
+		t.Skipf("GEOS %d.%d.%d reads and writes no M ordinates", geos.VersionMajor, geos.VersionMinor, geos.VersionPatch)
+	}
+
+	const (
+		input = "MULTILINESTRING M ((0.123456789 1.987654321 10.5, 2.555555555 3.444444444 20.25), (4.1 5.2 30, 6.99999 7.00001 40.125))"
+		want  = "MULTILINESTRING M ((0.1235 1.9877 10.5, 2.5556 3.4444 20.25), (4.1 5.2 30, 7 7 40.125))"
+	)
+
+	for _, format := range []string{GeometryFormatWKT, GeometryFormatWKB} {
+		t.Run(format, func(t *testing.T) {
+			geom := mustGeom(t, input)
+			defer geom.Destroy()
+
+			// Through the format a client would send and receive
+			parsed, err := ParseGeometry(FormatGeometry(geom, format), format)
+			if err != nil {
+				t.Fatalf("ParseGeometry: %v", err)
+			}
+			defer parsed.Destroy()
+
+			truncated, err := TruncateXY(parsed, 4)
+			if err != nil {
+				t.Fatalf("TruncateXY: %v", err)
+			}
+			defer truncated.Destroy()
+
+			output, err := ParseGeometry(FormatGeometry(truncated, format), format)
+			if err != nil {
+				t.Fatalf("ParseGeometry of the output: %v", err)
+			}
+			defer output.Destroy()
+
+			got := output.ToWKT()
+			if !strings.HasPrefix(got, "MULTILINESTRING M ") {
+				t.Fatalf("output %s lost its M ordinates", got)
+			}
+			// EqualsExact ignores M, so compare through the same WKT writer
+			wantGeom := mustGeom(t, want)
+			defer wantGeom.Destroy()
+			if got != wantGeom.ToWKT() {
+				t.Errorf("output = %s, want %s", got, wantGeom.ToWKT())
+			}
+		})
+	}
+}
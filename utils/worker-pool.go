@@ -2,7 +2,9 @@ package utils
 
 import (
 	"fmt"
+	"log"
 	"runtime"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -54,12 +56,25 @@ func (wp *WorkerPool) worker(id int, workFunc func(interface{}) interface{}) {
 	defer wp.wg.Done()
 	
 	for job := range wp.JobQueue {
-		result := workFunc(job)
+		result := runJob(workFunc, job)
 		// Always send the result, even if it's nil
 		wp.Results <- result
 	}
 }
 
+// runJob calls workFunc on job, turning a panic into a nil result so one bad
+// job is skipped instead of crashing the process. A recover in the caller of
+// ProcessBatch cannot catch panics raised on worker goroutines.
+func runJob(workFunc func(interface{}) interface{}, job interface{}) (result interface{}) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("PANIC recovered in worker, skipping job: %v", r)
+			result = nil
+		}
+	}()
+	return workFunc(job)
+}
+
 // SubmitJob adds a job to the job queue
 func (wp *WorkerPool) SubmitJob(job interface{}) {
 	wp.JobQueue <- job
@@ -105,6 +120,130 @@ func (pt *ProgressTracker) GetProgress() (int64, int64, float64) {
 	return processed, pt.Total, percentage
 }
 
+// FeatureTiming is the processing time spent on one feature, per phase
+type FeatureTiming struct {
+	Index    int                `json:"index"`
+	TotalMs  float64            `json:"totalMs"`
+	PhasesMs map[string]float64 `json:"phasesMs"`
+}
+
+// FeatureProfiler accumulates per-feature processing time across pipeline
+// phases. All methods are safe for concurrent use and are no-ops on a nil
+// profiler, so phases can call them unconditionally.
+type FeatureProfiler struct {
+	mu        sync.Mutex
+	durations map[int]map[string]time.Duration
+	indexMap  []int
+}
+
+// NewFeatureProfiler creates an empty profiler
+func NewFeatureProfiler() *FeatureProfiler {
+	return &FeatureProfiler{
+		durations: make(map[int]map[string]time.Duration),
+	}
+}
+
+// Record adds d to the time spent on feature index in phase
+func (fp *FeatureProfiler) Record(index int, phase string, d time.Duration) {
+	if fp == nil {
+		return
+	}
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	phases, ok := fp.durations[index]
+	if !ok {
+		phases = make(map[string]time.Duration)
+		fp.durations[index] = phases
+	}
+	phases[phase] += d
+}
+
+// SetIndexMap translates the indices later passed to Track, for phases that
+// work on a compacted slice: position i is recorded against indexMap[i].
+// Indices tracked before a map is set are recorded as given.
+func (fp *FeatureProfiler) SetIndexMap(indexMap []int) {
+	if fp == nil {
+		return
+	}
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+	fp.indexMap = indexMap
+}
+
+// CompactIndexMap follows a further compaction of the tracked slice, where
+// position i now holds what was at position kept[i]
+func (fp *FeatureProfiler) CompactIndexMap(kept []int) {
+	if fp == nil {
+		return
+	}
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	compacted := make([]int, len(kept))
+	for i, index := range kept {
+		if index < len(fp.indexMap) {
+			compacted[i] = fp.indexMap[index]
+		} else {
+			compacted[i] = index
+		}
+	}
+	fp.indexMap = compacted
+}
+
+// Track starts timing work on the given features in phase and returns a
+// function that records the elapsed time against each of them when called
+func (fp *FeatureProfiler) Track(phase string, indices ...int) func() {
+	if fp == nil {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		fp.mu.Lock()
+		indexMap := fp.indexMap
+		fp.mu.Unlock()
+		for _, index := range indices {
+			if index >= 0 && index < len(indexMap) {
+				index = indexMap[index]
+			}
+			fp.Record(index, phase, elapsed)
+		}
+	}
+}
+
+// Slowest returns the n features with the largest total processing time, slowest first
+func (fp *FeatureProfiler) Slowest(n int) []FeatureTiming {
+	if fp == nil {
+		return nil
+	}
+	fp.mu.Lock()
+	defer fp.mu.Unlock()
+
+	timings := make([]FeatureTiming, 0, len(fp.durations))
+	for index, phases := range fp.durations {
+		timing := FeatureTiming{Index: index, PhasesMs: make(map[string]float64, len(phases))}
+		var total time.Duration
+		for phase, d := range phases {
+			timing.PhasesMs[phase] = float64(d) / float64(time.Millisecond)
+			total += d
+		}
+		timing.TotalMs = float64(total) / float64(time.Millisecond)
+		timings = append(timings, timing)
+	}
+
+	sort.Slice(timings, func(i, j int) bool {
+		if timings[i].TotalMs != timings[j].TotalMs {
+			return timings[i].TotalMs > timings[j].TotalMs
+		}
+		return timings[i].Index < timings[j].Index
+	})
+	if n >= 0 && len(timings) > n {
+		timings = timings[:n]
+	}
+	return timings
+}
+
 // ParallelProcessor provides utilities for parallel processing
 type ParallelProcessor struct {
 	NumWorkers int